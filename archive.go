@@ -0,0 +1,215 @@
+package gin_storage
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	errorhandlerTool "github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/justdomepaul/toolbox/errorhandler"
+	"github.com/kelseyhightower/envconfig"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ArchiveZip   = "zip"
+	ArchiveTarGz = "tar.gz"
+)
+
+func newArchiveConfig() config.Archive {
+	env := config.Archive{}
+	if err := envconfig.Process("", &env); err != nil {
+		panic(fmt.Errorf("%w: %s", errorhandlerTool.ErrInitialFileClient, err.Error()))
+	}
+	return env
+}
+
+type archiveWriter interface {
+	Create(name string, size int64, modTime time.Time) (io.Writer, error)
+	Close() error
+}
+
+type zipWriter struct {
+	writer *zip.Writer
+}
+
+func (w zipWriter) Create(name string, size int64, modTime time.Time) (io.Writer, error) {
+	return w.writer.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime})
+}
+
+func (w zipWriter) Close() error { return w.writer.Close() }
+
+type tarGzWriter struct {
+	gzip   *gzip.Writer
+	writer *tar.Writer
+}
+
+func (w tarGzWriter) Create(name string, size int64, modTime time.Time) (io.Writer, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("%w: unknown size of %s", errorhandlerTool.ErrGetFile, name)
+	}
+	if err := w.writer.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Size: size, Mode: 0644, ModTime: modTime}); err != nil {
+		return nil, err
+	}
+	return w.writer, nil
+}
+
+func (w tarGzWriter) Close() error {
+	if err := w.writer.Close(); err != nil {
+		return err
+	}
+	return w.gzip.Close()
+}
+
+func newArchiveWriter(format string, w io.Writer) archiveWriter {
+	if format == ArchiveTarGz {
+		gw := gzip.NewWriter(w)
+		return tarGzWriter{gzip: gw, writer: tar.NewWriter(gw)}
+	}
+	return zipWriter{writer: zip.NewWriter(w)}
+}
+
+var archiveContentTypes = map[string]string{
+	ArchiveZip:   "application/zip",
+	ArchiveTarGz: "application/gzip",
+}
+
+// Archive streams the listed paths, or every object under prefix, as a ZIP or tar.gz
+// attachment. Entries are named by the requested filename of a listed path, else the original
+// filename in metadata, else the object name, under their folder relative to prefix, a colliding name gets a (n) suffix.
+// The status is committed with the first entry, an error after that truncates the archive.
+func (fh FileHandler) Archive(c *gin.Context) {
+	req := struct {
+		Paths  []BatchFile `json:"paths,omitempty" validate:"required_without=Prefix,omitempty,min=1,dive"`
+		Prefix string      `json:"prefix,omitempty" validate:"required_without=Paths,excluded_with=Paths"`
+		Format string      `json:"format,omitempty" validate:"omitempty,oneof=zip tar.gz"`
+	}{}
+	defer c.Request.Body.Close()
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		panic(errorhandler.NewErrJSONUnmarshal(err))
+	}
+	if err := validator.New().Struct(&req); err != nil {
+		panic(errorhandler.NewErrVariable(err))
+	}
+	if req.Format == "" {
+		req.Format = ArchiveZip
+	}
+
+	var archive archiveWriter
+	start := func() {
+		c.Header("Content-Type", archiveContentTypes[req.Format])
+		c.Header("Content-Disposition", `attachment; filename="archive.`+req.Format+`"`)
+		c.Status(http.StatusOK)
+		archive = newArchiveWriter(req.Format, c.Writer)
+	}
+	names := archiveNames{}
+	add := func(file storage.File, filename string) error {
+		if _, _, exist := file.FolderInfo(); exist || strings.HasSuffix(file.Path(), "/") {
+			return nil
+		}
+		if archive == nil {
+			start()
+		}
+		return fh.archiveFile(c, archive, names.unique(fh.archiveName(file, filename, req.Prefix)), file)
+	}
+	var err error
+	if req.Prefix != "" {
+		err = fh.storage.List(c, storage.WithFileCloudPrefix(storage.Query{}, req.Prefix), func(file storage.File) error {
+			return add(file, "")
+		})
+	} else {
+		// every path is checked before the status is committed
+		files := make([]storage.File, len(req.Paths))
+		for i, item := range req.Paths {
+			if files[i], err = fh.storage.Stat(c, item.Path); err != nil {
+				panic(reportError(err))
+			}
+		}
+		for i, file := range files {
+			if err = add(file, req.Paths[i].Filename); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil && archive == nil {
+		panic(reportError(err))
+	}
+	if err == nil && archive == nil {
+		start()
+	}
+	if err == nil {
+		err = archive.Close()
+	}
+	if err != nil {
+		_ = c.Error(err)
+	}
+}
+
+// archiveFile copies the content of file to a new entry of archive.
+func (fh FileHandler) archiveFile(c *gin.Context, archive archiveWriter, name string, file storage.File) error {
+	size, err := file.Size()
+	if err != nil {
+		return err
+	}
+	modTime, err := file.ModTime()
+	if err != nil {
+		return err
+	}
+	w, err := archive.Create(name, size, modTime)
+	if err != nil {
+		return err
+	}
+	reader, closeFn, err := file.NewReader(c)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+	_, err = io.Copy(w, reader)
+	return err
+}
+
+// archiveName names the entry of file by filename, else its original filename, under its folder relative to prefix.
+func (fh FileHandler) archiveName(file storage.File, filename, prefix string) string {
+	if filename == "" {
+		filename = file.Metadata()[fh.archive.ArchiveFilenameKey]
+	}
+	name := strings.ReplaceAll(filename, `\`, "/")
+	if name = path.Base(name); name == "." || name == "/" || name == ".." {
+		name = file.Name()
+	}
+	if prefix == "" {
+		return name
+	}
+	var folders []string
+	for _, folder := range strings.Split(path.Dir(strings.TrimPrefix(file.Path(), prefix)), "/") {
+		if folder != "" && folder != "." && folder != ".." {
+			folders = append(folders, folder)
+		}
+	}
+	return path.Join(append(folders, name)...)
+}
+
+// archiveNames tracks the entry names taken, regardless of case.
+type archiveNames map[string]bool
+
+// unique returns name, else the first free "name (n).ext".
+func (names archiveNames) unique(name string) string {
+	ext := path.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	candidate := name
+	for n := 1; names[strings.ToLower(candidate)]; n++ {
+		candidate = stem + " (" + strconv.Itoa(n) + ")" + ext
+	}
+	names[strings.ToLower(candidate)] = true
+	return candidate
+}
@@ -0,0 +1,181 @@
+package gin_storage
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	errorhandlerTool "github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// archiveFile serves content through NewReader.
+type archiveFile struct {
+	testFile
+	content string
+}
+
+func (f archiveFile) Size() (int64, error) { return int64(len(f.content)), nil }
+
+func (f archiveFile) NewReader(ctx context.Context) (io.ReadCloser, func() error, error) {
+	return io.NopCloser(strings.NewReader(f.content)), func() error { return nil }, nil
+}
+
+func readZip(b []byte) (map[string]string, error) {
+	reader, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, err
+	}
+	entries := map[string]string{}
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		entries[file.Name] = string(content)
+	}
+	return entries, nil
+}
+
+func readTarGz(b []byte) (map[string]string, error) {
+	gr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	reader := tar.NewReader(gr)
+	entries := map[string]string{}
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		entries[header.Name] = string(content)
+	}
+}
+
+func (suite *StorageSuite) TestArchive() {
+	files := map[string]storage.File{
+		"test/a":        archiveFile{testFile: testFile{path: "test/a", metadata: map[string]string{"filename": "report.pdf"}}, content: "a"},
+		"test/b":        archiveFile{testFile: testFile{path: "test/b", metadata: map[string]string{"filename": `C:\docs\Report.pdf`}}, content: "b"},
+		"test/c":        archiveFile{testFile: testFile{path: "test/c"}, content: "c"},
+		"test/sub/d":    archiveFile{testFile: testFile{path: "test/sub/d", metadata: map[string]string{"filename": "../d.txt"}}, content: "d"},
+		"test/sub/":     testFile{path: "test/sub/", folder: true},
+		"test/unlisted": archiveFile{testFile: testFile{path: "test/unlisted"}, content: "x"},
+	}
+	testCases := []struct {
+		Label   string
+		Param   map[string]interface{}
+		Read    func(b []byte) (map[string]string, error)
+		Entries map[string]string
+	}{
+		{
+			Label: "Archive paths",
+			Param: map[string]interface{}{
+				"paths": []BatchFile{{Filename: "a.pdf", Path: "test/a"}, {Filename: "A.pdf", Path: "test/b"}, {Filename: `..\c.txt`, Path: "test/c"}},
+			},
+			Read:    readZip,
+			Entries: map[string]string{"a.pdf": "a", "A (1).pdf": "b", "c.txt": "c"},
+		},
+		{
+			Label:   "Archive prefix",
+			Param:   map[string]interface{}{"prefix": "test/"},
+			Read:    readZip,
+			Entries: map[string]string{"report.pdf": "a", "Report (1).pdf": "b", "c": "c", "sub/d.txt": "d"},
+		},
+		{
+			Label:   "Archive prefix as tar.gz",
+			Param:   map[string]interface{}{"prefix": "test/", "format": "tar.gz"},
+			Read:    readTarGz,
+			Entries: map[string]string{"report.pdf": "a", "Report (1).pdf": "b", "c": "c", "sub/d.txt": "d"},
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			testIFile := &testIFile{}
+			for _, route := range []string{"test/a", "test/b", "test/c"} {
+				testIFile.On("Stat", mock.Anything, route).Return(files[route], nil)
+			}
+			testIFile.On("List", mock.Anything, storage.WithFileCloudPrefix(storage.Query{}, "test/"), mock.Anything).Run(func(args mock.Arguments) {
+				for _, route := range []string{"test/a", "test/b", "test/c", "test/sub/", "test/sub/d"} {
+					suite.NoError(args.Get(2).(storage.IterHandler)(files[route]))
+				}
+			}).Return(nil)
+			storage.Register(testIFile, func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			resp, err := PostJSON("/storage/archive", tc.Param, map[string]string{}, route)
+			suite.NoError(err, tc.Label)
+			entries, err := tc.Read(resp)
+			suite.NoError(err, tc.Label)
+			suite.Equal(tc.Entries, entries, tc.Label)
+		}()
+	}
+}
+
+func (suite *StorageSuite) TestArchiveError() {
+	testCases := []struct {
+		Label string
+		Param map[string]interface{}
+		Code  int
+	}{
+		{
+			Label: "Empty request",
+			Param: map[string]interface{}{},
+			Code:  http.StatusBadRequest,
+		},
+		{
+			Label: "Paths and prefix",
+			Param: map[string]interface{}{
+				"paths":  []BatchFile{{Filename: "a", Path: "test/a"}},
+				"prefix": "test/",
+			},
+			Code: http.StatusBadRequest,
+		},
+		{
+			Label: "Unknown format",
+			Param: map[string]interface{}{"prefix": "test/", "format": "rar"},
+			Code:  http.StatusBadRequest,
+		},
+		{
+			Label: "Path not exist",
+			Param: map[string]interface{}{
+				"paths": []BatchFile{{Filename: "a", Path: "test/a"}, {Filename: "none", Path: "test/none"}},
+			},
+			Code: http.StatusNotFound,
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			testIFile := &testIFile{}
+			testIFile.On("Stat", mock.Anything, "test/a").Return(archiveFile{testFile: testFile{path: "test/a"}, content: "a"}, nil)
+			testIFile.On("Stat", mock.Anything, "test/none").Return(nil, fmt.Errorf("%w: test/none", errorhandlerTool.ErrFileNotExist))
+			storage.Register(testIFile, func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			_, err := PostJSON("/storage/archive", tc.Param, map[string]string{}, route)
+			suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Code), tc.Label)
+		}()
+	}
+}
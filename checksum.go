@@ -0,0 +1,65 @@
+package gin_storage
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"io"
+	"net/textproto"
+)
+
+const (
+	HeaderContentMD5     = "Content-MD5"
+	HeaderChecksumSHA256 = "X-Checksum-SHA256"
+)
+
+// expectedChecksums reads the base64 Content-MD5 and the base64 or hex X-Checksum-SHA256
+// of the first header holding them, normalized to base64.
+func expectedChecksums(headers ...textproto.MIMEHeader) (storage.Checksums, error) {
+	expected := storage.Checksums{}
+	for _, header := range headers {
+		if expected.MD5 == "" && header.Get(HeaderContentMD5) != "" {
+			sum, err := base64.StdEncoding.DecodeString(header.Get(HeaderContentMD5))
+			if err != nil || len(sum) != 16 {
+				return expected, fmt.Errorf("%w: invalid %s", errorhandler.ErrInvalidQuery, HeaderContentMD5)
+			}
+			expected.MD5 = base64.StdEncoding.EncodeToString(sum)
+		}
+		if value := header.Get(HeaderChecksumSHA256); expected.SHA256 == "" && value != "" {
+			sum, err := hex.DecodeString(value)
+			if err != nil {
+				sum, err = base64.StdEncoding.DecodeString(value)
+			}
+			if err != nil || len(sum) != 32 {
+				return expected, fmt.Errorf("%w: invalid %s", errorhandler.ErrInvalidQuery, HeaderChecksumSHA256)
+			}
+			expected.SHA256 = base64.StdEncoding.EncodeToString(sum)
+		}
+	}
+	return expected, nil
+}
+
+// hashContent returns the checksums of the content of f, rewound for the upload.
+func hashContent(f io.ReadSeeker) (storage.Checksums, error) {
+	hasher := storage.NewHasher()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return storage.Checksums{}, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return storage.Checksums{}, err
+	}
+	return hasher.Sum(), nil
+}
+
+// verifyChecksums fails with ErrChecksumMismatch when sums differ from the expected ones.
+func verifyChecksums(expected, sums storage.Checksums) error {
+	if expected.MD5 != "" && expected.MD5 != sums.MD5 {
+		return fmt.Errorf("%w: md5 %s, got %s", errorhandler.ErrChecksumMismatch, expected.MD5, sums.MD5)
+	}
+	if expected.SHA256 != "" && expected.SHA256 != sums.SHA256 {
+		return fmt.Errorf("%w: sha256 %s, got %s", errorhandler.ErrChecksumMismatch, expected.SHA256, sums.SHA256)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package gin_storage
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+func (suite *StorageSuite) TestUploadChecksum() {
+	content, err := os.ReadFile("./storage/cloud/image.png")
+	suite.NoError(err)
+	hasher := storage.NewHasher()
+	_, _ = hasher.Write(content)
+	sums := hasher.Sum()
+	sha256, _ := base64.StdEncoding.DecodeString(sums.SHA256)
+
+	testCases := []struct {
+		Label   string
+		Headers map[string]string
+		Code    int
+	}{
+		{
+			Label:   "Upload without checksum",
+			Headers: map[string]string{},
+		},
+		{
+			Label:   "Upload with md5",
+			Headers: map[string]string{HeaderContentMD5: sums.MD5},
+		},
+		{
+			Label:   "Upload with hex sha256",
+			Headers: map[string]string{HeaderChecksumSHA256: hex.EncodeToString(sha256)},
+		},
+		{
+			Label:   "Upload with md5 mismatch",
+			Headers: map[string]string{HeaderContentMD5: "XrY7u+Ae7tCTyyK7j1rNww=="},
+			Code:    http.StatusBadRequest,
+		},
+		{
+			Label:   "Upload with sha256 mismatch",
+			Headers: map[string]string{HeaderChecksumSHA256: "uU0nuZNNPgilLlLX2n2r+sSE7+N6U4DukIj3rOLvzek="},
+			Code:    http.StatusBadRequest,
+		},
+		{
+			Label:   "Upload with invalid md5",
+			Headers: map[string]string{HeaderContentMD5: "not base64"},
+			Code:    http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			f, errOpen := os.Open("./storage/cloud/image.png")
+			suite.NoError(errOpen)
+			defer f.Close()
+
+			testIFile := &testIFile{}
+			testIFile.On("Upload", mock.Anything, "test", mock.Anything, mock.MatchedBy(func(attrs []storage.UploadAttrs) bool {
+				return storage.MergeUploadAttrs(attrs...).Checksums == storage.Checksums{CRC32C: sums.CRC32C, MD5: sums.MD5}
+			})).Run(func(args mock.Arguments) {
+				_, _ = io.ReadAll(args.Get(2).(io.Reader))
+			}).Return("test/testPath", nil)
+			storage.Register(testIFile, func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			resp, err := PostFile("/storage", map[string]io.Reader{"file": f, "prefix": strings.NewReader("test")}, tc.Headers, route)
+			if tc.Code != 0 {
+				suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Code), tc.Label)
+				return
+			}
+			suite.NoError(err, tc.Label)
+			var result struct {
+				Path      string            `json:"path"`
+				Checksums storage.Checksums `json:"checksums"`
+			}
+			suite.NoError(json.Unmarshal(resp, &result), tc.Label)
+			suite.Equal("test/testPath", result.Path, tc.Label)
+			suite.Equal(sums, result.Checksums, tc.Label)
+		}()
+	}
+}
@@ -0,0 +1,31 @@
+// Command encryption-rewrap wraps the data keys of the encrypted objects in the
+// bucket by the current master key and prints how many it rewrapped. Noncurrent
+// versions aren't rewrapped, keep their master key until they are removed.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/justdomepaul/gin-storage/storage"
+	_ "github.com/justdomepaul/gin-storage/storage/cloud"
+	"github.com/justdomepaul/gin-storage/storage/encryption"
+	"log"
+	"os"
+)
+
+func main() {
+	fileStorage, closeFn := storage.Load()
+	defer closeFn()
+
+	enc, err := encryption.FromEnv(fileStorage)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	count, err := enc.RewrapAll(context.Background(), storage.Query{})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(map[string]int{"rewrapped": count}); err != nil {
+		log.Fatalln(err)
+	}
+}
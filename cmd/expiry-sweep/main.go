@@ -0,0 +1,30 @@
+// Command expiry-sweep removes the temporary uploads whose expiry has passed
+// and prints how many it removed.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/justdomepaul/gin-storage/storage"
+	_ "github.com/justdomepaul/gin-storage/storage/cloud"
+	"github.com/justdomepaul/gin-storage/storage/expiry"
+	"log"
+	"os"
+)
+
+func main() {
+	fileStorage, closeFn := storage.Load()
+	defer closeFn()
+
+	sweeper, err := expiry.FromEnv(fileStorage)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	count, err := sweeper.Sweep(context.Background())
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(map[string]int{"removed": count}); err != nil {
+		log.Fatalln(err)
+	}
+}
@@ -0,0 +1,31 @@
+// Command index-rebuild repopulates the search index from the objects stored
+// in the bucket and prints how many it indexed.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/justdomepaul/gin-storage/storage"
+	_ "github.com/justdomepaul/gin-storage/storage/cloud"
+	"github.com/justdomepaul/gin-storage/storage/index"
+	"log"
+	"os"
+)
+
+func main() {
+	fileStorage, closeFn := storage.Load()
+	defer closeFn()
+
+	idx, err := index.FromEnv(fileStorage)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer idx.Close()
+	count, err := idx.Rebuild(context.Background())
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(map[string]int{"indexed": count}); err != nil {
+		log.Fatalln(err)
+	}
+}
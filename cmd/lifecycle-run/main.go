@@ -0,0 +1,30 @@
+// Command lifecycle-run applies the lifecycle rules of LIFECYCLE_RULES_PATH once
+// and prints how many objects and versions it removed.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/justdomepaul/gin-storage/storage"
+	_ "github.com/justdomepaul/gin-storage/storage/cloud"
+	"github.com/justdomepaul/gin-storage/storage/lifecycle"
+	"log"
+	"os"
+)
+
+func main() {
+	fileStorage, closeFn := storage.Load()
+	defer closeFn()
+
+	evaluator, err := lifecycle.FromEnv(fileStorage)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	count, err := evaluator.Run(context.Background())
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(map[string]int{"removed": count}); err != nil {
+		log.Fatalln(err)
+	}
+}
@@ -0,0 +1,30 @@
+// Command quota-reconcile recomputes the quota usages from the objects stored
+// in the bucket and writes them back to the usage store.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/justdomepaul/gin-storage/storage"
+	_ "github.com/justdomepaul/gin-storage/storage/cloud"
+	"github.com/justdomepaul/gin-storage/storage/quota"
+	"log"
+	"os"
+)
+
+func main() {
+	fileStorage, closeFn := storage.Load()
+	defer closeFn()
+
+	q, err := quota.FromEnv(fileStorage)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	usages, err := q.Reconcile(context.Background())
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(usages); err != nil {
+		log.Fatalln(err)
+	}
+}
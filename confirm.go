@@ -0,0 +1,69 @@
+package gin_storage
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/kelseyhightower/envconfig"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// confirmer signs short lived tokens a client must echo back to run a destructive
+// request. Without CONFIRM_SECRET a random secret is used, tokens are then only
+// valid on the process which issued them.
+type confirmer struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+func newConfirmer() confirmer {
+	env := config.Confirm{}
+	if err := envconfig.Process("", &env); err != nil {
+		panic(fmt.Errorf("%w: %s", errorhandler.ErrInitialFileClient, err.Error()))
+	}
+	secret := []byte(env.ConfirmSecret)
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			panic(fmt.Errorf("%w: %s", errorhandler.ErrInitialFileClient, err.Error()))
+		}
+	}
+	return confirmer{
+		secret: secret,
+		ttl:    env.ConfirmTTL,
+	}
+}
+
+// token returns the token confirming subject and its expiry.
+func (cf confirmer) token(subject string, now time.Time) (string, time.Time) {
+	expires := now.Add(cf.ttl).Truncate(time.Second)
+	unix := strconv.FormatInt(expires.Unix(), 10)
+	return unix + "." + cf.sign(subject, unix), expires
+}
+
+func (cf confirmer) verify(subject, token string, now time.Time) error {
+	unix, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(cf.sign(subject, unix))) {
+		return fmt.Errorf("%w: signature mismatch", errorhandler.ErrConfirmToken)
+	}
+	expires, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errorhandler.ErrConfirmToken, err.Error())
+	}
+	if now.After(time.Unix(expires, 0)) {
+		return fmt.Errorf("%w: expired", errorhandler.ErrConfirmToken)
+	}
+	return nil
+}
+
+func (cf confirmer) sign(subject, unix string) string {
+	mac := hmac.New(sha256.New, cf.secret)
+	mac.Write([]byte(subject + "\n" + unix))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
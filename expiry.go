@@ -0,0 +1,56 @@
+package gin_storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	errorhandlerTool "github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/toolbox/errorhandler"
+	"github.com/kelseyhightower/envconfig"
+	"time"
+)
+
+func newExpiryConfig() config.Expiry {
+	env := config.Expiry{}
+	if err := envconfig.Process("", &env); err != nil {
+		panic(fmt.Errorf("%w: %s", errorhandlerTool.ErrInitialFileClient, err.Error()))
+	}
+	return env
+}
+
+// expiresIn reads the expires_in form field as a duration, zero when absent.
+func (fh FileHandler) expiresIn(values map[string][]string) time.Duration {
+	if len(values["expires_in"]) == 0 || values["expires_in"][0] == "" {
+		return 0
+	}
+	ttl, err := time.ParseDuration(values["expires_in"][0])
+	if err != nil {
+		panic(errorhandler.NewErrVariable(err))
+	}
+	if ttl <= 0 || (fh.expiry.ExpiryMaxTTL > 0 && ttl > fh.expiry.ExpiryMaxTTL) {
+		panic(errorhandler.NewErrVariable(fmt.Errorf("%w: expires_in %s out of range", errorhandlerTool.ErrInvalidQuery, ttl)))
+	}
+	return ttl
+}
+
+// Commit removes the expiry metadata key of a temporary upload so the sweeper keeps it,
+// the empty value deletes the key as UpdateMetadata does.
+func (fh FileHandler) Commit(c *gin.Context) {
+	req := struct {
+		Path string `json:"path,omitempty" validate:"required"`
+	}{}
+	defer c.Request.Body.Close()
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		panic(errorhandler.NewErrJSONUnmarshal(err))
+	}
+	if err := validator.New().Struct(&req); err != nil {
+		panic(errorhandler.NewErrVariable(err))
+	}
+	file, err := fh.storage.UpdateMetadata(c, req.Path, map[string]string{fh.expiry.ExpiryMetadataKey: ""}, preconditions(c)...)
+	if err != nil {
+		panic(reportError(err))
+	}
+	fh.respondFile(c, file)
+}
@@ -0,0 +1,121 @@
+package gin_storage
+
+import (
+	"encoding/json"
+	"fmt"
+	errorhandlerTool "github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/justdomepaul/gin-storage/storage/expiry"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+func (suite *StorageSuite) TestUploadExpiresIn() {
+	testCases := []struct {
+		Label     string
+		ExpiresIn string
+		Code      int
+	}{
+		{
+			Label:     "Upload expiring in an hour",
+			ExpiresIn: "1h",
+		},
+		{
+			Label:     "Upload with invalid duration",
+			ExpiresIn: "soon",
+			Code:      http.StatusBadRequest,
+		},
+		{
+			Label:     "Upload with negative duration",
+			ExpiresIn: "-1h",
+			Code:      http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			f, errOpen := os.Open("./storage/cloud/image.png")
+			suite.NoError(errOpen)
+			defer f.Close()
+
+			var attrs []storage.UploadAttrs
+			testIFile := &testIFile{}
+			testIFile.On("Upload", mock.Anything, "test", mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) {
+					attrs = args.Get(3).([]storage.UploadAttrs)
+				}).Return("test/testPath", nil)
+			storage.Register(testIFile, func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			_, err := PostFile("/storage", map[string]io.Reader{
+				"file":       f,
+				"prefix":     strings.NewReader("test"),
+				"expires_in": strings.NewReader(tc.ExpiresIn),
+			}, map[string]string{}, route)
+			if tc.Code != 0 {
+				suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Code), tc.Label)
+				return
+			}
+			suite.NoError(err, tc.Label)
+			expiresAt, err := time.Parse(time.RFC3339, storage.MergeUploadAttrs(attrs...).Metadata["expires_at"])
+			suite.NoError(err, tc.Label)
+			suite.WithinDuration(time.Now().Add(time.Hour), expiresAt, time.Minute, tc.Label)
+		}()
+	}
+}
+
+func (suite *StorageSuite) TestCommit() {
+	testCases := []struct {
+		Label string
+		Path  string
+		Error error
+		Code  int
+	}{
+		{
+			Label: "Commit temporary upload",
+			Path:  "test/a",
+		},
+		{
+			Label: "Commit not exist",
+			Path:  "test/a",
+			Error: errorhandlerTool.ErrFileNotExist,
+			Code:  http.StatusNotFound,
+		},
+		{
+			Label: "Commit without path",
+			Code:  http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			testIFile := &testIFile{}
+			call := testIFile.On("UpdateMetadata", mock.Anything, "test/a", map[string]string{"expires_at": ""})
+			if tc.Error != nil {
+				call.Return(nil, tc.Error)
+			} else {
+				call.Return(testFile{path: "test/a", metadata: map[string]string{"owner": "a"}}, nil)
+			}
+			storage.Register(testIFile, func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			resp, err := PostJSON("/storage/commit", map[string]interface{}{"path": tc.Path}, map[string]string{}, route)
+			if tc.Code != 0 {
+				suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Code), tc.Label)
+				return
+			}
+			suite.NoError(err, tc.Label)
+			var result FileV1
+			suite.NoError(json.Unmarshal(resp, &result))
+			suite.NotContains(result.Metadata, "expires_at", tc.Label)
+			_, expiring := expiry.ExpiresAt(testFile{metadata: result.Metadata}, "expires_at")
+			suite.False(expiring, tc.Label)
+		}()
+	}
+}
@@ -0,0 +1,134 @@
+package gin_storage
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/justdomepaul/toolbox/errorhandler"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+type FolderProgress struct {
+	Done  int    `json:"done"`
+	Total int    `json:"total"`
+	Error string `json:"error,omitempty"`
+}
+
+func (fh FileHandler) CreateFolder(c *gin.Context) {
+	req := struct {
+		Path string `json:"path,omitempty" validate:"required"`
+	}{}
+	defer c.Request.Body.Close()
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		panic(errorhandler.NewErrJSONUnmarshal(err))
+	}
+	if err := validator.New().Struct(&req); err != nil {
+		panic(errorhandler.NewErrVariable(err))
+	}
+	path, err := fh.storage.CreateFolder(c, req.Path)
+	if err != nil {
+		panic(reportError(err))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"path": path,
+	})
+}
+
+func (fh FileHandler) RemoveFolder(c *gin.Context) {
+	req := struct {
+		Path   string `validate:"required"`
+		DryRun bool
+	}{
+		Path: c.Query("path"),
+	}
+	if c.Query("dry_run") != "" {
+		dryRun, err := strconv.ParseBool(c.Query("dry_run"))
+		if err != nil {
+			panic(errorhandler.NewErrVariable(err))
+		}
+		req.DryRun = dryRun
+	}
+	if err := validator.New().Struct(&req); err != nil {
+		panic(errorhandler.NewErrVariable(err))
+	}
+	var (
+		count int
+		err   error
+	)
+	if req.DryRun {
+		count, err = fh.storage.RemoveFolder(c, req.Path, true)
+	} else {
+		count, err = fh.removeFolder(c, req.Path)
+	}
+	if err != nil {
+		panic(reportError(err))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"path":    req.Path,
+		"count":   count,
+		"dry_run": req.DryRun,
+	})
+}
+
+// RenameFolder responds the count of moved objects, or streams a FolderProgress
+// line per moved object when the client accepts NDJSON. The remove interceptors see
+// src as a folder path with a trailing slash.
+func (fh FileHandler) RenameFolder(c *gin.Context) {
+	req := struct {
+		Src string `json:"src,omitempty" validate:"required"`
+		Dst string `json:"dst,omitempty" validate:"required"`
+	}{}
+	defer c.Request.Body.Close()
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		panic(errorhandler.NewErrJSONUnmarshal(err))
+	}
+	if err := validator.New().Struct(&req); err != nil {
+		panic(errorhandler.NewErrVariable(err))
+	}
+	folder := folderPath(req.Src)
+	if err := fh.interceptors.beforeRemove(c, folder); err != nil {
+		panic(reportError(err))
+	}
+	if c.NegotiateFormat(gin.MIMEJSON, MIMENDJSON) != MIMENDJSON {
+		count, err := fh.storage.RenameFolder(c, req.Src, req.Dst, nil)
+		if err != nil {
+			panic(reportError(err))
+		}
+		fh.interceptors.afterRemove(c, folder)
+		c.JSON(http.StatusOK, gin.H{
+			"path":  req.Dst,
+			"count": count,
+		})
+		return
+	}
+
+	var (
+		mu      sync.Mutex
+		started bool
+		total   int
+	)
+	encoder := json.NewEncoder(c.Writer)
+	count, err := fh.storage.RenameFolder(c, req.Src, req.Dst, func(done, all int) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !started {
+			started = true
+			c.Header("Content-Type", MIMENDJSON)
+			c.Status(http.StatusOK)
+		}
+		total = all
+		_ = encoder.Encode(FolderProgress{Done: done, Total: all})
+		c.Writer.Flush()
+	})
+	if err == nil {
+		fh.interceptors.afterRemove(c, folder)
+		return
+	}
+	if !started {
+		panic(reportError(err))
+	}
+	_ = encoder.Encode(FolderProgress{Done: count, Total: total, Error: err.Error()})
+	_ = c.Error(err)
+}
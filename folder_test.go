@@ -0,0 +1,189 @@
+package gin_storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	errorhandlerTool "github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+)
+
+func (suite *StorageSuite) TestCreateFolder() {
+	testCases := []struct {
+		Label string
+		Path  string
+		Error error
+		Code  int
+	}{
+		{
+			Label: "Create folder",
+			Path:  "test/folder",
+		},
+		{
+			Label: "Create existing folder",
+			Path:  "test/folder",
+			Error: errorhandlerTool.ErrFileExist,
+			Code:  http.StatusConflict,
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			testIFile := &testIFile{}
+			testIFile.On("CreateFolder", mock.Anything, tc.Path).Return(tc.Path+"/", tc.Error)
+			storage.Register(testIFile, func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			resp, err := PostJSON("/storage/folder", map[string]interface{}{
+				"path": tc.Path,
+			}, map[string]string{}, route)
+			if tc.Code != 0 {
+				suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Code), tc.Label)
+				return
+			}
+			suite.NoError(err, tc.Label)
+			var result map[string]interface{}
+			suite.NoError(json.Unmarshal(resp, &result))
+			suite.Equal(tc.Path+"/", result["path"], tc.Label)
+		}()
+	}
+}
+
+func (suite *StorageSuite) TestRemoveFolder() {
+	testCases := []struct {
+		Label  string
+		Query  string
+		DryRun bool
+		Error  error
+		Code   int
+	}{
+		{
+			Label: "Remove folder",
+			Query: "?path=test/folder",
+		},
+		{
+			Label:  "Remove folder dry run",
+			Query:  "?path=test/folder&dry_run=true",
+			DryRun: true,
+		},
+		{
+			Label: "Remove folder error",
+			Query: "?path=test/folder",
+			Error: errorhandlerTool.ErrFileRemove,
+			Code:  http.StatusConflict,
+		},
+		{
+			Label: "Remove folder invalid dry run",
+			Query: "?path=test/folder&dry_run=maybe",
+			Code:  http.StatusBadRequest,
+		},
+		{
+			Label: "Remove folder without path",
+			Code:  http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			testIFile := &testIFile{}
+			testIFile.On("RemoveFolder", mock.Anything, "test/folder", tc.DryRun).Return(3, tc.Error)
+			storage.Register(testIFile, func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			resp, err := DeleteJSON("/storage/folder"+tc.Query, map[string]interface{}{}, map[string]string{}, route)
+			if tc.Code != 0 {
+				suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Code), tc.Label)
+				return
+			}
+			suite.NoError(err, tc.Label)
+			var result map[string]interface{}
+			suite.NoError(json.Unmarshal(resp, &result))
+			suite.Equal(float64(3), result["count"], tc.Label)
+			suite.Equal(tc.DryRun, result["dry_run"], tc.Label)
+		}()
+	}
+}
+
+func (suite *StorageSuite) TestRenameFolder() {
+	testIFile := &testIFile{}
+	testIFile.On("RenameFolder", mock.Anything, "test/a", "test/b", mock.Anything).Return(2, nil)
+	storage.Register(testIFile, func() {})
+	defer storage.Unload()
+	route := NewMockGinServer()
+	Register(route)
+
+	resp, err := PostJSON("/storage/folder/rename", map[string]interface{}{
+		"src": "test/a",
+		"dst": "test/b",
+	}, map[string]string{}, route)
+	suite.NoError(err)
+	var result map[string]interface{}
+	suite.NoError(json.Unmarshal(resp, &result))
+	suite.Equal(float64(2), result["count"])
+	suite.Equal("test/b", result["path"])
+
+	_, err = PostJSON("/storage/folder/rename", map[string]interface{}{
+		"src": "test/a",
+	}, map[string]string{}, route)
+	suite.Error(err)
+}
+
+func (suite *StorageSuite) TestRenameFolderProgress() {
+	testCases := []struct {
+		Label string
+		Moved int
+		Error error
+		Code  int
+		Body  string
+	}{
+		{
+			Label: "Rename folder progress",
+			Moved: 2,
+			Code:  http.StatusOK,
+			Body:  `{"done":1,"total":2}` + "\n" + `{"done":2,"total":2}` + "\n",
+		},
+		{
+			Label: "Rename folder fail halfway",
+			Moved: 1,
+			Error: errorhandlerTool.ErrFileCopy,
+			Code:  http.StatusOK,
+			Body:  `{"done":1,"total":2}` + "\n" + `{"done":1,"total":2,"error":"fail to copy file"}` + "\n",
+		},
+		{
+			Label: "Rename empty folder",
+			Error: errorhandlerTool.ErrFolderNotExist,
+			Code:  http.StatusNotFound,
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			testIFile := &testIFile{}
+			testIFile.On("RenameFolder", mock.Anything, "test/a", "test/b", mock.Anything).
+				Run(func(args mock.Arguments) {
+					for i := 1; i <= tc.Moved; i++ {
+						args.Get(3).(storage.ProgressFn)(i, 2)
+					}
+				}).
+				Return(tc.Moved, tc.Error)
+			storage.Register(testIFile, func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			req := httptest.NewRequest(http.MethodPost, "/storage/folder/rename", bytes.NewReader([]byte(`{"src":"test/a","dst":"test/b"}`)))
+			req.Header.Set("Accept", MIMENDJSON)
+			w := httptest.NewRecorder()
+			route.ServeHTTP(w, req)
+			suite.Equal(tc.Code, w.Code, tc.Label)
+			if tc.Body != "" {
+				suite.Equal(MIMENDJSON, w.Header().Get("Content-Type"), tc.Label)
+				suite.Equal(tc.Body, w.Body.String(), tc.Label)
+			}
+		}()
+	}
+}
@@ -10,7 +10,11 @@ require (
 	github.com/google/uuid v1.3.0
 	github.com/justdomepaul/toolbox v0.0.1
 	github.com/kelseyhightower/envconfig v1.4.0
+	github.com/klauspost/compress v1.16.7
 	github.com/stretchr/testify v1.7.0
+	go.etcd.io/bbolt v1.3.6
+	go.uber.org/zap v1.21.0
+	golang.org/x/image v0.0.0-20220302094943-723b81ca9867
 	google.golang.org/api v0.73.0
 )
 
@@ -50,7 +54,6 @@ require (
 	go.opencensus.io v0.23.0 // indirect
 	go.uber.org/atomic v1.9.0 // indirect
 	go.uber.org/multierr v1.8.0 // indirect
-	go.uber.org/zap v1.21.0 // indirect
 	golang.org/x/crypto v0.0.0-20220315160706-3147a52a75dd // indirect
 	golang.org/x/net v0.0.0-20220225172249-27dd8689420f // indirect
 	golang.org/x/oauth2 v0.0.0-20220309155454-6242fa91716a // indirect
@@ -0,0 +1,94 @@
+package gin_storage
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/justdomepaul/toolbox/errorhandler"
+	"net/http"
+)
+
+// ObjectLock responds the holds and retention of the object at path.
+func (fh FileHandler) ObjectLock(c *gin.Context) {
+	req := struct {
+		Path string `validate:"required"`
+	}{
+		Path: c.Query("path"),
+	}
+	if err := validator.New().Struct(&req); err != nil {
+		panic(errorhandler.NewErrVariable(err))
+	}
+	lock, err := fh.storage.ObjectLock(c, req.Path)
+	if err != nil {
+		panic(reportError(err))
+	}
+	c.JSON(http.StatusOK, lock)
+}
+
+// SetHold sets or releases the holds named in the request, the others are left unchanged.
+func (fh FileHandler) SetHold(c *gin.Context) {
+	req := struct {
+		Path       string `json:"path,omitempty" validate:"required"`
+		EventBased *bool  `json:"event_based,omitempty" validate:"required_without=Temporary"`
+		Temporary  *bool  `json:"temporary,omitempty" validate:"required_without=EventBased"`
+	}{}
+	defer c.Request.Body.Close()
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		panic(errorhandler.NewErrJSONUnmarshal(err))
+	}
+	if err := validator.New().Struct(&req); err != nil {
+		panic(errorhandler.NewErrVariable(err))
+	}
+	lock, err := fh.storage.SetHold(c, req.Path, storage.Hold{EventBased: req.EventBased, Temporary: req.Temporary})
+	if err != nil {
+		panic(reportError(err))
+	}
+	c.JSON(http.StatusOK, lock)
+}
+
+// Retention responds the retention policy of the bucket.
+func (fh FileHandler) Retention(c *gin.Context) {
+	retention, err := fh.storage.Retention(c)
+	if err != nil {
+		panic(reportError(err))
+	}
+	c.JSON(http.StatusOK, retention)
+}
+
+// SetRetention sets the retention period of the bucket, zero removes the policy.
+func (fh FileHandler) SetRetention(c *gin.Context) {
+	req := struct {
+		PeriodSeconds *int64 `json:"period_seconds,omitempty" validate:"required,min=0"`
+	}{}
+	defer c.Request.Body.Close()
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		panic(errorhandler.NewErrJSONUnmarshal(err))
+	}
+	if err := validator.New().Struct(&req); err != nil {
+		panic(errorhandler.NewErrVariable(err))
+	}
+	retention, err := fh.storage.SetRetention(c, *req.PeriodSeconds)
+	if err != nil {
+		panic(reportError(err))
+	}
+	c.JSON(http.StatusOK, retention)
+}
+
+// ReleaseHold releases every hold of the object at path, the retention still applies.
+func (fh FileHandler) ReleaseHold(c *gin.Context) {
+	req := struct {
+		Path string `validate:"required"`
+	}{
+		Path: c.Query("path"),
+	}
+	if err := validator.New().Struct(&req); err != nil {
+		panic(errorhandler.NewErrVariable(err))
+	}
+	release := false
+	lock, err := fh.storage.SetHold(c, req.Path, storage.Hold{EventBased: &release, Temporary: &release})
+	if err != nil {
+		panic(reportError(err))
+	}
+	c.JSON(http.StatusOK, lock)
+}
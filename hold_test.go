@@ -0,0 +1,184 @@
+package gin_storage
+
+import (
+	"encoding/json"
+	"fmt"
+	errorhandlerTool "github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"time"
+)
+
+func (suite *StorageSuite) TestObjectLock() {
+	retainUntil := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	testIFile := &testIFile{}
+	testIFile.On("ObjectLock", mock.Anything, "test/a").Return(storage.ObjectLock{TemporaryHold: true, RetainUntil: retainUntil}, nil)
+	testIFile.On("ObjectLock", mock.Anything, "test/b").Return(storage.ObjectLock{}, errorhandlerTool.ErrFileNotExist)
+	storage.Register(testIFile, func() {})
+	defer storage.Unload()
+	route := NewMockGinServer()
+	Register(route)
+
+	resp, err := Get("/storage/hold?path=test/a", map[string]string{}, route)
+	suite.NoError(err)
+	var lock storage.ObjectLock
+	suite.NoError(json.Unmarshal(resp, &lock))
+	suite.Equal(storage.ObjectLock{TemporaryHold: true, RetainUntil: retainUntil}, lock)
+
+	_, err = Get("/storage/hold?path=test/b", map[string]string{}, route)
+	suite.EqualError(err, fmt.Sprintf("request error by code: %d", http.StatusNotFound))
+	_, err = Get("/storage/hold", map[string]string{}, route)
+	suite.EqualError(err, fmt.Sprintf("request error by code: %d", http.StatusBadRequest))
+}
+
+func (suite *StorageSuite) TestSetHold() {
+	set, release := true, false
+	testCases := []struct {
+		Label string
+		Param map[string]interface{}
+		Hold  storage.Hold
+		Error error
+		Code  int
+	}{
+		{
+			Label: "Set event based hold",
+			Param: map[string]interface{}{"path": "test/a", "event_based": true},
+			Hold:  storage.Hold{EventBased: &set},
+		},
+		{
+			Label: "Release temporary hold",
+			Param: map[string]interface{}{"path": "test/a", "temporary": false},
+			Hold:  storage.Hold{Temporary: &release},
+		},
+		{
+			Label: "Set hold on not exist",
+			Param: map[string]interface{}{"path": "test/a", "temporary": true},
+			Hold:  storage.Hold{Temporary: &set},
+			Error: errorhandlerTool.ErrFileNotExist,
+			Code:  http.StatusNotFound,
+		},
+		{
+			Label: "Set without hold",
+			Param: map[string]interface{}{"path": "test/a"},
+			Code:  http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			testIFile := &testIFile{}
+			testIFile.On("SetHold", mock.Anything, "test/a", tc.Hold).Return(storage.ObjectLock{EventBasedHold: true}, tc.Error)
+			storage.Register(testIFile, func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			_, err := PutJSON("/storage/hold", tc.Param, map[string]string{}, route)
+			if tc.Code != 0 {
+				suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Code), tc.Label)
+				return
+			}
+			suite.NoError(err, tc.Label)
+			testIFile.AssertExpectations(suite.T())
+		}()
+	}
+}
+
+func (suite *StorageSuite) TestReleaseHold() {
+	release := false
+	testIFile := &testIFile{}
+	testIFile.On("SetHold", mock.Anything, "test/a", storage.Hold{EventBased: &release, Temporary: &release}).Return(storage.ObjectLock{}, nil)
+	storage.Register(testIFile, func() {})
+	defer storage.Unload()
+	route := NewMockGinServer()
+	Register(route)
+
+	_, err := DeleteJSON("/storage/hold?path=test/a", map[string]interface{}{}, map[string]string{}, route)
+	suite.NoError(err)
+	testIFile.AssertExpectations(suite.T())
+}
+
+func (suite *StorageSuite) TestRetention() {
+	testIFile := &testIFile{}
+	testIFile.On("Retention", mock.Anything).Return(storage.Retention{PeriodSeconds: 86400, Locked: true}, nil)
+	storage.Register(testIFile, func() {})
+	defer storage.Unload()
+	route := NewMockGinServer()
+	Register(route)
+
+	resp, err := Get("/storage/retention", map[string]string{}, route)
+	suite.NoError(err)
+	var retention storage.Retention
+	suite.NoError(json.Unmarshal(resp, &retention))
+	suite.Equal(storage.Retention{PeriodSeconds: 86400, Locked: true}, retention)
+}
+
+func (suite *StorageSuite) TestSetRetention() {
+	testCases := []struct {
+		Label  string
+		Param  map[string]interface{}
+		Period int64
+		Error  error
+		Code   int
+	}{
+		{
+			Label:  "Set retention",
+			Param:  map[string]interface{}{"period_seconds": 86400},
+			Period: 86400,
+		},
+		{
+			Label: "Remove retention",
+			Param: map[string]interface{}{"period_seconds": 0},
+		},
+		{
+			Label:  "Shorten locked retention",
+			Param:  map[string]interface{}{"period_seconds": 60},
+			Period: 60,
+			Error:  errorhandlerTool.ErrObjectLocked,
+			Code:   http.StatusLocked,
+		},
+		{
+			Label: "Set without period",
+			Param: map[string]interface{}{},
+			Code:  http.StatusBadRequest,
+		},
+		{
+			Label: "Set negative period",
+			Param: map[string]interface{}{"period_seconds": -1},
+			Code:  http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			testIFile := &testIFile{}
+			testIFile.On("SetRetention", mock.Anything, tc.Period).Return(storage.Retention{PeriodSeconds: tc.Period}, tc.Error)
+			storage.Register(testIFile, func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			resp, err := PutJSON("/storage/retention", tc.Param, map[string]string{}, route)
+			if tc.Code != 0 {
+				suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Code), tc.Label)
+				return
+			}
+			suite.NoError(err, tc.Label)
+			var retention storage.Retention
+			suite.NoError(json.Unmarshal(resp, &retention), tc.Label)
+			suite.Equal(storage.Retention{PeriodSeconds: tc.Period}, retention, tc.Label)
+			testIFile.AssertExpectations(suite.T())
+		}()
+	}
+}
+
+func (suite *StorageSuite) TestRemoveLocked() {
+	testIFile := &testIFile{}
+	testIFile.On("Remove", mock.Anything, "test/a").Return(fmt.Errorf("%w: test/a", errorhandlerTool.ErrObjectLocked))
+	storage.Register(testIFile, func() {})
+	defer storage.Unload()
+	route := NewMockGinServer()
+	Register(route)
+
+	_, err := DeleteJSON("/storage?path=test/a", map[string]interface{}{}, map[string]string{}, route)
+	suite.EqualError(err, fmt.Sprintf("request error by code: %d", http.StatusLocked))
+}
@@ -0,0 +1,63 @@
+package gin_storage
+
+import (
+	"github.com/cockroachdb/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	errorhandlerTool "github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/justdomepaul/gin-storage/storage/imaging"
+	"github.com/justdomepaul/toolbox/errorhandler"
+	"net/http"
+	"strconv"
+)
+
+// Image responds the original at path resized to w and h, the derivative is cached next to the original.
+// w and h are clamped to IMAGE_MAX_DIMENSION and must be one of IMAGE_SIZES when it is set.
+func (fh FileHandler) Image(c *gin.Context) {
+	i, ok := storage.Unwrap[*imaging.Imaging](fh.storage)
+	if !ok {
+		panic(errorhandler.NewErrNotFound(errorhandlerTool.ErrImageNotEnable))
+	}
+	req := struct {
+		Path string `validate:"required"`
+	}{
+		Path: c.Query("path"),
+	}
+	if err := validator.New().Struct(&req); err != nil {
+		panic(errorhandler.NewErrVariable(err))
+	}
+	file, reader, err := i.Image(c, req.Path, imaging.Resize{
+		Width:  int(queryInt64(c, "w")),
+		Height: int(queryInt64(c, "h")),
+		Fit:    imaging.FitType(c.Query("fit")),
+	})
+	if err != nil {
+		panic(imageError(err))
+	}
+	defer reader.Close()
+	size, err := file.Size()
+	if err != nil {
+		panic(errorhandler.NewErrExecute(err))
+	}
+	contentType := file.ContentType()
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.DataFromReader(http.StatusOK, size, contentType, reader, map[string]string{
+		"ETag":         etag(file.Generation()),
+		"X-Generation": strconv.FormatInt(file.Generation(), 10),
+	})
+}
+
+func imageError(err error) errorhandler.IGinErrorReport {
+	switch {
+	case errors.Is(err, errorhandlerTool.ErrInvalidQuery):
+		return errorhandler.NewErrVariable(err)
+	case errors.Is(err, errorhandlerTool.ErrImageFormat):
+		return errorhandlerTool.NewErrStatus(http.StatusUnsupportedMediaType, err)
+	case errors.Is(err, errorhandlerTool.ErrImageProcess):
+		return errorhandler.NewErrExecute(err)
+	}
+	return reportError(err)
+}
@@ -0,0 +1,94 @@
+package gin_storage
+
+import (
+	"fmt"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	errorhandlerTool "github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/justdomepaul/gin-storage/storage/imaging"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"strings"
+)
+
+func (suite *StorageSuite) TestImage() {
+	testCases := []struct {
+		Label string
+		Query string
+		Want  string
+		Code  int
+	}{
+		{
+			Label: "Image cached",
+			Query: "path=test/a&w=50",
+			Want:  "12345",
+		},
+		{
+			Label: "Image clamped",
+			Query: "path=test/a&w=5000",
+			Want:  "1000",
+		},
+		{
+			Label: "Image size not allowed",
+			Query: "path=test/a&w=60",
+			Code:  http.StatusBadRequest,
+		},
+		{
+			Label: "Image not an image",
+			Query: "path=test/b&w=50",
+			Code:  http.StatusUnsupportedMediaType,
+		},
+		{
+			Label: "Image without size",
+			Query: "path=test/a",
+			Code:  http.StatusBadRequest,
+		},
+		{
+			Label: "Image unknown fit",
+			Query: "path=test/a&w=50&h=50&fit=stretch",
+			Code:  http.StatusBadRequest,
+		},
+		{
+			Label: "Image not exist",
+			Query: "path=test/c&w=50",
+			Code:  http.StatusNotFound,
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			testIFile := &testIFile{}
+			testIFile.On("Stat", mock.Anything, "test/a").Return(testFile{path: "test/a", generation: 2}, nil)
+			testIFile.On("Stat", mock.Anything, "test/b").Return(testFile{path: "test/b", generation: 3}, nil)
+			testIFile.On("Stat", mock.Anything, "test/c").Return(nil, errorhandlerTool.ErrFileNotExist)
+			testIFile.On("Download", mock.Anything, "test/a@w50", int64(0)).
+				Return(testFile{path: "test/a@w50", metadata: map[string]string{imaging.SourceGenerationKey: "2"}}, io.NopCloser(strings.NewReader("12345")), nil)
+			testIFile.On("Download", mock.Anything, "test/a@w1000", int64(0)).
+				Return(testFile{path: "test/a@w1000", metadata: map[string]string{imaging.SourceGenerationKey: "2"}}, io.NopCloser(strings.NewReader("1000")), nil)
+			testIFile.On("Download", mock.Anything, "test/b@w50", int64(0)).Return(nil, nil, errorhandlerTool.ErrFileNotExist)
+			testIFile.On("Download", mock.Anything, "test/b", int64(3)).Return(testFile{path: "test/b"}, io.NopCloser(strings.NewReader("plain text")), nil)
+			storage.Register(imaging.NewImaging(config.Media{}, config.Image{ImageMaxDimension: 1000, ImageSizes: []int{50, 1000}, ImageMaxBytes: 1 << 20}, testIFile), func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			resp, err := Get("/storage/image?"+tc.Query, map[string]string{}, route)
+			if tc.Code != 0 {
+				suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Code), tc.Label)
+				return
+			}
+			suite.NoError(err, tc.Label)
+			suite.Equal(tc.Want, string(resp), tc.Label)
+		}()
+	}
+}
+
+func (suite *StorageSuite) TestImageNotEnable() {
+	storage.Register(&testIFile{}, func() {})
+	defer storage.Unload()
+	route := NewMockGinServer()
+	Register(route)
+
+	_, err := Get("/storage/image?path=test/a&w=50", map[string]string{}, route)
+	suite.EqualError(err, fmt.Sprintf("request error by code: %d", http.StatusNotFound))
+}
@@ -0,0 +1,108 @@
+package gin_storage
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+)
+
+// UploadInfo describes a file being uploaded through the handlers.
+type UploadInfo struct {
+	Filename    string
+	Prefix      string
+	Size        int64
+	ContentType string
+}
+
+// IUploadInterceptor runs around uploads. BeforeUpload may replace the content by
+// the returned reader, an error rejects the upload. AfterUpload runs once stored.
+type IUploadInterceptor interface {
+	BeforeUpload(ctx context.Context, info UploadInfo, reader io.Reader) (io.Reader, error)
+	AfterUpload(ctx context.Context, path string, info UploadInfo)
+}
+
+// IRemoveInterceptor runs around the removal of a path, an error from BeforeRemove rejects it.
+// path is a removed, moved or purged object, or a folder ending with a slash when a folder or
+// prefix is removed or renamed as a whole.
+type IRemoveInterceptor interface {
+	BeforeRemove(ctx context.Context, path string) error
+	AfterRemove(ctx context.Context, path string)
+}
+
+// IPublicizeInterceptor runs around publicizing a path, an error from BeforePublicize rejects it.
+type IPublicizeInterceptor interface {
+	BeforePublicize(ctx context.Context, path string) error
+	AfterPublicize(ctx context.Context, path, url string)
+}
+
+// Interceptors run in order around the handlers, the first error stops the chain.
+// The trash purger expiring objects in the background is not intercepted.
+type Interceptors struct {
+	Upload    []IUploadInterceptor
+	Remove    []IRemoveInterceptor
+	Publicize []IPublicizeInterceptor
+}
+
+func (i Interceptors) beforeUpload(ctx context.Context, info UploadInfo, f io.ReadCloser) (io.ReadCloser, error) {
+	var reader io.Reader = f
+	for _, interceptor := range i.Upload {
+		var err error
+		if reader, err = interceptor.BeforeUpload(ctx, info, reader); err != nil {
+			return nil, err
+		}
+	}
+	if reader == io.Reader(f) {
+		return f, nil
+	}
+	return readCloser{Reader: reader, Closer: f}, nil
+}
+
+func (i Interceptors) afterUpload(ctx context.Context, path string, info UploadInfo) {
+	for _, interceptor := range i.Upload {
+		interceptor.AfterUpload(ctx, path, info)
+	}
+}
+
+func (i Interceptors) beforeRemove(ctx context.Context, path string) error {
+	for _, interceptor := range i.Remove {
+		if err := interceptor.BeforeRemove(ctx, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i Interceptors) afterRemove(ctx context.Context, path string) {
+	for _, interceptor := range i.Remove {
+		interceptor.AfterRemove(ctx, path)
+	}
+}
+
+func (i Interceptors) beforePublicize(ctx context.Context, path string) error {
+	for _, interceptor := range i.Publicize {
+		if err := interceptor.BeforePublicize(ctx, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i Interceptors) afterPublicize(ctx context.Context, path, url string) {
+	for _, interceptor := range i.Publicize {
+		interceptor.AfterPublicize(ctx, path, url)
+	}
+}
+
+func newUploadInfo(prefix string, file *multipart.FileHeader) UploadInfo {
+	return UploadInfo{
+		Filename:    file.Filename,
+		Prefix:      prefix,
+		Size:        file.Size,
+		ContentType: file.Header.Get("Content-Type"),
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,243 @@
+package gin_storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	errorhandlerTool "github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// testInterceptor records its calls and rejects every operation by reject.
+type testInterceptor struct {
+	reject error
+	calls  []string
+}
+
+func (t *testInterceptor) BeforeUpload(ctx context.Context, info UploadInfo, reader io.Reader) (io.Reader, error) {
+	t.calls = append(t.calls, "before upload "+info.Prefix+" "+info.ContentType)
+	if t.reject != nil {
+		return nil, t.reject
+	}
+	return strings.NewReader("replaced"), nil
+}
+
+func (t *testInterceptor) AfterUpload(ctx context.Context, path string, info UploadInfo) {
+	t.calls = append(t.calls, "after upload "+path)
+}
+
+func (t *testInterceptor) BeforeRemove(ctx context.Context, path string) error {
+	t.calls = append(t.calls, "before remove "+path)
+	return t.reject
+}
+
+func (t *testInterceptor) AfterRemove(ctx context.Context, path string) {
+	t.calls = append(t.calls, "after remove "+path)
+}
+
+func (t *testInterceptor) BeforePublicize(ctx context.Context, path string) error {
+	t.calls = append(t.calls, "before publicize "+path)
+	return t.reject
+}
+
+func (t *testInterceptor) AfterPublicize(ctx context.Context, path, url string) {
+	t.calls = append(t.calls, "after publicize "+path+" "+url)
+}
+
+func (suite *StorageSuite) TestUploadInterceptor() {
+	testCases := []struct {
+		Label string
+		Error error
+		Calls []string
+		Code  int
+	}{
+		{
+			Label: "Upload intercepted",
+			Calls: []string{"before upload test image/png", "after upload test/testPath"},
+		},
+		{
+			Label: "Upload rejected",
+			Error: errorhandlerTool.ErrRejected,
+			Calls: []string{"before upload test image/png"},
+			Code:  http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			f, errOpen := os.Open("./storage/cloud/image.png")
+			suite.NoError(errOpen)
+			defer f.Close()
+
+			var content []byte
+			testIFile := &testIFile{}
+			testIFile.On("Upload", mock.Anything, "test", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				content, _ = io.ReadAll(args.Get(2).(io.Reader))
+			}).Return("test/testPath", nil)
+			storage.Register(testIFile, func() {})
+			defer storage.Unload()
+			interceptor := &testInterceptor{reject: tc.Error}
+			route := NewMockGinServer()
+			RegisterWithInterceptors(route, Interceptors{Upload: []IUploadInterceptor{interceptor}})
+
+			_, err := PostFile("/storage", map[string]io.Reader{"file": f, "prefix": strings.NewReader("test")}, map[string]string{}, route)
+			suite.Equal(tc.Calls, interceptor.calls, tc.Label)
+			if tc.Code != 0 {
+				suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Code), tc.Label)
+				testIFile.AssertNotCalled(suite.T(), "Upload", mock.Anything, "test", mock.Anything, mock.Anything)
+				return
+			}
+			suite.NoError(err, tc.Label)
+			suite.Equal("replaced", string(content), tc.Label)
+		}()
+	}
+}
+
+func (suite *StorageSuite) TestRemoveInterceptor() {
+	testCases := []struct {
+		Label  string
+		Error  error
+		Calls  []string
+		Status string
+		Code   int
+	}{
+		{
+			Label:  "Remove intercepted",
+			Calls:  []string{"before remove test/a", "after remove test/a"},
+			Status: RemoveStatusRemoved,
+		},
+		{
+			Label:  "Remove rejected",
+			Error:  errorhandlerTool.ErrRejected,
+			Calls:  []string{"before remove test/a"},
+			Status: RemoveStatusError,
+			Code:   http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			testIFile := &testIFile{}
+			testIFile.On("Remove", mock.Anything, "test/a").Return(nil)
+			storage.Register(testIFile, func() {})
+			defer storage.Unload()
+			interceptor := &testInterceptor{reject: tc.Error}
+			route := NewMockGinServer()
+			RegisterWithInterceptors(route, Interceptors{Remove: []IRemoveInterceptor{interceptor}})
+
+			_, err := DeleteJSON("/storage?path=test/a", map[string]interface{}{}, map[string]string{}, route)
+			if tc.Code != 0 {
+				suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Code), tc.Label)
+			} else {
+				suite.NoError(err, tc.Label)
+			}
+			suite.Equal(tc.Calls, interceptor.calls, tc.Label)
+
+			interceptor.calls = nil
+			resp, err := DeleteJSON("/storage/multiple", map[string]interface{}{
+				"paths": []map[string]interface{}{{"filename": "a", "path": "test/a"}},
+			}, map[string]string{}, route)
+			suite.NoError(err, tc.Label)
+			var results []RemoveResult
+			suite.NoError(json.Unmarshal(resp, &results), tc.Label)
+			suite.Equal(tc.Status, results[0].Status, tc.Label)
+			suite.Equal(tc.Calls, interceptor.calls, tc.Label)
+		}()
+	}
+}
+
+func (suite *StorageSuite) TestBulkRemoveInterceptor() {
+	testCases := []struct {
+		Label string
+		Error error
+		Calls []string
+		Code  int
+	}{
+		{
+			Label: "Bulk removes intercepted",
+			Calls: []string{
+				"before remove test/folder/", "after remove test/folder/",
+				"before remove test/folder/", "after remove test/folder/",
+				"before remove test/a", "after remove test/a",
+			},
+		},
+		{
+			Label: "Bulk removes rejected",
+			Error: errorhandlerTool.ErrRejected,
+			Calls: []string{"before remove test/folder/", "before remove test/folder/", "before remove test/a"},
+			Code:  http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			testIFile := &testIFile{}
+			testIFile.On("RemoveFolder", mock.Anything, "test/folder", false).Return(2, nil)
+			testIFile.On("RenameFolder", mock.Anything, "test/folder", "test/other", mock.Anything).Return(2, nil)
+			testIFile.On("Move", mock.Anything, "test/a", "test/b", false).Return(nil)
+			storage.Register(testIFile, func() {})
+			defer storage.Unload()
+			interceptor := &testInterceptor{reject: tc.Error}
+			route := NewMockGinServer()
+			RegisterWithInterceptors(route, Interceptors{Remove: []IRemoveInterceptor{interceptor}})
+
+			_, errRemove := DeleteJSON("/storage/folder?path=test/folder", map[string]interface{}{}, map[string]string{}, route)
+			_, errRename := PostJSON("/storage/folder/rename", map[string]interface{}{"src": "test/folder", "dst": "test/other"}, map[string]string{}, route)
+			_, errMove := PostJSON("/storage/move", map[string]interface{}{"src": "test/a", "dst": "test/b"}, map[string]string{}, route)
+			suite.Equal(tc.Calls, interceptor.calls, tc.Label)
+			for _, err := range []error{errRemove, errRename, errMove} {
+				if tc.Code != 0 {
+					suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Code), tc.Label)
+					continue
+				}
+				suite.NoError(err, tc.Label)
+			}
+			if tc.Code != 0 {
+				testIFile.AssertNotCalled(suite.T(), "RemoveFolder", mock.Anything, "test/folder", false)
+				testIFile.AssertNotCalled(suite.T(), "Move", mock.Anything, "test/a", "test/b", false)
+			}
+		}()
+	}
+}
+
+func (suite *StorageSuite) TestPublicizeInterceptor() {
+	testCases := []struct {
+		Label string
+		Error error
+		Calls []string
+		Code  int
+	}{
+		{
+			Label: "Publicize intercepted",
+			Calls: []string{"before publicize test/a", "after publicize test/a https://example.com/a"},
+		},
+		{
+			Label: "Publicize rejected",
+			Error: errorhandlerTool.ErrRejected,
+			Calls: []string{"before publicize test/a"},
+			Code:  http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			testIFile := &testIFile{}
+			testIFile.On("GetURL", mock.Anything, "test/a").Return("https://example.com/a", nil)
+			storage.Register(testIFile, func() {})
+			defer storage.Unload()
+			interceptor := &testInterceptor{reject: tc.Error}
+			route := NewMockGinServer()
+			RegisterWithInterceptors(route, Interceptors{Publicize: []IPublicizeInterceptor{interceptor}})
+
+			_, err := PutJSON("/storage", map[string]interface{}{"path": "test/a"}, map[string]string{}, route)
+			suite.Equal(tc.Calls, interceptor.calls, tc.Label)
+			if tc.Code != 0 {
+				suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Code), tc.Label)
+				testIFile.AssertNotCalled(suite.T(), "GetURL", mock.Anything, "test/a")
+				return
+			}
+			suite.NoError(err, tc.Label)
+		}()
+	}
+}
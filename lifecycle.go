@@ -0,0 +1,47 @@
+package gin_storage
+
+import (
+	"encoding/json"
+	"github.com/cockroachdb/errors"
+	"github.com/gin-gonic/gin"
+	errorhandlerTool "github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/justdomepaul/toolbox/errorhandler"
+	"net/http"
+)
+
+func (fh FileHandler) lifecycle() storage.ILifecycle {
+	l, ok := storage.Unwrap[storage.ILifecycle](fh.storage)
+	if !ok {
+		panic(errorhandler.NewErrNotFound(errorhandlerTool.ErrLifecycleNotEnable))
+	}
+	return l
+}
+
+func (fh FileHandler) GetLifecycle(c *gin.Context) {
+	lifecycle, err := fh.lifecycle().Lifecycle(c)
+	if err != nil {
+		panic(errorhandler.NewErrDBExecute(err))
+	}
+	if lifecycle.Rules == nil {
+		lifecycle.Rules = []storage.LifecycleRule{}
+	}
+	c.JSON(http.StatusOK, lifecycle)
+}
+
+// SetLifecycle replaces the lifecycle rules of the bucket.
+func (fh FileHandler) SetLifecycle(c *gin.Context) {
+	l := fh.lifecycle()
+	req := storage.Lifecycle{}
+	defer c.Request.Body.Close()
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		panic(errorhandler.NewErrJSONUnmarshal(err))
+	}
+	if err := l.SetLifecycle(c, req); err != nil {
+		if errors.Is(err, errorhandlerTool.ErrLifecycleRule) {
+			panic(errorhandler.NewErrVariable(err))
+		}
+		panic(errorhandler.NewErrDBExecute(err))
+	}
+	c.String(http.StatusOK, "ok")
+}
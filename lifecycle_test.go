@@ -0,0 +1,66 @@
+package gin_storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/justdomepaul/gin-storage/storage/lifecycle"
+	"net/http"
+)
+
+func (suite *StorageSuite) TestLifecycle() {
+	evaluator, err := lifecycle.NewEvaluator(config.Media{}, config.Lifecycle{}, &testIFile{})
+	suite.NoError(err)
+	storage.Register(evaluator, func() {})
+	defer storage.Unload()
+	route := NewMockGinServer()
+	Register(route)
+
+	testCases := []struct {
+		Label string
+		Rules []map[string]interface{}
+		Want  []storage.LifecycleRule
+		Code  int
+	}{
+		{
+			Label: "Set delete rule",
+			Rules: []map[string]interface{}{{"action": "Delete", "age_in_days": 30}},
+			Want:  []storage.LifecycleRule{{Action: storage.LifecycleDelete, AgeInDays: 30}},
+		},
+		{
+			Label: "Set invalid rule",
+			Rules: []map[string]interface{}{{"action": "Delete"}},
+			Want:  []storage.LifecycleRule{{Action: storage.LifecycleDelete, AgeInDays: 30}},
+			Code:  http.StatusBadRequest,
+		},
+		{
+			Label: "Remove rules",
+			Rules: []map[string]interface{}{},
+			Want:  []storage.LifecycleRule{},
+		},
+	}
+	for _, tc := range testCases {
+		_, err := PutJSON("/storage/lifecycle", map[string]interface{}{"rules": tc.Rules}, map[string]string{}, route)
+		if tc.Code != 0 {
+			suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Code), tc.Label)
+		} else {
+			suite.NoError(err, tc.Label)
+		}
+		resp, err := Get("/storage/lifecycle", map[string]string{}, route)
+		suite.NoError(err, tc.Label)
+		var result storage.Lifecycle
+		suite.NoError(json.Unmarshal(resp, &result), tc.Label)
+		suite.Equal(tc.Want, result.Rules, tc.Label)
+	}
+}
+
+func (suite *StorageSuite) TestLifecycleNotEnable() {
+	storage.Register(&testIFile{}, func() {})
+	defer storage.Unload()
+	route := NewMockGinServer()
+	Register(route)
+
+	_, err := Get("/storage/lifecycle", map[string]string{}, route)
+	suite.EqualError(err, fmt.Sprintf("request error by code: %d", http.StatusNotFound))
+}
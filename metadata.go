@@ -0,0 +1,106 @@
+package gin_storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	errorhandlerTool "github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/justdomepaul/gin-storage/storage/expiry"
+	"github.com/justdomepaul/toolbox/errorhandler"
+	"github.com/kelseyhightower/envconfig"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func newMetadataConfig() config.Metadata {
+	env := config.Metadata{}
+	if err := envconfig.Process("", &env); err != nil {
+		panic(fmt.Errorf("%w: %s", errorhandlerTool.ErrInitialFileClient, err.Error()))
+	}
+	return env
+}
+
+// uploadAttrs collects the form fields named with the metadata prefix as the
+// metadata of the uploaded objects, expires_in adds the expiry of a temporary upload.
+func (fh FileHandler) uploadAttrs(values map[string][]string) []storage.UploadAttrs {
+	metadata := map[string]string{}
+	for key, value := range values {
+		if !strings.HasPrefix(key, fh.metadata.MetadataPrefix) || len(value) == 0 {
+			continue
+		}
+		metadata[strings.TrimPrefix(key, fh.metadata.MetadataPrefix)] = value[0]
+	}
+	if ttl := fh.expiresIn(values); ttl > 0 {
+		metadata[fh.expiry.ExpiryMetadataKey] = expiry.Format(time.Now().Add(ttl))
+	}
+	if len(metadata) == 0 {
+		return nil
+	}
+	fh.validateMetadata(metadata)
+	return []storage.UploadAttrs{{Metadata: metadata}}
+}
+
+func (fh FileHandler) validateMetadata(metadata map[string]string) {
+	size := 0
+	for key, value := range metadata {
+		if key == "" {
+			panic(errorhandler.NewErrVariable(fmt.Errorf("%w: empty metadata key", errorhandlerTool.ErrInvalidQuery)))
+		}
+		size += len(key) + len(value)
+	}
+	if size > fh.metadata.MetadataMaxBytes {
+		panic(errorhandler.NewErrVariable(fmt.Errorf("%w: metadata larger than %d bytes", errorhandlerTool.ErrInvalidQuery, fh.metadata.MetadataMaxBytes)))
+	}
+}
+
+// Stat responds the live object at path.
+func (fh FileHandler) Stat(c *gin.Context) {
+	req := struct {
+		Path string `validate:"required"`
+	}{
+		Path: c.Query("path"),
+	}
+	if err := validator.New().Struct(&req); err != nil {
+		panic(errorhandler.NewErrVariable(err))
+	}
+	file, err := fh.storage.Stat(c, req.Path)
+	if err != nil {
+		panic(reportError(err))
+	}
+	fh.respondFile(c, file)
+}
+
+// UpdateMetadata merges the metadata of the request into the object, an empty value
+// removes its key and an empty metadata clears it.
+func (fh FileHandler) UpdateMetadata(c *gin.Context) {
+	req := struct {
+		Path     string            `json:"path,omitempty" validate:"required"`
+		Metadata map[string]string `json:"metadata"`
+	}{}
+	defer c.Request.Body.Close()
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		panic(errorhandler.NewErrJSONUnmarshal(err))
+	}
+	if err := validator.New().Struct(&req); err != nil {
+		panic(errorhandler.NewErrVariable(err))
+	}
+	fh.validateMetadata(req.Metadata)
+	file, err := fh.storage.UpdateMetadata(c, req.Path, req.Metadata, preconditions(c)...)
+	if err != nil {
+		panic(reportError(err))
+	}
+	fh.respondFile(c, file)
+}
+
+func (fh FileHandler) respondFile(c *gin.Context, file storage.File) {
+	item, err := NewFileV1(file)
+	if err != nil {
+		panic(errorhandler.NewErrExecute(err))
+	}
+	c.Header("ETag", etag(item.Generation))
+	c.JSON(http.StatusOK, item)
+}
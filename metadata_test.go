@@ -0,0 +1,207 @@
+package gin_storage
+
+import (
+	"encoding/json"
+	"fmt"
+	errorhandlerTool "github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+)
+
+func (suite *StorageSuite) TestUploadMetadata() {
+	testCases := []struct {
+		Label string
+		Forms map[string]string
+		Attrs []storage.UploadAttrs
+		Code  int
+	}{
+		{
+			Label: "Upload with metadata",
+			Forms: map[string]string{"meta_owner": "42", "meta_type": "invoice", "other": "skip"},
+			Attrs: []storage.UploadAttrs{{Metadata: map[string]string{"owner": "42", "type": "invoice"}}},
+		},
+		{
+			Label: "Upload with filename metadata",
+			Forms: map[string]string{"meta_filename": "invoice.pdf"},
+			Attrs: []storage.UploadAttrs{{Metadata: map[string]string{"filename": "invoice.pdf"}}},
+		},
+		{
+			Label: "Upload with empty metadata key",
+			Forms: map[string]string{"meta_": "42"},
+			Code:  http.StatusBadRequest,
+		},
+		{
+			Label: "Upload with metadata over limit",
+			Forms: map[string]string{"meta_owner": strings.Repeat("x", 8192)},
+			Code:  http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			f, errOpen := os.Open("./storage/cloud/image.png")
+			suite.NoError(errOpen)
+			defer f.Close()
+
+			testIFile := &testIFile{}
+			testIFile.On("Upload", mock.Anything, "test", mock.Anything, mock.MatchedBy(func(attrs []storage.UploadAttrs) bool {
+				metadata := storage.MergeUploadAttrs(attrs...).Metadata
+				// the original filename is recorded unless the form sets it
+				if _, exist := tc.Attrs[0].Metadata["filename"]; !exist {
+					if !strings.HasSuffix(metadata["filename"], ".txt") {
+						return false
+					}
+					delete(metadata, "filename")
+				}
+				return reflect.DeepEqual(tc.Attrs[0].Metadata, metadata)
+			})).Return("test/testPath", nil)
+			storage.Register(testIFile, func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			forms := map[string]io.Reader{
+				"file":   f,
+				"prefix": strings.NewReader("test"),
+			}
+			for key, value := range tc.Forms {
+				forms[key] = strings.NewReader(value)
+			}
+			_, err := PostFile("/storage", forms, map[string]string{}, route)
+			if tc.Code != 0 {
+				suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Code), tc.Label)
+				return
+			}
+			suite.NoError(err, tc.Label)
+			testIFile.AssertExpectations(suite.T())
+		}()
+	}
+}
+
+func (suite *StorageSuite) TestStat() {
+	testCases := []struct {
+		Label string
+		Error error
+		Code  int
+	}{
+		{
+			Label: "Stat object",
+		},
+		{
+			Label: "Stat not exist",
+			Error: errorhandlerTool.ErrFileNotExist,
+			Code:  http.StatusNotFound,
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			testIFile := &testIFile{}
+			if tc.Error != nil {
+				testIFile.On("Stat", mock.Anything, "test/a").Return(nil, tc.Error)
+			} else {
+				testIFile.On("Stat", mock.Anything, "test/a").
+					Return(testFile{path: "test/a", generation: 2, metadata: map[string]string{"owner": "42"}}, nil)
+			}
+			storage.Register(testIFile, func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			resp, err := Get("/storage/stat?path=test/a", map[string]string{}, route)
+			if tc.Code != 0 {
+				suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Code), tc.Label)
+				return
+			}
+			suite.NoError(err, tc.Label)
+			var result FileV1
+			suite.NoError(json.Unmarshal(resp, &result))
+			suite.Equal(map[string]string{"owner": "42"}, result.Metadata, tc.Label)
+			suite.Equal(int64(2), result.Generation, tc.Label)
+		}()
+	}
+}
+
+func (suite *StorageSuite) TestUpdateMetadata() {
+	testCases := []struct {
+		Label    string
+		Metadata map[string]string
+		Headers  map[string]string
+		Conds    []storage.Precondition
+		Error    error
+		Code     int
+	}{
+		{
+			Label:    "Update metadata",
+			Metadata: map[string]string{"owner": "42"},
+		},
+		{
+			Label:    "Clear metadata",
+			Metadata: map[string]string{},
+		},
+		{
+			Label:    "Update metadata at stale generation",
+			Metadata: map[string]string{"owner": "42"},
+			Headers:  map[string]string{"If-Match": "1"},
+			Conds:    []storage.Precondition{storage.IfGenerationMatch(1)},
+			Error:    errorhandlerTool.ErrPreconditionFailed,
+			Code:     http.StatusPreconditionFailed,
+		},
+		{
+			Label:    "Update metadata with empty key",
+			Metadata: map[string]string{"": "42"},
+			Code:     http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			testIFile := &testIFile{}
+			call := testIFile.On("UpdateMetadata", mock.Anything, "test/a", tc.Metadata)
+			if tc.Conds != nil {
+				call = testIFile.On("UpdateMetadata", mock.Anything, "test/a", tc.Metadata, tc.Conds)
+			}
+			if tc.Error != nil {
+				call.Return(nil, tc.Error)
+			} else {
+				call.Return(testFile{path: "test/a", metadata: tc.Metadata}, nil)
+			}
+			storage.Register(testIFile, func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			headers := tc.Headers
+			if headers == nil {
+				headers = map[string]string{}
+			}
+			jsonByte, _ := json.Marshal(map[string]interface{}{"path": "test/a", "metadata": tc.Metadata})
+			req, _ := http.NewRequest(http.MethodPatch, "/storage/meta", strings.NewReader(string(jsonByte)))
+			resp, err := getBody(req, headers, route)
+			if tc.Code != 0 {
+				suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Code), tc.Label)
+				return
+			}
+			suite.NoError(err, tc.Label)
+			var result FileV1
+			suite.NoError(json.Unmarshal(resp, &result))
+			suite.Equal(len(tc.Metadata), len(result.Metadata), tc.Label)
+		}()
+	}
+}
+
+func (suite *StorageSuite) TestListMetadata() {
+	testIFile := &testIFile{}
+	q := storage.WithFileMetadata(storage.Query{}, "owner", "42")
+	testIFile.On("ListPage", mock.Anything, q, mock.Anything).Return("", nil)
+	storage.Register(testIFile, func() {})
+	defer storage.Unload()
+	route := NewMockGinServer()
+	Register(route)
+
+	_, err := Get("/storage?meta_owner=42", map[string]string{}, route)
+	suite.NoError(err)
+	testIFile.AssertExpectations(suite.T())
+}
@@ -0,0 +1,7 @@
+package config
+
+// Archive type
+type Archive struct {
+	// ArchiveFilenameKey is the metadata key recording the original filename of an upload
+	ArchiveFilenameKey string `split_words:"true" default:"filename"`
+}
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"github.com/justdomepaul/toolbox/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"testing"
+)
+
+type ArchiveSuite struct {
+	suite.Suite
+}
+
+func (suite *ArchiveSuite) SetupSuite() {
+	t := suite.T()
+	os.Clearenv()
+	assert.NoError(t, os.Setenv("ARCHIVE_FILENAME_KEY", "original_name"))
+}
+
+func (suite *ArchiveSuite) TestDefaultOption() {
+	t := suite.T()
+	options := &Archive{}
+	suite.NoError(config.LoadFromEnv(options))
+	assert.Equal(t, "original_name", options.ArchiveFilenameKey)
+}
+
+func TestArchiveSuite(t *testing.T) {
+	suite.Run(t, new(ArchiveSuite))
+}
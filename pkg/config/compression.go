@@ -0,0 +1,8 @@
+package config
+
+// Compression type
+type Compression struct {
+	CompressionEncoding     string   `split_words:"true" default:"gzip"`
+	CompressionContentTypes []string `split_words:"true" default:"text/*,application/json,application/x-ndjson,application/xml,application/javascript"`
+	CompressionMinBytes     int      `split_words:"true" default:"1024"`
+}
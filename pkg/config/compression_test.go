@@ -0,0 +1,32 @@
+package config
+
+import (
+	"github.com/justdomepaul/toolbox/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"testing"
+)
+
+type CompressionSuite struct {
+	suite.Suite
+}
+
+func (suite *CompressionSuite) SetupSuite() {
+	t := suite.T()
+	os.Clearenv()
+	assert.NoError(t, os.Setenv("COMPRESSION_ENCODING", "zstd"))
+}
+
+func (suite *CompressionSuite) TestDefaultOption() {
+	t := suite.T()
+	options := &Compression{}
+	suite.NoError(config.LoadFromEnv(options))
+	assert.Equal(t, "zstd", options.CompressionEncoding)
+	assert.Equal(t, []string{"text/*", "application/json", "application/x-ndjson", "application/xml", "application/javascript"}, options.CompressionContentTypes)
+	assert.Equal(t, 1024, options.CompressionMinBytes)
+}
+
+func TestCompressionSuite(t *testing.T) {
+	suite.Run(t, new(CompressionSuite))
+}
@@ -0,0 +1,9 @@
+package config
+
+import "time"
+
+// Confirm type
+type Confirm struct {
+	ConfirmSecret string        `split_words:"true" default:""`
+	ConfirmTTL    time.Duration `split_words:"true" default:"5m"`
+}
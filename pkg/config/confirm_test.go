@@ -0,0 +1,34 @@
+package config
+
+import (
+	"github.com/justdomepaul/toolbox/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"testing"
+	"time"
+)
+
+type ConfirmSuite struct {
+	suite.Suite
+	ConfirmSecret string
+}
+
+func (suite *ConfirmSuite) SetupSuite() {
+	t := suite.T()
+	os.Clearenv()
+	suite.ConfirmSecret = "testSecret"
+	assert.NoError(t, os.Setenv("CONFIRM_SECRET", suite.ConfirmSecret))
+}
+
+func (suite *ConfirmSuite) TestDefaultOption() {
+	t := suite.T()
+	options := &Confirm{}
+	suite.NoError(config.LoadFromEnv(options))
+	assert.Equal(t, suite.ConfirmSecret, options.ConfirmSecret)
+	assert.Equal(t, 5*time.Minute, options.ConfirmTTL)
+}
+
+func TestConfirmSuite(t *testing.T) {
+	suite.Run(t, new(ConfirmSuite))
+}
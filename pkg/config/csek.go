@@ -0,0 +1,7 @@
+package config
+
+// CSEK type
+type CSEK struct {
+	CSEKKey        string            `split_words:"true" default:""`
+	CSEKTenantKeys map[string]string `split_words:"true"`
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"github.com/justdomepaul/toolbox/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"testing"
+)
+
+type CSEKSuite struct {
+	suite.Suite
+}
+
+func (suite *CSEKSuite) SetupSuite() {
+	t := suite.T()
+	os.Clearenv()
+	assert.NoError(t, os.Setenv("CSEK_KEY", "AAAA"))
+	assert.NoError(t, os.Setenv("CSEK_TENANT_KEYS", "tenant1:BBBB,tenant2:CCCC"))
+}
+
+func (suite *CSEKSuite) TestDefaultOption() {
+	t := suite.T()
+	options := &CSEK{}
+	suite.NoError(config.LoadFromEnv(options))
+	assert.Equal(t, "AAAA", options.CSEKKey)
+	assert.Equal(t, map[string]string{"tenant1": "BBBB", "tenant2": "CCCC"}, options.CSEKTenantKeys)
+}
+
+func TestCSEKSuite(t *testing.T) {
+	suite.Run(t, new(CSEKSuite))
+}
@@ -0,0 +1,9 @@
+package config
+
+// Encryption type
+type Encryption struct {
+	EncryptionKeys       map[string]string `split_words:"true"`
+	EncryptionCurrentKey string            `split_words:"true" default:""`
+	EncryptionKeysPath   string            `split_words:"true" default:""`
+	EncryptionChunkSize  int               `split_words:"true" default:"65536"`
+}
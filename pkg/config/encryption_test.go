@@ -0,0 +1,34 @@
+package config
+
+import (
+	"github.com/justdomepaul/toolbox/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"testing"
+)
+
+type EncryptionSuite struct {
+	suite.Suite
+}
+
+func (suite *EncryptionSuite) SetupSuite() {
+	t := suite.T()
+	os.Clearenv()
+	assert.NoError(t, os.Setenv("ENCRYPTION_KEYS", "k1:AAAA,k2:BBBB"))
+	assert.NoError(t, os.Setenv("ENCRYPTION_CURRENT_KEY", "k2"))
+}
+
+func (suite *EncryptionSuite) TestDefaultOption() {
+	t := suite.T()
+	options := &Encryption{}
+	suite.NoError(config.LoadFromEnv(options))
+	assert.Equal(t, map[string]string{"k1": "AAAA", "k2": "BBBB"}, options.EncryptionKeys)
+	assert.Equal(t, "k2", options.EncryptionCurrentKey)
+	assert.Equal(t, "", options.EncryptionKeysPath)
+	assert.Equal(t, 65536, options.EncryptionChunkSize)
+}
+
+func TestEncryptionSuite(t *testing.T) {
+	suite.Run(t, new(EncryptionSuite))
+}
@@ -0,0 +1,10 @@
+package config
+
+import "time"
+
+// Expiry type
+type Expiry struct {
+	ExpiryMetadataKey   string        `split_words:"true" default:"expires_at"`
+	ExpiryMaxTTL        time.Duration `split_words:"true" default:"0"`
+	ExpirySweepInterval time.Duration `split_words:"true" default:"10m"`
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"github.com/justdomepaul/toolbox/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"testing"
+	"time"
+)
+
+type ExpirySuite struct {
+	suite.Suite
+}
+
+func (suite *ExpirySuite) SetupSuite() {
+	t := suite.T()
+	os.Clearenv()
+	assert.NoError(t, os.Setenv("EXPIRY_MAX_TTL", "24h"))
+}
+
+func (suite *ExpirySuite) TestDefaultOption() {
+	t := suite.T()
+	options := &Expiry{}
+	suite.NoError(config.LoadFromEnv(options))
+	assert.Equal(t, "expires_at", options.ExpiryMetadataKey)
+	assert.Equal(t, 24*time.Hour, options.ExpiryMaxTTL)
+	assert.Equal(t, 10*time.Minute, options.ExpirySweepInterval)
+}
+
+func TestExpirySuite(t *testing.T) {
+	suite.Run(t, new(ExpirySuite))
+}
@@ -0,0 +1,13 @@
+package config
+
+// Image type
+type Image struct {
+	ImageWidths        []int `split_words:"true" default:"320,640"`
+	ImageMaxDimension  int   `split_words:"true" default:"4096"`
+	ImageMaxPixels     int   `split_words:"true" default:"50000000"`
+	ImageMaxBytes      int64 `split_words:"true" default:"33554432"`
+	ImageQuality       int   `split_words:"true" default:"85"`
+	ImageStripMetadata bool  `split_words:"true" default:"true"`
+	// ImageSizes are the widths and heights an image is resized to on demand, any up to ImageMaxDimension when empty
+	ImageSizes []int `split_words:"true" default:""`
+}
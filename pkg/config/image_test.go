@@ -0,0 +1,36 @@
+package config
+
+import (
+	"github.com/justdomepaul/toolbox/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"testing"
+)
+
+type ImageSuite struct {
+	suite.Suite
+}
+
+func (suite *ImageSuite) SetupSuite() {
+	t := suite.T()
+	os.Clearenv()
+	assert.NoError(t, os.Setenv("IMAGE_WIDTHS", "160,320,1280"))
+}
+
+func (suite *ImageSuite) TestDefaultOption() {
+	t := suite.T()
+	options := &Image{}
+	suite.NoError(config.LoadFromEnv(options))
+	assert.Equal(t, []int{160, 320, 1280}, options.ImageWidths)
+	assert.Equal(t, 4096, options.ImageMaxDimension)
+	assert.Empty(t, options.ImageSizes)
+	assert.Equal(t, 50000000, options.ImageMaxPixels)
+	assert.Equal(t, int64(33554432), options.ImageMaxBytes)
+	assert.Equal(t, 85, options.ImageQuality)
+	assert.True(t, options.ImageStripMetadata)
+}
+
+func TestImageSuite(t *testing.T) {
+	suite.Run(t, new(ImageSuite))
+}
@@ -0,0 +1,6 @@
+package config
+
+// Index type
+type Index struct {
+	IndexPath string `split_words:"true" default:""`
+}
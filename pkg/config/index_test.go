@@ -0,0 +1,30 @@
+package config
+
+import (
+	"github.com/justdomepaul/toolbox/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"testing"
+)
+
+type IndexSuite struct {
+	suite.Suite
+}
+
+func (suite *IndexSuite) SetupSuite() {
+	t := suite.T()
+	os.Clearenv()
+	assert.NoError(t, os.Setenv("INDEX_PATH", "/tmp/index.db"))
+}
+
+func (suite *IndexSuite) TestDefaultOption() {
+	t := suite.T()
+	options := &Index{}
+	suite.NoError(config.LoadFromEnv(options))
+	assert.Equal(t, "/tmp/index.db", options.IndexPath)
+}
+
+func TestIndexSuite(t *testing.T) {
+	suite.Run(t, new(IndexSuite))
+}
@@ -0,0 +1,9 @@
+package config
+
+import "time"
+
+// Lifecycle type
+type Lifecycle struct {
+	LifecycleRulesPath string        `split_words:"true" default:""`
+	LifecycleInterval  time.Duration `split_words:"true" default:"24h"`
+}
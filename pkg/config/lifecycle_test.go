@@ -0,0 +1,32 @@
+package config
+
+import (
+	"github.com/justdomepaul/toolbox/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"testing"
+	"time"
+)
+
+type LifecycleSuite struct {
+	suite.Suite
+}
+
+func (suite *LifecycleSuite) SetupSuite() {
+	t := suite.T()
+	os.Clearenv()
+	assert.NoError(t, os.Setenv("LIFECYCLE_RULES_PATH", "/tmp/lifecycle.json"))
+}
+
+func (suite *LifecycleSuite) TestDefaultOption() {
+	t := suite.T()
+	options := &Lifecycle{}
+	suite.NoError(config.LoadFromEnv(options))
+	assert.Equal(t, "/tmp/lifecycle.json", options.LifecycleRulesPath)
+	assert.Equal(t, 24*time.Hour, options.LifecycleInterval)
+}
+
+func TestLifecycleSuite(t *testing.T) {
+	suite.Run(t, new(LifecycleSuite))
+}
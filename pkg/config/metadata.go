@@ -0,0 +1,7 @@
+package config
+
+// Metadata type
+type Metadata struct {
+	MetadataPrefix   string `split_words:"true" default:"meta_"`
+	MetadataMaxBytes int    `split_words:"true" default:"8192"`
+}
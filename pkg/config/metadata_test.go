@@ -0,0 +1,31 @@
+package config
+
+import (
+	"github.com/justdomepaul/toolbox/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"testing"
+)
+
+type MetadataSuite struct {
+	suite.Suite
+}
+
+func (suite *MetadataSuite) SetupSuite() {
+	t := suite.T()
+	os.Clearenv()
+	assert.NoError(t, os.Setenv("METADATA_PREFIX", "x-meta-"))
+}
+
+func (suite *MetadataSuite) TestDefaultOption() {
+	t := suite.T()
+	options := &Metadata{}
+	suite.NoError(config.LoadFromEnv(options))
+	assert.Equal(t, "x-meta-", options.MetadataPrefix)
+	assert.Equal(t, 8192, options.MetadataMaxBytes)
+}
+
+func TestMetadataSuite(t *testing.T) {
+	suite.Run(t, new(MetadataSuite))
+}
@@ -0,0 +1,8 @@
+package config
+
+// Quota type
+type Quota struct {
+	QuotaMaxBytes   int64  `split_words:"true" default:"0"`
+	QuotaMaxObjects int64  `split_words:"true" default:"0"`
+	QuotaStorePath  string `split_words:"true" default:""`
+}
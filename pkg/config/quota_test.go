@@ -0,0 +1,36 @@
+package config
+
+import (
+	"github.com/justdomepaul/toolbox/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"testing"
+)
+
+type QuotaSuite struct {
+	suite.Suite
+	QuotaStorePath string
+}
+
+func (suite *QuotaSuite) SetupSuite() {
+	t := suite.T()
+	os.Clearenv()
+	suite.QuotaStorePath = "/tmp/usage.json"
+	assert.NoError(t, os.Setenv("QUOTA_MAX_BYTES", "1024"))
+	assert.NoError(t, os.Setenv("QUOTA_MAX_OBJECTS", "10"))
+	assert.NoError(t, os.Setenv("QUOTA_STORE_PATH", suite.QuotaStorePath))
+}
+
+func (suite *QuotaSuite) TestDefaultOption() {
+	t := suite.T()
+	options := &Quota{}
+	suite.NoError(config.LoadFromEnv(options))
+	assert.Equal(t, int64(1024), options.QuotaMaxBytes)
+	assert.Equal(t, int64(10), options.QuotaMaxObjects)
+	assert.Equal(t, suite.QuotaStorePath, options.QuotaStorePath)
+}
+
+func TestQuotaSuite(t *testing.T) {
+	suite.Run(t, new(QuotaSuite))
+}
@@ -0,0 +1,13 @@
+package config
+
+import "time"
+
+// Scan type
+type Scan struct {
+	ScanNetwork          string        `split_words:"true" default:"tcp"`
+	ScanAddress          string        `split_words:"true" default:"127.0.0.1:3310"`
+	ScanTimeout          time.Duration `split_words:"true" default:"30s"`
+	ScanChunkSize        int           `split_words:"true" default:"65536"`
+	ScanQuarantinePrefix string        `split_words:"true" default:"quarantine"`
+	ScanSuspicious       []string      `split_words:"true" default:"Heuristics.,PUA."`
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"github.com/justdomepaul/toolbox/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"testing"
+	"time"
+)
+
+type ScanSuite struct {
+	suite.Suite
+}
+
+func (suite *ScanSuite) SetupSuite() {
+	t := suite.T()
+	os.Clearenv()
+	assert.NoError(t, os.Setenv("SCAN_NETWORK", "unix"))
+	assert.NoError(t, os.Setenv("SCAN_ADDRESS", "/run/clamav/clamd.ctl"))
+}
+
+func (suite *ScanSuite) TestDefaultOption() {
+	t := suite.T()
+	options := &Scan{}
+	suite.NoError(config.LoadFromEnv(options))
+	assert.Equal(t, "unix", options.ScanNetwork)
+	assert.Equal(t, "/run/clamav/clamd.ctl", options.ScanAddress)
+	assert.Equal(t, 30*time.Second, options.ScanTimeout)
+	assert.Equal(t, 65536, options.ScanChunkSize)
+	assert.Equal(t, "quarantine", options.ScanQuarantinePrefix)
+	assert.Equal(t, []string{"Heuristics.", "PUA."}, options.ScanSuspicious)
+}
+
+func TestScanSuite(t *testing.T) {
+	suite.Run(t, new(ScanSuite))
+}
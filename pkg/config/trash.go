@@ -0,0 +1,10 @@
+package config
+
+import "time"
+
+// Trash type
+type Trash struct {
+	TrashPrefix        string        `split_words:"true" default:".trash"`
+	TrashRetention     time.Duration `split_words:"true" default:"720h"`
+	TrashPurgeInterval time.Duration `split_words:"true" default:"1h"`
+}
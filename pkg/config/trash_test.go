@@ -0,0 +1,33 @@
+package config
+
+import (
+	"github.com/justdomepaul/toolbox/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"testing"
+	"time"
+)
+
+type TrashSuite struct {
+	suite.Suite
+}
+
+func (suite *TrashSuite) SetupSuite() {
+	t := suite.T()
+	os.Clearenv()
+	assert.NoError(t, os.Setenv("TRASH_RETENTION", "24h"))
+}
+
+func (suite *TrashSuite) TestDefaultOption() {
+	t := suite.T()
+	options := &Trash{}
+	suite.NoError(config.LoadFromEnv(options))
+	assert.Equal(t, ".trash", options.TrashPrefix)
+	assert.Equal(t, 24*time.Hour, options.TrashRetention)
+	assert.Equal(t, time.Hour, options.TrashPurgeInterval)
+}
+
+func TestTrashSuite(t *testing.T) {
+	suite.Run(t, new(TrashSuite))
+}
@@ -6,13 +6,46 @@ import (
 )
 
 var (
-	ErrFailCloseSession  = errors.New("fail to close connection")
-	ErrDriveNotExist     = errors.New("file drive not exist")
-	ErrFileNotExist      = errors.New("file not exist")
-	ErrFileUpload        = errors.New("fail to upload file")
-	ErrFailGenerateUUID  = fmt.Errorf("%w: fail to generate uuid", ErrFileUpload)
-	ErrFileUpdate        = errors.New("fail to update file")
-	ErrFileRemove        = errors.New("fail to remove file")
-	ErrGetFile           = errors.New("fail to get file")
-	ErrInitialFileClient = errors.New("fail to initial file client")
+	ErrFailCloseSession   = errors.New("fail to close connection")
+	ErrDriveNotExist      = errors.New("file drive not exist")
+	ErrFileNotExist       = errors.New("file not exist")
+	ErrFileUpload         = errors.New("fail to upload file")
+	ErrFailGenerateUUID   = fmt.Errorf("%w: fail to generate uuid", ErrFileUpload)
+	ErrFileUpdate         = errors.New("fail to update file")
+	ErrFileRemove         = errors.New("fail to remove file")
+	ErrRemoveNotExist     = fmt.Errorf("%w: file not exist", ErrFileRemove)
+	ErrGetFile            = errors.New("fail to get file")
+	ErrInitialFileClient  = errors.New("fail to initial file client")
+	ErrQuotaExceeded      = errors.New("storage quota exceeded")
+	ErrQuotaTooLarge      = fmt.Errorf("%w: file larger than remaining quota", ErrQuotaExceeded)
+	ErrQuotaNotEnable     = errors.New("storage quota not enable")
+	ErrUsageStore         = errors.New("fail to access usage store")
+	ErrInvalidQuery       = errors.New("invalid query")
+	ErrFileCopy           = errors.New("fail to copy file")
+	ErrFileExist          = errors.New("file already exist")
+	ErrFolderNotExist     = fmt.Errorf("%w: folder is empty", ErrFileNotExist)
+	ErrConfirmToken       = errors.New("invalid confirm token")
+	ErrVersionNotExist    = fmt.Errorf("%w: version not exist", ErrFileNotExist)
+	ErrPreconditionFailed = errors.New("precondition failed")
+	ErrIndexStore         = errors.New("fail to access index store")
+	ErrIndexNotEnable     = errors.New("storage index not enable")
+	ErrTrashNotEnable     = errors.New("storage trash not enable")
+	ErrTrashEntry         = fmt.Errorf("%w: not a trash entry", ErrFileNotExist)
+	ErrLifecycle          = errors.New("fail to access lifecycle")
+	ErrLifecycleRule      = errors.New("invalid lifecycle rule")
+	ErrLifecycleNotEnable = errors.New("storage lifecycle not enable")
+	ErrObjectLocked       = errors.New("object is locked")
+	ErrRetention          = errors.New("fail to access retention")
+	ErrImageProcess       = errors.New("fail to process image")
+	ErrImageFormat        = fmt.Errorf("%w: unsupported image format", ErrImageProcess)
+	ErrImageNotEnable     = errors.New("storage image not enable")
+	ErrRejected           = errors.New("operation rejected")
+	ErrChecksumMismatch   = fmt.Errorf("%w: checksum mismatch", ErrFileUpload)
+	ErrEncryption         = errors.New("fail to encrypt file")
+	ErrEncryptionKey      = fmt.Errorf("%w: key not exist", ErrEncryption)
+	ErrDecryption         = errors.New("fail to decrypt file")
+	ErrCompression        = errors.New("fail to compress file")
+	ErrScan               = errors.New("fail to scan file")
+	ErrInfected           = fmt.Errorf("%w: infected file", ErrRejected)
+	ErrQuarantined        = fmt.Errorf("%w: suspicious file quarantined", ErrRejected)
 )
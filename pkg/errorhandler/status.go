@@ -0,0 +1,58 @@
+package errorhandler
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	errorhandlerTool "github.com/justdomepaul/toolbox/errorhandler"
+	zapTool "github.com/justdomepaul/toolbox/zap"
+	"go.uber.org/zap"
+)
+
+const ErrHttpStatus = "errHttpStatus"
+
+// ErrStatus reports an error with an explicit HTTP status code, for the
+// cases the toolbox error types have no matching status for.
+type ErrStatus struct {
+	system string
+	code   int
+	err    error
+}
+
+func (e *ErrStatus) SetSystem(system string) errorhandlerTool.IErrorReport {
+	if e.system == "" {
+		e.system = system
+	}
+	return e
+}
+
+// GetName method
+func (e ErrStatus) GetName() string {
+	return ErrHttpStatus
+}
+
+func (e ErrStatus) GetError() error {
+	return e.err
+}
+
+func (e ErrStatus) GetCode() int {
+	return e.code
+}
+
+func (e ErrStatus) Error() string {
+	return fmt.Sprintln("[ERROR]:", e.err.Error())
+}
+
+func (e ErrStatus) Report(prefix string) {
+	zapTool.Logger.Warn(prefix, zap.Error(e.GetError()))
+}
+
+func (e ErrStatus) GinReport(c *gin.Context) {
+	c.AbortWithError(e.code, e.err)
+}
+
+func NewErrStatus(code int, err error) *ErrStatus {
+	return &ErrStatus{
+		code: code,
+		err:  err,
+	}
+}
@@ -0,0 +1,36 @@
+package errorhandler
+
+import (
+	"github.com/cockroachdb/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type StatusSuite struct {
+	suite.Suite
+}
+
+func (suite *StatusSuite) TestErrStatus() {
+	err := NewErrStatus(http.StatusInsufficientStorage, ErrQuotaExceeded)
+	suite.Equal(ErrHttpStatus, err.GetName())
+	suite.Equal(http.StatusInsufficientStorage, err.GetCode())
+	suite.True(errors.Is(err.GetError(), ErrQuotaExceeded))
+	suite.Equal(err, err.SetSystem("system"))
+	suite.Contains(err.Error(), ErrQuotaExceeded.Error())
+}
+
+func (suite *StatusSuite) TestGinReport() {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	NewErrStatus(http.StatusRequestEntityTooLarge, ErrQuotaTooLarge).GinReport(c)
+	suite.Equal(http.StatusRequestEntityTooLarge, w.Code)
+	suite.True(c.IsAborted())
+}
+
+func TestStatusSuite(t *testing.T) {
+	suite.Run(t, new(StatusSuite))
+}
@@ -0,0 +1,47 @@
+package gin_storage
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/justdomepaul/toolbox/errorhandler"
+	"strconv"
+	"strings"
+)
+
+// preconditions reads the conditional headers of the request, If-Match takes the
+// generation of the target as a strong ETag and If-None-Match only takes *.
+func preconditions(c *gin.Context) []storage.Precondition {
+	var conds []storage.Precondition
+	if match := c.GetHeader("If-Match"); match != "" {
+		generation, err := strconv.ParseInt(strings.Trim(match, `"`), 10, 64)
+		if err != nil {
+			panic(errorhandler.NewErrVariable(err))
+		}
+		if generation <= 0 {
+			panic(errorhandler.NewErrVariable(fmt.Errorf("invalid If-Match: %s", match)))
+		}
+		conds = append(conds, storage.IfGenerationMatch(generation))
+	}
+	if noneMatch := c.GetHeader("If-None-Match"); noneMatch != "" {
+		if noneMatch != "*" || len(conds) > 0 {
+			panic(errorhandler.NewErrVariable(fmt.Errorf("invalid If-None-Match: %s", noneMatch)))
+		}
+		conds = append(conds, storage.IfDoesNotExist())
+	}
+	return conds
+}
+
+// sharedPreconditions reads the conditional headers of a request writing objects that have no single
+// generation to match, several objects or a generated name, If-Match is refused.
+func sharedPreconditions(c *gin.Context) []storage.Precondition {
+	conds := preconditions(c)
+	if storage.MergePreconditions(conds...).GenerationMatch != 0 {
+		panic(errorhandler.NewErrVariable(fmt.Errorf("If-Match needs a single existing object: %s", c.GetHeader("If-Match"))))
+	}
+	return conds
+}
+
+func etag(generation int64) string {
+	return `"` + strconv.FormatInt(generation, 10) + `"`
+}
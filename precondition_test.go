@@ -0,0 +1,261 @@
+package gin_storage
+
+import (
+	"encoding/json"
+	"fmt"
+	errorhandlerTool "github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+)
+
+func (suite *StorageSuite) TestRemovePrecondition() {
+	testCases := []struct {
+		Label   string
+		Headers map[string]string
+		Conds   []storage.Precondition
+		Error   error
+		Code    int
+	}{
+		{
+			Label:   "Remove at generation",
+			Headers: map[string]string{"If-Match": `"3"`},
+			Conds:   []storage.Precondition{storage.IfGenerationMatch(3)},
+		},
+		{
+			Label:   "Remove at stale generation",
+			Headers: map[string]string{"If-Match": "3"},
+			Conds:   []storage.Precondition{storage.IfGenerationMatch(3)},
+			Error:   errorhandlerTool.ErrPreconditionFailed,
+			Code:    http.StatusPreconditionFailed,
+		},
+		{
+			Label:   "Remove with weak If-Match",
+			Headers: map[string]string{"If-Match": `W/"3"`},
+			Code:    http.StatusBadRequest,
+		},
+		{
+			Label:   "Remove with If-Match zero",
+			Headers: map[string]string{"If-Match": "0"},
+			Code:    http.StatusBadRequest,
+		},
+		{
+			Label:   "Remove with If-None-Match etag",
+			Headers: map[string]string{"If-None-Match": `"3"`},
+			Code:    http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			testIFile := &testIFile{}
+			testIFile.On("Remove", mock.Anything, "test/a", tc.Conds).Return(tc.Error)
+			storage.Register(testIFile, func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			_, err := DeleteJSON("/storage?path=test/a", map[string]interface{}{}, tc.Headers, route)
+			if tc.Code != 0 {
+				suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Code), tc.Label)
+				return
+			}
+			suite.NoError(err, tc.Label)
+			testIFile.AssertExpectations(suite.T())
+		}()
+	}
+}
+
+func (suite *StorageSuite) TestTransferPrecondition() {
+	testCases := []struct {
+		Label   string
+		Headers map[string]string
+		Conds   []storage.Precondition
+		Error   error
+		Code    int
+	}{
+		{
+			Label:   "Copy onto generation",
+			Headers: map[string]string{"If-Match": "7"},
+			Conds:   []storage.Precondition{storage.IfGenerationMatch(7)},
+		},
+		{
+			Label:   "Copy onto absent",
+			Headers: map[string]string{"If-None-Match": "*"},
+			Conds:   []storage.Precondition{storage.IfDoesNotExist()},
+			Error:   errorhandlerTool.ErrPreconditionFailed,
+			Code:    http.StatusPreconditionFailed,
+		},
+		{
+			Label:   "Copy with both conditions",
+			Headers: map[string]string{"If-Match": "7", "If-None-Match": "*"},
+			Code:    http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			testIFile := &testIFile{}
+			testIFile.On("Copy", mock.Anything, "test/a", "test/b", false, tc.Conds).Return(tc.Error)
+			storage.Register(testIFile, func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			_, err := PostJSON("/storage/copy", map[string]interface{}{
+				"src": "test/a",
+				"dst": "test/b",
+			}, tc.Headers, route)
+			if tc.Code != 0 {
+				suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Code), tc.Label)
+				return
+			}
+			suite.NoError(err, tc.Label)
+			testIFile.AssertExpectations(suite.T())
+		}()
+	}
+}
+
+func (suite *StorageSuite) TestUploadPrecondition() {
+	testCases := []struct {
+		Label   string
+		URI     string
+		Headers map[string]string
+		Cond    storage.Precondition
+		Error   error
+		Code    int
+	}{
+		{
+			Label:   "Upload onto absent",
+			URI:     "/storage",
+			Headers: map[string]string{"If-None-Match": "*"},
+			Cond:    storage.IfDoesNotExist(),
+		},
+		{
+			Label:   "Upload onto taken name",
+			URI:     "/storage",
+			Headers: map[string]string{"If-None-Match": "*"},
+			Cond:    storage.IfDoesNotExist(),
+			Error:   errorhandlerTool.ErrPreconditionFailed,
+			Code:    http.StatusPreconditionFailed,
+		},
+		{
+			Label:   "Upload with If-Match",
+			URI:     "/storage",
+			Headers: map[string]string{"If-Match": "7"},
+			Code:    http.StatusBadRequest,
+		},
+		{
+			Label:   "Batch with If-Match",
+			URI:     "/storage/multiple",
+			Headers: map[string]string{"If-Match": "7"},
+			Code:    http.StatusBadRequest,
+		},
+		{
+			Label:   "Batch onto absent",
+			URI:     "/storage/multiple",
+			Headers: map[string]string{"If-None-Match": "*"},
+			Cond:    storage.IfDoesNotExist(),
+		},
+		{
+			Label:   "Upload with invalid If-Match",
+			URI:     "/storage",
+			Headers: map[string]string{"If-Match": "x"},
+			Code:    http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			f, errOpen := os.Open("./storage/cloud/image.png")
+			suite.NoError(errOpen)
+			defer f.Close()
+
+			testIFile := &testIFile{}
+			testIFile.On("Upload", mock.Anything, "test", mock.Anything, mock.MatchedBy(func(attrs []storage.UploadAttrs) bool {
+				return storage.MergeUploadAttrs(attrs...).Precondition == tc.Cond
+			})).Return("test/testPath", tc.Error)
+			storage.Register(testIFile, func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			var err error
+			if tc.URI == "/storage" {
+				_, err = PostFile(tc.URI, map[string]io.Reader{"file": f, "prefix": strings.NewReader("test")}, tc.Headers, route)
+			} else {
+				_, err = PostFiles(tc.URI, map[string][]io.Reader{"file[]": {f}, "prefix": {strings.NewReader("test")}}, tc.Headers, route)
+			}
+			if tc.Code != 0 {
+				suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Code), tc.Label)
+				return
+			}
+			suite.NoError(err, tc.Label)
+			testIFile.AssertExpectations(suite.T())
+		}()
+	}
+}
+
+func (suite *StorageSuite) TestMultipleTransferPrecondition() {
+	testIFile := &testIFile{}
+	testIFile.On("Move", mock.Anything, "test/a", "test/b", false, []storage.Precondition{storage.IfGenerationMatch(3)}).Return(nil)
+	testIFile.On("Move", mock.Anything, "test/c", "test/d", false, []storage.Precondition{storage.IfGenerationMatch(4)}).Return(errorhandlerTool.ErrPreconditionFailed)
+	testIFile.On("Move", mock.Anything, "test/e", "test/f", false, []storage.Precondition{storage.IfDoesNotExist()}).Return(errorhandlerTool.ErrFileExist)
+	testIFile.On("Move", mock.Anything, "test/g", "test/h", false, []storage.Precondition{storage.IfDoesNotExist()}).Return(nil)
+	storage.Register(testIFile, func() {})
+	defer storage.Unload()
+	route := NewMockGinServer()
+	Register(route)
+
+	resp, err := PostJSON("/storage/move/multiple", map[string]interface{}{
+		"paths": []map[string]interface{}{
+			{"src": "test/a", "dst": "test/b", "generation": 3},
+			{"src": "test/c", "dst": "test/d", "generation": 4},
+		},
+	}, map[string]string{}, route)
+	suite.NoError(err)
+	var results []TransferResult
+	suite.NoError(json.Unmarshal(resp, &results))
+	suite.Equal([]string{TransferStatusDone, TransferStatusPreconditionFailed}, []string{results[0].Status, results[1].Status})
+	suite.Empty(results[0].Error)
+	suite.NotEmpty(results[1].Error)
+
+	resp, err = PostJSON("/storage/move/multiple", map[string]interface{}{
+		"paths": []map[string]interface{}{{"src": "test/e", "dst": "test/f"}, {"src": "test/g", "dst": "test/h"}},
+	}, map[string]string{"If-None-Match": "*"}, route)
+	suite.NoError(err)
+	results = nil
+	suite.NoError(json.Unmarshal(resp, &results))
+	suite.Equal([]TransferResult{
+		{Src: "test/e", Dst: "test/f", Status: TransferStatusExists, Error: errorhandlerTool.ErrFileExist.Error()},
+		{Src: "test/g", Dst: "test/h", Status: TransferStatusDone},
+	}, results)
+	testIFile.AssertExpectations(suite.T())
+
+	for label, headers := range map[string]map[string]string{
+		"If-Match on several objects":   {"If-Match": "3"},
+		"generation with If-None-Match": {"If-None-Match": "*"},
+	} {
+		_, err = PostJSON("/storage/copy/multiple", map[string]interface{}{
+			"paths": []map[string]interface{}{{"src": "test/a", "dst": "test/b", "generation": 3}},
+		}, headers, route)
+		suite.EqualError(err, fmt.Sprintf("request error by code: %d", http.StatusBadRequest), label)
+	}
+	testIFile.AssertNotCalled(suite.T(), "Copy", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *StorageSuite) TestDownloadETag() {
+	testIFile := &testIFile{}
+	testIFile.On("Download", mock.Anything, "test/a", int64(0)).
+		Return(testFile{path: "test/a", generation: 3}, io.NopCloser(strings.NewReader("12345")), nil)
+	storage.Register(testIFile, func() {})
+	defer storage.Unload()
+	route := NewMockGinServer()
+	Register(route)
+
+	w := httptest.NewRecorder()
+	route.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/storage/download?path=test/a", nil))
+	suite.Equal(http.StatusOK, w.Code)
+	suite.Equal(`"3"`, w.Header().Get("ETag"))
+}
@@ -0,0 +1,157 @@
+package gin_storage
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/cockroachdb/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	errorhandlerTool "github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/justdomepaul/toolbox/errorhandler"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	RemoveStatusRemoved  = "removed"
+	RemoveStatusNotFound = "not_found"
+	RemoveStatusLocked   = "locked"
+	RemoveStatusError    = "error"
+)
+
+type RemoveResult struct {
+	Filename string `json:"filename,omitempty"`
+	Path     string `json:"path"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+type RemovePrefix struct {
+	Prefix       string    `json:"prefix"`
+	Count        int       `json:"count"`
+	Removed      bool      `json:"removed"`
+	ConfirmToken string    `json:"confirm_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitempty"`
+}
+
+// MultipleRemove removes the listed paths concurrently and responds a result per
+// path. A request naming a prefix instead first responds the matched count with
+// a confirm token, the prefix is only removed once the token is sent back.
+func (fh FileHandler) MultipleRemove(c *gin.Context) {
+	req := struct {
+		Paths        []BatchFile `json:"paths,omitempty" validate:"required_without=Prefix,omitempty,min=1,dive"`
+		Prefix       string      `json:"prefix,omitempty" validate:"required_without=Paths,excluded_with=Paths"`
+		ConfirmToken string      `json:"confirm_token,omitempty"`
+	}{}
+	defer c.Request.Body.Close()
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		panic(errorhandler.NewErrJSONUnmarshal(err))
+	}
+	if err := validator.New().Struct(&req); err != nil {
+		panic(errorhandler.NewErrVariable(err))
+	}
+	if req.Prefix != "" {
+		fh.removePrefix(c, req.Prefix, req.ConfirmToken)
+		return
+	}
+	results := make([]RemoveResult, len(req.Paths))
+	_ = storage.ForEach(c, storage.DefaultConcurrency, len(req.Paths), func(ctx context.Context, i int) error {
+		results[i] = removeResult(req.Paths[i], fh.remove(ctx, req.Paths[i].Path))
+		return nil
+	})
+	c.JSON(http.StatusOK, results)
+}
+
+// remove removes path through the remove interceptors.
+func (fh FileHandler) remove(ctx context.Context, path string, conds ...storage.Precondition) error {
+	if err := fh.interceptors.beforeRemove(ctx, path); err != nil {
+		return err
+	}
+	if err := fh.storage.Remove(ctx, path, conds...); err != nil {
+		return err
+	}
+	fh.interceptors.afterRemove(ctx, path)
+	return nil
+}
+
+// removeFolder removes every object under path through the remove interceptors, which
+// see the folder as path with a trailing slash.
+func (fh FileHandler) removeFolder(ctx context.Context, path string) (int, error) {
+	folder := folderPath(path)
+	if err := fh.interceptors.beforeRemove(ctx, folder); err != nil {
+		return 0, err
+	}
+	count, err := fh.storage.RemoveFolder(ctx, path, false)
+	if err != nil {
+		return count, err
+	}
+	fh.interceptors.afterRemove(ctx, folder)
+	return count, nil
+}
+
+// move moves src to dst through the remove interceptors of src.
+func (fh FileHandler) move(ctx context.Context, src, dst string, overwrite bool, conds ...storage.Precondition) error {
+	if err := fh.interceptors.beforeRemove(ctx, src); err != nil {
+		return err
+	}
+	if err := fh.storage.Move(ctx, src, dst, overwrite, conds...); err != nil {
+		return err
+	}
+	fh.interceptors.afterRemove(ctx, src)
+	return nil
+}
+
+func folderPath(path string) string {
+	return strings.TrimSuffix(path, "/") + "/"
+}
+
+func removeResult(file BatchFile, err error) RemoveResult {
+	result := RemoveResult{
+		Filename: file.Filename,
+		Path:     file.Path,
+		Status:   RemoveStatusRemoved,
+	}
+	switch {
+	case err == nil:
+	case errors.Is(err, errorhandlerTool.ErrRemoveNotExist):
+		result.Status = RemoveStatusNotFound
+	case errors.Is(err, errorhandlerTool.ErrObjectLocked):
+		result.Status = RemoveStatusLocked
+		result.Error = err.Error()
+	default:
+		result.Status = RemoveStatusError
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func (fh FileHandler) removePrefix(c *gin.Context, prefix, token string) {
+	if token == "" {
+		count, err := fh.storage.RemoveFolder(c, prefix, true)
+		if err != nil {
+			panic(reportError(err))
+		}
+		token, expires := fh.confirmer.token(prefix, time.Now())
+		c.JSON(http.StatusOK, RemovePrefix{
+			Prefix:       prefix,
+			Count:        count,
+			ConfirmToken: token,
+			ExpiresAt:    expires,
+		})
+		return
+	}
+	if err := fh.confirmer.verify(prefix, token, time.Now()); err != nil {
+		panic(reportError(err))
+	}
+	count, err := fh.removeFolder(c, prefix)
+	if err != nil {
+		panic(reportError(err))
+	}
+	c.JSON(http.StatusOK, RemovePrefix{
+		Prefix:  prefix,
+		Count:   count,
+		Removed: true,
+	})
+}
@@ -0,0 +1,126 @@
+package gin_storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	errorhandlerTool "github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"time"
+)
+
+func (suite *StorageSuite) TestMultipleRemove() {
+	testIFile := &testIFile{}
+	testIFile.On("Remove", mock.Anything, "test/a.png").Return(nil)
+	testIFile.On("Remove", mock.Anything, "test/b.png").Return(fmt.Errorf("%w: b.png", errorhandlerTool.ErrRemoveNotExist))
+	testIFile.On("Remove", mock.Anything, "test/c.png").Return(fmt.Errorf("%w: denied", errorhandlerTool.ErrFileRemove))
+	testIFile.On("Remove", mock.Anything, "test/d.png").Return(fmt.Errorf("%w: test/d.png", errorhandlerTool.ErrObjectLocked))
+	storage.Register(testIFile, func() {})
+	defer storage.Unload()
+	route := NewMockGinServer()
+	Register(route)
+
+	resp, err := DeleteJSON("/storage/multiple", map[string]interface{}{
+		"paths": []BatchFile{
+			{Filename: "a.png", Path: "test/a.png"},
+			{Filename: "b.png", Path: "test/b.png"},
+			{Filename: "c.png", Path: "test/c.png"},
+			{Filename: "d.png", Path: "test/d.png"},
+		},
+	}, map[string]string{}, route)
+	suite.NoError(err)
+	var results []RemoveResult
+	suite.NoError(json.Unmarshal(resp, &results))
+	suite.Equal([]RemoveResult{
+		{Filename: "a.png", Path: "test/a.png", Status: RemoveStatusRemoved},
+		{Filename: "b.png", Path: "test/b.png", Status: RemoveStatusNotFound},
+		{Filename: "c.png", Path: "test/c.png", Status: RemoveStatusError, Error: "fail to remove file: denied"},
+		{Filename: "d.png", Path: "test/d.png", Status: RemoveStatusLocked, Error: "object is locked: test/d.png"},
+	}, results)
+}
+
+func (suite *StorageSuite) TestMultipleRemoveInvalid() {
+	testCases := []struct {
+		Label string
+		Param map[string]interface{}
+	}{
+		{
+			Label: "Empty request",
+			Param: map[string]interface{}{},
+		},
+		{
+			Label: "Empty paths",
+			Param: map[string]interface{}{"paths": []BatchFile{}},
+		},
+		{
+			Label: "Paths and prefix",
+			Param: map[string]interface{}{
+				"paths":  []BatchFile{{Filename: "a.png", Path: "test/a.png"}},
+				"prefix": "test",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			storage.Register(&testIFile{}, func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			_, err := DeleteJSON("/storage/multiple", tc.Param, map[string]string{}, route)
+			suite.EqualError(err, fmt.Sprintf("request error by code: %d", http.StatusBadRequest), tc.Label)
+		}()
+	}
+}
+
+func (suite *StorageSuite) TestMultipleRemovePrefix() {
+	testIFile := &testIFile{}
+	testIFile.On("RemoveFolder", mock.Anything, "test", true).Return(3, nil)
+	testIFile.On("RemoveFolder", mock.Anything, "test", false).Return(3, nil)
+	storage.Register(testIFile, func() {})
+	defer storage.Unload()
+	route := NewMockGinServer()
+	Register(route)
+
+	resp, err := DeleteJSON("/storage/multiple", map[string]interface{}{
+		"prefix": "test",
+	}, map[string]string{}, route)
+	suite.NoError(err)
+	var pending RemovePrefix
+	suite.NoError(json.Unmarshal(resp, &pending))
+	suite.Equal(3, pending.Count)
+	suite.False(pending.Removed)
+	suite.NotEmpty(pending.ConfirmToken)
+	testIFile.AssertNotCalled(suite.T(), "RemoveFolder", mock.Anything, "test", false)
+
+	_, err = DeleteJSON("/storage/multiple", map[string]interface{}{
+		"prefix":        "other",
+		"confirm_token": pending.ConfirmToken,
+	}, map[string]string{}, route)
+	suite.EqualError(err, fmt.Sprintf("request error by code: %d", http.StatusForbidden))
+
+	resp, err = DeleteJSON("/storage/multiple", map[string]interface{}{
+		"prefix":        "test",
+		"confirm_token": pending.ConfirmToken,
+	}, map[string]string{}, route)
+	suite.NoError(err)
+	var removed RemovePrefix
+	suite.NoError(json.Unmarshal(resp, &removed))
+	suite.Equal(3, removed.Count)
+	suite.True(removed.Removed)
+	testIFile.AssertCalled(suite.T(), "RemoveFolder", mock.Anything, "test", false)
+}
+
+func (suite *StorageSuite) TestConfirmer() {
+	cf := confirmer{secret: []byte("secret"), ttl: time.Minute}
+	now := time.Now()
+	token, expires := cf.token("test", now)
+	suite.True(expires.After(now))
+	suite.NoError(cf.verify("test", token, now))
+	suite.True(errors.Is(cf.verify("other", token, now), errorhandlerTool.ErrConfirmToken))
+	suite.True(errors.Is(cf.verify("test", token, now.Add(2*time.Minute)), errorhandlerTool.ErrConfirmToken))
+	suite.True(errors.Is(cf.verify("test", "malformed", now), errorhandlerTool.ErrConfirmToken))
+	suite.True(errors.Is(confirmer{secret: []byte("other"), ttl: time.Minute}.verify("test", token, now), errorhandlerTool.ErrConfirmToken))
+}
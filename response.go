@@ -0,0 +1,81 @@
+package gin_storage
+
+import (
+	"github.com/justdomepaul/gin-storage/storage"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// FileV1 is the v1 JSON contract of a listed file. It is built only from the
+// storage.File methods so that drivers can't change it; fields are never
+// removed or renamed, a breaking change needs a new version.
+type FileV1 struct {
+	Name        string            `json:"name"`
+	Path        string            `json:"path"`
+	IsFolder    bool              `json:"is_folder"`
+	Size        int64             `json:"size"`
+	ContentType string            `json:"content_type"`
+	PublicURL   string            `json:"public_url"`
+	Created     string            `json:"created"`
+	Updated     string            `json:"updated"`
+	Generation  int64             `json:"generation,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+var fileV1Columns = []string{"name", "path", "is_folder", "size", "content_type", "public_url", "created", "updated", "generation", "metadata"}
+
+func NewFileV1(file storage.File) (FileV1, error) {
+	if folderName, folderPath, exist := file.FolderInfo(); exist {
+		return FileV1{Name: folderName, Path: folderPath, IsFolder: true}, nil
+	}
+	size, err := file.Size()
+	if err != nil {
+		return FileV1{}, err
+	}
+	created, err := file.CreatedTime()
+	if err != nil {
+		return FileV1{}, err
+	}
+	updated, err := file.ModTime()
+	if err != nil {
+		return FileV1{}, err
+	}
+	return FileV1{
+		Name:        file.Name(),
+		Path:        file.Path(),
+		Size:        size,
+		ContentType: file.ContentType(),
+		PublicURL:   file.GetURL(),
+		Created:     formatTime(created),
+		Updated:     formatTime(updated),
+		Generation:  file.Generation(),
+		Metadata:    file.Metadata(),
+	}, nil
+}
+
+// record is the CSV row of f, metadata is encoded as a URL query.
+func (f FileV1) record() []string {
+	generation := ""
+	if f.Generation != 0 {
+		generation = strconv.FormatInt(f.Generation, 10)
+	}
+	metadata := url.Values{}
+	for key, value := range f.Metadata {
+		metadata.Set(key, value)
+	}
+	return []string{f.Name, f.Path, strconv.FormatBool(f.IsFolder), strconv.FormatInt(f.Size, 10), f.ContentType, f.PublicURL, f.Created, f.Updated, generation, metadata.Encode()}
+}
+
+// formatTime formats t as RFC 3339 in UTC, the zero time is empty.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+type ListResponse struct {
+	Items         []FileV1 `json:"items"`
+	NextPageToken string   `json:"next_page_token,omitempty"`
+}
@@ -0,0 +1,33 @@
+package gin_storage
+
+import (
+	"github.com/gin-gonic/gin"
+	errorhandlerTool "github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/justdomepaul/gin-storage/storage/index"
+	"github.com/justdomepaul/toolbox/errorhandler"
+	"net/http"
+)
+
+// Search responds a page of the indexed files matching the list filters, the page
+// token is the path of the last file and the delimiter is ignored.
+func (fh FileHandler) Search(c *gin.Context) {
+	idx, ok := storage.Unwrap[*index.Index](fh.storage)
+	if !ok {
+		panic(errorhandler.NewErrNotFound(errorhandlerTool.ErrIndexNotEnable))
+	}
+	resp := ListResponse{Items: []FileV1{}}
+	nextPageToken, err := idx.Search(c, listQuery(c, fh.metadata.MetadataPrefix), func(file storage.File) error {
+		item, err := NewFileV1(file)
+		if err != nil {
+			return err
+		}
+		resp.Items = append(resp.Items, item)
+		return nil
+	})
+	if err != nil {
+		panic(listError(err))
+	}
+	resp.NextPageToken = nextPageToken
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,82 @@
+package gin_storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/justdomepaul/gin-storage/storage/index"
+	"net/http"
+	"time"
+)
+
+func (suite *StorageSuite) TestSearch() {
+	store := index.NewMemoryStore()
+	created := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	suite.NoError(store.Put(context.Background(),
+		index.Record{Path: "test/a.png", ContentType: "image/png", Size: 5, Created: created, Updated: created, Metadata: map[string]string{"owner": "alice"}},
+		index.Record{Path: "test/b.txt", ContentType: "text/plain", Size: 3, Created: created, Updated: created},
+		index.Record{Path: "test/c.png", ContentType: "image/png", Size: 7, Created: created, Updated: created},
+	))
+	storage.Register(index.NewIndex(config.Media{}, &testIFile{}, store), func() {})
+	defer storage.Unload()
+	route := NewMockGinServer()
+	Register(route)
+
+	testCases := []struct {
+		Label string
+		Query string
+		Want  []string
+		Next  string
+		Code  int
+	}{
+		{
+			Label: "Search content type",
+			Query: "content_type=image/png",
+			Want:  []string{"test/a.png", "test/c.png"},
+		},
+		{
+			Label: "Search metadata",
+			Query: "meta_owner=alice",
+			Want:  []string{"test/a.png"},
+		},
+		{
+			Label: "Search page",
+			Query: "page_size=1&min_size=4",
+			Want:  []string{"test/a.png"},
+			Next:  "test/a.png",
+		},
+		{
+			Label: "Search invalid regex",
+			Query: "regex=%28",
+			Code:  http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		resp, err := Get("/storage/search?"+tc.Query, map[string]string{}, route)
+		if tc.Code != 0 {
+			suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Code), tc.Label)
+			continue
+		}
+		suite.NoError(err, tc.Label)
+		var list ListResponse
+		suite.NoError(json.Unmarshal(resp, &list), tc.Label)
+		var paths []string
+		for _, item := range list.Items {
+			paths = append(paths, item.Path)
+		}
+		suite.Equal(tc.Want, paths, tc.Label)
+		suite.Equal(tc.Next, list.NextPageToken, tc.Label)
+	}
+}
+
+func (suite *StorageSuite) TestSearchNotEnable() {
+	storage.Register(&testIFile{}, func() {})
+	defer storage.Unload()
+	route := NewMockGinServer()
+	Register(route)
+
+	_, err := Get("/storage/search", map[string]string{}, route)
+	suite.EqualError(err, fmt.Sprintf("request error by code: %d", http.StatusNotFound))
+}
@@ -1,14 +1,24 @@
 package gin_storage
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/cockroachdb/errors"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
+	"github.com/justdomepaul/gin-storage/pkg/config"
 	errorhandlerTool "github.com/justdomepaul/gin-storage/pkg/errorhandler"
 	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/justdomepaul/gin-storage/storage/quota"
 	"github.com/justdomepaul/toolbox/errorhandler"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
 )
 
 const (
@@ -28,9 +38,20 @@ func Register(r *gin.Engine, prefixOptions ...string) (closeFn func()) {
 	return RouteRegister(&(r.RouterGroup), prefixOptions...)
 }
 
+// RegisterWithInterceptors registers the routes like Register, running interceptors around the handlers.
+func RegisterWithInterceptors(r *gin.Engine, interceptors Interceptors, prefixOptions ...string) (closeFn func()) {
+	return RouteRegisterWithInterceptors(&(r.RouterGroup), interceptors, prefixOptions...)
+}
+
 func RouteRegister(rg *gin.RouterGroup, prefixOptions ...string) (closeFn func()) {
+	return RouteRegisterWithInterceptors(rg, Interceptors{}, prefixOptions...)
+}
+
+// RouteRegisterWithInterceptors registers the routes like RouteRegister, running interceptors around the handlers.
+func RouteRegisterWithInterceptors(rg *gin.RouterGroup, interceptors Interceptors, prefixOptions ...string) (closeFn func()) {
 	fileStorage, fn := storage.Load()
 	handler := NewFileHandler(fileStorage)
+	handler.interceptors = interceptors
 
 	prefixRouter := rg.Group(getPrefix(prefixOptions...))
 	{
@@ -40,6 +61,34 @@ func RouteRegister(rg *gin.RouterGroup, prefixOptions ...string) (closeFn func()
 		prefixRouter.PUT("", handler.Publicize)
 		prefixRouter.PUT("/multiple", handler.MultiplePublicize)
 		prefixRouter.DELETE("", handler.Remove)
+		prefixRouter.DELETE("/multiple", handler.MultipleRemove)
+		prefixRouter.GET("/usage", handler.Usage)
+		prefixRouter.POST("/copy", handler.Copy)
+		prefixRouter.POST("/copy/multiple", handler.MultipleCopy)
+		prefixRouter.POST("/move", handler.Move)
+		prefixRouter.POST("/move/multiple", handler.MultipleMove)
+		prefixRouter.POST("/folder", handler.CreateFolder)
+		prefixRouter.DELETE("/folder", handler.RemoveFolder)
+		prefixRouter.POST("/folder/rename", handler.RenameFolder)
+		prefixRouter.GET("/versions", handler.Versions)
+		prefixRouter.POST("/versions/restore", handler.RestoreVersion)
+		prefixRouter.GET("/download", handler.Download)
+		prefixRouter.GET("/stat", handler.Stat)
+		prefixRouter.PATCH("/meta", handler.UpdateMetadata)
+		prefixRouter.POST("/commit", handler.Commit)
+		prefixRouter.GET("/search", handler.Search)
+		prefixRouter.GET("/image", handler.Image)
+		prefixRouter.GET("/hold", handler.ObjectLock)
+		prefixRouter.PUT("/hold", handler.SetHold)
+		prefixRouter.DELETE("/hold", handler.ReleaseHold)
+		prefixRouter.GET("/retention", handler.Retention)
+		prefixRouter.PUT("/retention", handler.SetRetention)
+		prefixRouter.GET("/lifecycle", handler.GetLifecycle)
+		prefixRouter.PUT("/lifecycle", handler.SetLifecycle)
+		prefixRouter.GET("/trash", handler.ListTrash)
+		prefixRouter.POST("/trash/restore", handler.RestoreTrash)
+		prefixRouter.DELETE("/trash", handler.PurgeTrash)
+		prefixRouter.POST("/archive", handler.Archive)
 	}
 
 	return fn
@@ -47,12 +96,45 @@ func RouteRegister(rg *gin.RouterGroup, prefixOptions ...string) (closeFn func()
 
 func NewFileHandler(storage storage.IFile) *FileHandler {
 	return &FileHandler{
-		storage: storage,
+		storage:   storage,
+		confirmer: newConfirmer(),
+		metadata:  newMetadataConfig(),
+		expiry:    newExpiryConfig(),
+		archive:   newArchiveConfig(),
 	}
 }
 
 type FileHandler struct {
-	storage storage.IFile
+	storage      storage.IFile
+	confirmer    confirmer
+	metadata     config.Metadata
+	expiry       config.Expiry
+	archive      config.Archive
+	interceptors Interceptors
+}
+
+func reportError(err error) errorhandler.IGinErrorReport {
+	switch {
+	case errors.Is(err, errorhandlerTool.ErrQuotaTooLarge):
+		return errorhandlerTool.NewErrStatus(http.StatusRequestEntityTooLarge, err)
+	case errors.Is(err, errorhandlerTool.ErrQuotaExceeded):
+		return errorhandlerTool.NewErrStatus(http.StatusInsufficientStorage, err)
+	case errors.Is(err, errorhandlerTool.ErrFileNotExist):
+		return errorhandler.NewErrNotFound(err)
+	case errors.Is(err, errorhandlerTool.ErrFileExist):
+		return errorhandler.NewErrDBAlreadyExists(err)
+	case errors.Is(err, errorhandlerTool.ErrPreconditionFailed):
+		return errorhandlerTool.NewErrStatus(http.StatusPreconditionFailed, err)
+	case errors.Is(err, errorhandlerTool.ErrConfirmToken):
+		return errorhandlerTool.NewErrStatus(http.StatusForbidden, err)
+	case errors.Is(err, errorhandlerTool.ErrObjectLocked):
+		return errorhandlerTool.NewErrStatus(http.StatusLocked, err)
+	case errors.Is(err, errorhandlerTool.ErrChecksumMismatch):
+		return errorhandler.NewErrVariable(err)
+	case errors.Is(err, errorhandlerTool.ErrRejected):
+		return errorhandlerTool.NewErrStatus(http.StatusUnprocessableEntity, err)
+	}
+	return errorhandler.NewErrDBExecute(err)
 }
 
 func (fh FileHandler) Upload(c *gin.Context) {
@@ -60,22 +142,63 @@ func (fh FileHandler) Upload(c *gin.Context) {
 	if err != nil {
 		panic(errorhandler.NewErrVariable(err))
 	}
+	path, checksums := fh.upload(c, c.Request.FormValue("prefix"), file, fh.uploadAttrs(c.Request.MultipartForm.Value), textproto.MIMEHeader(c.Request.Header))
+	c.JSON(http.StatusOK, gin.H{
+		"path":      path,
+		"checksums": checksums,
+	})
+}
+
+// upload stores file through the upload interceptors and returns its path and the
+// checksums of the received content. The Content-MD5 and X-Checksum-SHA256 of the file
+// part, else of the headers, are verified before uploading, and the digests of the received
+// content are handed to the driver so that it refuses to commit differing bytes. If-None-Match
+// guards the uploaded object, the generated name leaves nothing for If-Match to match. The
+// original filename is recorded under the archive filename key unless the form sets it.
+func (fh FileHandler) upload(c *gin.Context, prefix string, file *multipart.FileHeader, attrs []storage.UploadAttrs, headers ...textproto.MIMEHeader) (string, storage.Checksums) {
+	if fh.archive.ArchiveFilenameKey != "" && file.Filename != "" {
+		attrs = append([]storage.UploadAttrs{{Metadata: map[string]string{fh.archive.ArchiveFilenameKey: file.Filename}}}, attrs...)
+	}
+	if cond := storage.MergePreconditions(sharedPreconditions(c)...); !cond.IsZero() {
+		attrs = append(attrs, storage.UploadAttrs{Precondition: cond})
+	}
+	expected, err := expectedChecksums(append([]textproto.MIMEHeader{file.Header}, headers...)...)
+	if err != nil {
+		panic(errorhandler.NewErrVariable(err))
+	}
 	f, err := file.Open()
 	if err != nil {
 		panic(errorhandler.NewErrExecute(err))
 	}
-	path, err := fh.storage.Upload(c, c.Request.FormValue("prefix"), f)
+	defer f.Close()
+	sums, err := hashContent(f)
 	if err != nil {
-		panic(errorhandler.NewErrDBExecute(err))
+		panic(errorhandler.NewErrExecute(err))
 	}
-	c.JSON(http.StatusOK, gin.H{
-		"path": path,
-	})
+	if err := verifyChecksums(expected, sums); err != nil {
+		panic(reportError(err))
+	}
+	info := newUploadInfo(prefix, file)
+	reader, err := fh.interceptors.beforeUpload(c, info, f)
+	if err != nil {
+		panic(reportError(err))
+	}
+	// an interceptor replacing the content invalidates the digests
+	if reader == io.ReadCloser(f) {
+		attrs = append(attrs, storage.UploadAttrs{Checksums: storage.Checksums{CRC32C: sums.CRC32C, MD5: sums.MD5}})
+	}
+	path, err := fh.storage.Upload(c, prefix, reader, attrs...)
+	if err != nil {
+		panic(reportError(err))
+	}
+	fh.interceptors.afterUpload(c, path, info)
+	return path, sums
 }
 
 type BatchFile struct {
-	Filename string `json:"filename,omitempty" validate:"required"`
-	Path     string `json:"path,omitempty" validate:"required"`
+	Filename  string             `json:"filename,omitempty" validate:"required"`
+	Path      string             `json:"path,omitempty" validate:"required"`
+	Checksums *storage.Checksums `json:"checksums,omitempty"`
 }
 
 type PublicizeURL struct {
@@ -88,19 +211,14 @@ func (fh FileHandler) Batch(c *gin.Context) {
 	if err != nil {
 		panic(errorhandler.NewErrVariable(err))
 	}
+	attrs := fh.uploadAttrs(form.Value)
 	var responsePaths []BatchFile
 	for _, file := range form.File["file[]"] {
-		f, err := file.Open()
-		if err != nil {
-			panic(errorhandler.NewErrExecute(err))
-		}
-		path, err := fh.storage.Upload(c, c.Request.FormValue("prefix"), f)
-		if err != nil {
-			panic(errorhandler.NewErrDBExecute(err))
-		}
+		path, checksums := fh.upload(c, c.Request.FormValue("prefix"), file, attrs)
 		responsePaths = append(responsePaths, BatchFile{
-			Filename: file.Filename,
-			Path:     path,
+			Filename:  file.Filename,
+			Path:      path,
+			Checksums: &checksums,
 		})
 	}
 	c.JSON(http.StatusOK, responsePaths)
@@ -117,10 +235,7 @@ func (fh FileHandler) Publicize(c *gin.Context) {
 	if err := validator.New().Struct(&req); err != nil {
 		panic(errorhandler.NewErrVariable(err))
 	}
-	url, err := fh.storage.GetURL(c, req.Path)
-	if err != nil {
-		panic(errorhandler.NewErrDBExecute(err))
-	}
+	url := fh.publicize(c, req.Path)
 	c.JSON(http.StatusOK, gin.H{
 		"url": url,
 	})
@@ -139,10 +254,7 @@ func (fh FileHandler) MultiplePublicize(c *gin.Context) {
 	}
 	var responseURLs []PublicizeURL
 	for _, path := range req.Paths {
-		url, err := fh.storage.GetURL(c, path.Path)
-		if err != nil {
-			panic(errorhandler.NewErrDBExecute(err))
-		}
+		url := fh.publicize(c, path.Path)
 		responseURLs = append(responseURLs, PublicizeURL{
 			Filename: path.Filename,
 			URL:      url,
@@ -151,6 +263,19 @@ func (fh FileHandler) MultiplePublicize(c *gin.Context) {
 	c.JSON(http.StatusOK, responseURLs)
 }
 
+// publicize returns the public URL of path through the publicize interceptors.
+func (fh FileHandler) publicize(c *gin.Context, path string) string {
+	if err := fh.interceptors.beforePublicize(c, path); err != nil {
+		panic(reportError(err))
+	}
+	url, err := fh.storage.GetURL(c, path)
+	if err != nil {
+		panic(errorhandler.NewErrDBExecute(err))
+	}
+	fh.interceptors.afterPublicize(c, path, url)
+	return url
+}
+
 func (fh FileHandler) Remove(c *gin.Context) {
 	req := struct {
 		Path string `validate:"required"`
@@ -161,13 +286,143 @@ func (fh FileHandler) Remove(c *gin.Context) {
 	if err := validator.New().Struct(&req); err != nil {
 		panic(errorhandler.NewErrVariable(err))
 	}
-	if err := fh.storage.Remove(c, req.Path); err != nil {
-		panic(errorhandler.NewErrDBExecute(err))
+	if err := fh.remove(c, req.Path, preconditions(c)...); err != nil {
+		panic(reportError(err))
 	}
 	c.String(http.StatusOK, "ok")
 }
 
-func (fh FileHandler) List(c *gin.Context) {
+const (
+	TransferStatusDone               = "done"
+	TransferStatusNotFound           = "not_found"
+	TransferStatusExists             = "exists"
+	TransferStatusPreconditionFailed = "precondition_failed"
+	TransferStatusLocked             = "locked"
+	TransferStatusError              = "error"
+)
+
+type TransferFile struct {
+	Src       string `json:"src,omitempty" validate:"required"`
+	Dst       string `json:"dst,omitempty" validate:"required"`
+	Overwrite bool   `json:"overwrite,omitempty"`
+	// Generation guards dst as If-Match does, zero for no requirement.
+	Generation int64 `json:"generation,omitempty" validate:"min=0"`
+}
+
+type TransferResult struct {
+	Src    string `json:"src"`
+	Dst    string `json:"dst"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// conds adds the generation of file to the request preconditions.
+func (file TransferFile) conds(conds []storage.Precondition) []storage.Precondition {
+	if file.Generation == 0 {
+		return conds
+	}
+	return append(append([]storage.Precondition{}, conds...), storage.IfGenerationMatch(file.Generation))
+}
+
+type transferFn func(ctx context.Context, src, dst string, overwrite bool, conds ...storage.Precondition) error
+
+func (fh FileHandler) Copy(c *gin.Context) {
+	fh.transfer(c, fh.storage.Copy)
+}
+
+func (fh FileHandler) MultipleCopy(c *gin.Context) {
+	fh.multipleTransfer(c, fh.storage.Copy)
+}
+
+func (fh FileHandler) Move(c *gin.Context) {
+	fh.transfer(c, fh.move)
+}
+
+func (fh FileHandler) MultipleMove(c *gin.Context) {
+	fh.multipleTransfer(c, fh.move)
+}
+
+func (fh FileHandler) transfer(c *gin.Context, fn transferFn) {
+	req := TransferFile{}
+	defer c.Request.Body.Close()
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		panic(errorhandler.NewErrJSONUnmarshal(err))
+	}
+	if err := validator.New().Struct(&req); err != nil {
+		panic(errorhandler.NewErrVariable(err))
+	}
+	conds := req.conds(preconditions(c))
+	if cond := storage.MergePreconditions(conds...); cond.GenerationMatch != 0 && cond.DoesNotExist {
+		panic(errorhandler.NewErrVariable(fmt.Errorf("generation and If-None-Match exclude each other")))
+	}
+	if err := fn(c, req.Src, req.Dst, req.Overwrite, conds...); err != nil {
+		panic(reportError(err))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"path": req.Dst,
+	})
+}
+
+func (fh FileHandler) multipleTransfer(c *gin.Context, fn transferFn) {
+	req := struct {
+		Paths []TransferFile `json:"paths,omitempty" validate:"required,min=1,dive"`
+	}{}
+	defer c.Request.Body.Close()
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		panic(errorhandler.NewErrJSONUnmarshal(err))
+	}
+	if err := validator.New().Struct(&req); err != nil {
+		panic(errorhandler.NewErrVariable(err))
+	}
+	conds := sharedPreconditions(c)
+	for _, path := range req.Paths {
+		if path.Generation != 0 && storage.MergePreconditions(conds...).DoesNotExist {
+			panic(errorhandler.NewErrVariable(fmt.Errorf("generation and If-None-Match exclude each other: %s", path.Dst)))
+		}
+	}
+	// transfers run in order, a later one may depend on an earlier one
+	results := make([]TransferResult, len(req.Paths))
+	for i, path := range req.Paths {
+		results[i] = transferResult(path, fn(c, path.Src, path.Dst, path.Overwrite, path.conds(conds)...))
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+func transferResult(file TransferFile, err error) TransferResult {
+	result := TransferResult{
+		Src:    file.Src,
+		Dst:    file.Dst,
+		Status: TransferStatusDone,
+	}
+	switch {
+	case err == nil:
+		return result
+	case errors.Is(err, errorhandlerTool.ErrFileNotExist), errors.Is(err, errorhandlerTool.ErrRemoveNotExist):
+		result.Status = TransferStatusNotFound
+	case errors.Is(err, errorhandlerTool.ErrFileExist):
+		result.Status = TransferStatusExists
+	case errors.Is(err, errorhandlerTool.ErrPreconditionFailed):
+		result.Status = TransferStatusPreconditionFailed
+	case errors.Is(err, errorhandlerTool.ErrObjectLocked):
+		result.Status = TransferStatusLocked
+	default:
+		result.Status = TransferStatusError
+	}
+	result.Error = err.Error()
+	return result
+}
+
+func listError(err error) errorhandler.IGinErrorReport {
+	if errors.Is(err, errorhandlerTool.ErrInvalidQuery) {
+		return errorhandler.NewErrVariable(err)
+	}
+	if errors.Is(err, errorhandlerTool.ErrGetFile) {
+		return errorhandler.NewErrDBRowNotFound(err)
+	}
+	return errorhandler.NewErrDBExecute(err)
+}
+
+func listQuery(c *gin.Context, metadataPrefix string) storage.Query {
 	q := storage.Query{}
 	if c.Query("delimiter") != "" {
 		q = storage.WithFileCloudDelimiter(q, c.Query("delimiter"))
@@ -175,15 +430,111 @@ func (fh FileHandler) List(c *gin.Context) {
 	if c.Query("prefix") != "" {
 		q = storage.WithFileCloudPrefix(q, c.Query("prefix"))
 	}
+	if c.Query("page_size") != "" {
+		pageSize, err := strconv.Atoi(c.Query("page_size"))
+		if err != nil {
+			panic(errorhandler.NewErrVariable(err))
+		}
+		q = storage.WithFilePageSize(q, pageSize)
+	}
+	if c.Query("page_token") != "" {
+		q = storage.WithFilePageToken(q, c.Query("page_token"))
+	}
+	if c.Query("glob") != "" {
+		q = storage.WithFileNameGlob(q, c.Query("glob"))
+	}
+	if c.Query("regex") != "" {
+		q = storage.WithFileNameRegex(q, c.Query("regex"))
+	}
+	if c.Query("content_type") != "" {
+		q = storage.WithFileContentType(q, c.Query("content_type"))
+	}
+	if c.Query("min_size") != "" || c.Query("max_size") != "" {
+		q = storage.WithFileSizeRange(q, queryInt64(c, "min_size"), queryInt64(c, "max_size"))
+	}
+	if c.Query("created_after") != "" || c.Query("created_before") != "" {
+		q = storage.WithFileCreatedRange(q, queryTime(c, "created_after"), queryTime(c, "created_before"))
+	}
+	if c.Query("updated_after") != "" || c.Query("updated_before") != "" {
+		q = storage.WithFileUpdatedRange(q, queryTime(c, "updated_after"), queryTime(c, "updated_before"))
+	}
+	if c.Query("sort") != "" {
+		sortBy, ok := sortFields[c.Query("sort")]
+		if !ok {
+			panic(errorhandler.NewErrVariable(fmt.Errorf("%w: unknown sort %s", errorhandlerTool.ErrInvalidQuery, c.Query("sort"))))
+		}
+		q = storage.WithFileSort(q, sortBy, c.Query("order") == "desc")
+	}
+	for key, value := range c.Request.URL.Query() {
+		if strings.HasPrefix(key, metadataPrefix) && len(value) > 0 {
+			q = storage.WithFileMetadata(q, strings.TrimPrefix(key, metadataPrefix), value[0])
+		}
+	}
+	return q
+}
 
-	var files []storage.File
-	if err := fh.storage.List(c, q, func(file storage.File) error {
-		files = append(files, file)
+var sortFields = map[string]storage.SortEnumType{
+	"name": storage.SortByName,
+	"size": storage.SortBySize,
+	"time": storage.SortByTime,
+}
+
+func queryInt64(c *gin.Context, key string) int64 {
+	if c.Query(key) == "" {
+		return 0
+	}
+	v, err := strconv.ParseInt(c.Query(key), 10, 64)
+	if err != nil {
+		panic(errorhandler.NewErrVariable(err))
+	}
+	return v
+}
+
+// queryTime parses an RFC 3339 query value, empty is the zero time.
+func queryTime(c *gin.Context, key string) time.Time {
+	if c.Query(key) == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, c.Query(key))
+	if err != nil {
+		panic(errorhandler.NewErrVariable(err))
+	}
+	return t
+}
+
+// List responds a page of files as JSON, or streams every file under the query
+// as NDJSON or CSV when the client accepts one of them.
+func (fh FileHandler) List(c *gin.Context) {
+	q := listQuery(c, fh.metadata.MetadataPrefix)
+	if format := c.NegotiateFormat(gin.MIMEJSON, MIMENDJSON, MIMECSV); format == MIMENDJSON || format == MIMECSV {
+		fh.streamList(c, q, format)
+		return
+	}
+
+	resp := ListResponse{Items: []FileV1{}}
+	nextPageToken, err := fh.storage.ListPage(c, q, func(file storage.File) error {
+		item, err := NewFileV1(file)
+		if err != nil {
+			return err
+		}
+		resp.Items = append(resp.Items, item)
 		return nil
-	}); errors.Is(err, errorhandlerTool.ErrGetFile) {
-		panic(errorhandler.NewErrDBRowNotFound(err))
-	} else if err != nil {
+	})
+	if err != nil {
+		panic(listError(err))
+	}
+	resp.NextPageToken = nextPageToken
+	c.JSON(http.StatusOK, resp)
+}
+
+func (fh FileHandler) Usage(c *gin.Context) {
+	q, ok := storage.Unwrap[*quota.Quota](fh.storage)
+	if !ok {
+		panic(errorhandler.NewErrNotFound(errorhandlerTool.ErrQuotaNotEnable))
+	}
+	report, err := q.Usage(c, c.Query("prefix"))
+	if err != nil {
 		panic(errorhandler.NewErrDBExecute(err))
 	}
-	c.JSON(http.StatusOK, files)
+	c.JSON(http.StatusOK, report)
 }
@@ -0,0 +1,56 @@
+package storage
+
+// UploadAttrs are the optional attributes written with an uploaded object.
+type UploadAttrs struct {
+	Metadata map[string]string
+	// Name replaces the generated object name under the prefix, an existing object is overwritten.
+	Name string
+	// ContentType is stored as is, else the driver detects it from the content.
+	ContentType string
+	// Checksums are the digests of the uploaded content known up front, a driver able to
+	// verify them refuses to commit differing content with ErrChecksumMismatch.
+	Checksums Checksums
+	// Precondition guards the object overwritten at Name and fails with ErrPreconditionFailed,
+	// a generated name only satisfies DoesNotExist.
+	Precondition Precondition
+}
+
+// MergeUploadAttrs combines attrs into a single UploadAttrs, a later key wins.
+func MergeUploadAttrs(attrs ...UploadAttrs) UploadAttrs {
+	var merged UploadAttrs
+	for _, item := range attrs {
+		if item.Name != "" {
+			merged.Name = item.Name
+		}
+		if item.ContentType != "" {
+			merged.ContentType = item.ContentType
+		}
+		if item.Checksums.CRC32C != "" {
+			merged.Checksums.CRC32C = item.Checksums.CRC32C
+		}
+		if item.Checksums.MD5 != "" {
+			merged.Checksums.MD5 = item.Checksums.MD5
+		}
+		if item.Checksums.SHA256 != "" {
+			merged.Checksums.SHA256 = item.Checksums.SHA256
+		}
+		merged.Precondition = MergePreconditions(merged.Precondition, item.Precondition)
+		for k, v := range item.Metadata {
+			if merged.Metadata == nil {
+				merged.Metadata = map[string]string{}
+			}
+			merged.Metadata[k] = v
+		}
+	}
+	return merged
+}
+
+// ContentChanged drops the checksums of attrs, for a decorator uploading other bytes than it received.
+func ContentChanged(attrs []UploadAttrs) []UploadAttrs {
+	changed := make([]UploadAttrs, len(attrs))
+	for i, item := range attrs {
+		item.Checksums = Checksums{}
+		changed[i] = item
+	}
+	return changed
+}
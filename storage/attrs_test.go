@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type AttrsSuite struct {
+	suite.Suite
+}
+
+func (suite *AttrsSuite) TestMergeUploadAttrs() {
+	testCases := []struct {
+		Label string
+		Attrs []UploadAttrs
+		Want  UploadAttrs
+	}{
+		{
+			Label: "No attrs",
+		},
+		{
+			Label: "Later key wins",
+			Attrs: []UploadAttrs{
+				{Name: "a", Metadata: map[string]string{"owner": "a", "kind": "doc"}, Checksums: Checksums{MD5: "md5", CRC32C: "crc"}},
+				{ContentType: "text/plain", Metadata: map[string]string{"owner": "b"}, Checksums: Checksums{CRC32C: "crc2"}},
+			},
+			Want: UploadAttrs{
+				Name:        "a",
+				ContentType: "text/plain",
+				Metadata:    map[string]string{"owner": "b", "kind": "doc"},
+				Checksums:   Checksums{MD5: "md5", CRC32C: "crc2"},
+			},
+		},
+		{
+			Label: "Preconditions combine",
+			Attrs: []UploadAttrs{
+				{Precondition: IfGenerationMatch(3)},
+				{Precondition: IfDoesNotExist()},
+			},
+			Want: UploadAttrs{Precondition: Precondition{GenerationMatch: 3, DoesNotExist: true}},
+		},
+	}
+	for _, tc := range testCases {
+		suite.Equal(tc.Want, MergeUploadAttrs(tc.Attrs...), tc.Label)
+	}
+}
+
+func (suite *AttrsSuite) TestContentChanged() {
+	attrs := []UploadAttrs{{Name: "a", Checksums: Checksums{MD5: "md5"}}}
+	suite.Equal([]UploadAttrs{{Name: "a"}}, ContentChanged(attrs))
+	suite.Equal("md5", attrs[0].Checksums.MD5)
+}
+
+func TestAttrsSuite(t *testing.T) {
+	suite.Run(t, new(AttrsSuite))
+}
@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"context"
+	zapTool "github.com/justdomepaul/toolbox/zap"
+	"go.uber.org/zap"
+	"time"
+)
+
+// Every calls fn every interval until ctx is done or stop is called, stop waits for a
+// running call to return. A failed call is logged under name and retried on the next tick.
+func Every(ctx context.Context, interval time.Duration, name string, fn func(ctx context.Context) error) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := fn(ctx); err != nil && ctx.Err() == nil {
+					zapTool.Logger.Warn(name, zap.Error(err))
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
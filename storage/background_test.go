@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"context"
+	"github.com/stretchr/testify/suite"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type BackgroundSuite struct {
+	suite.Suite
+}
+
+func (suite *BackgroundSuite) TestEvery() {
+	var calls int64
+	stop := Every(context.Background(), time.Millisecond, "test", func(ctx context.Context) error {
+		atomic.AddInt64(&calls, 1)
+		return nil
+	})
+	suite.Eventually(func() bool { return atomic.LoadInt64(&calls) >= 2 }, time.Second, time.Millisecond)
+	stop()
+	stopped := atomic.LoadInt64(&calls)
+	time.Sleep(5 * time.Millisecond)
+	suite.Equal(stopped, atomic.LoadInt64(&calls))
+}
+
+func (suite *BackgroundSuite) TestEveryContextDone() {
+	ctx, cancel := context.WithCancel(context.Background())
+	stop := Every(ctx, time.Hour, "test", func(ctx context.Context) error {
+		suite.Fail("not expected")
+		return nil
+	})
+	cancel()
+	stop()
+}
+
+func TestBackgroundSuite(t *testing.T) {
+	suite.Run(t, new(BackgroundSuite))
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"hash"
+	"hash/crc32"
+)
+
+// Checksums are the base64 digests of an object content, CRC32C is big-endian like Cloud Storage encodes it.
+type Checksums struct {
+	CRC32C string `json:"crc32c,omitempty"`
+	MD5    string `json:"md5,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// NewHasher method
+func NewHasher() *Hasher {
+	return &Hasher{
+		crc32c: crc32.New(crc32.MakeTable(crc32.Castagnoli)),
+		md5:    md5.New(),
+		sha256: sha256.New(),
+	}
+}
+
+// Hasher computes the Checksums of what is written to it.
+type Hasher struct {
+	crc32c hash.Hash32
+	md5    hash.Hash
+	sha256 hash.Hash
+}
+
+func (h *Hasher) Write(p []byte) (int, error) {
+	h.crc32c.Write(p)
+	h.md5.Write(p)
+	h.sha256.Write(p)
+	return len(p), nil
+}
+
+func (h *Hasher) Sum() Checksums {
+	return Checksums{
+		CRC32C: EncodeCRC32C(h.crc32c.Sum32()),
+		MD5:    base64.StdEncoding.EncodeToString(h.md5.Sum(nil)),
+		SHA256: base64.StdEncoding.EncodeToString(h.sha256.Sum(nil)),
+	}
+}
+
+func EncodeCRC32C(sum uint32) string {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, sum)
+	return base64.StdEncoding.EncodeToString(b)
+}
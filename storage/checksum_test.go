@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"github.com/stretchr/testify/suite"
+	"strings"
+	"testing"
+)
+
+type ChecksumSuite struct {
+	suite.Suite
+}
+
+func (suite *ChecksumSuite) TestHasher() {
+	testCases := []struct {
+		Label     string
+		Content   string
+		Checksums Checksums
+	}{
+		{
+			Label:   "Hash empty",
+			Content: "",
+			Checksums: Checksums{
+				CRC32C: "AAAAAA==",
+				MD5:    "1B2M2Y8AsgTpgAmY7PhCfg==",
+				SHA256: "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=",
+			},
+		},
+		{
+			Label:   "Hash content",
+			Content: "hello world",
+			Checksums: Checksums{
+				CRC32C: "yZRlqg==",
+				MD5:    "XrY7u+Ae7tCTyyK7j1rNww==",
+				SHA256: "uU0nuZNNPgilLlLX2n2r+sSE7+N6U4DukIj3rOLvzek=",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		hasher := NewHasher()
+		for _, part := range strings.SplitAfter(tc.Content, " ") {
+			_, err := hasher.Write([]byte(part))
+			suite.NoError(err, tc.Label)
+		}
+		suite.Equal(tc.Checksums, hasher.Sum(), tc.Label)
+	}
+}
+
+func TestChecksumSuite(t *testing.T) {
+	suite.Run(t, new(ChecksumSuite))
+}
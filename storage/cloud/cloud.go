@@ -3,6 +3,9 @@ package cloud
 import (
 	gs "cloud.google.com/go/storage"
 	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/binary"
 	"fmt"
 	"github.com/cockroachdb/errors"
 	"github.com/go-playground/validator/v10"
@@ -14,15 +17,23 @@ import (
 	"github.com/justdomepaul/toolbox/database/cloud"
 	zapTool "github.com/justdomepaul/toolbox/zap"
 	"github.com/kelseyhightower/envconfig"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 	"io"
+	"net/http"
 	"net/url"
 	"path"
 	"regexp"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
-const StorageDomain = "https://storage.googleapis.com/"
+const (
+	StorageDomain = "https://storage.googleapis.com/"
+	// MaxPageSize is the largest page Cloud Storage returns for a single objects list call
+	MaxPageSize = 1000
+)
 
 func init() {
 	f, fn, err := getFile()
@@ -36,8 +47,9 @@ func getFile() (storage.IFile, func(), error) {
 	cg := config.Core{}
 	media := configTool.Media{}
 	st := config.Cloud{}
+	csek := configTool.CSEK{}
 
-	for _, item := range []interface{}{&cg, &media, &st} {
+	for _, item := range []interface{}{&cg, &media, &st, &csek} {
 		err := envconfig.Process("", item)
 		if err != nil {
 			return nil, nil, fmt.Errorf("%w: %s", errorhandler.ErrInitialFileClient, err.Error())
@@ -51,7 +63,15 @@ func getFile() (storage.IFile, func(), error) {
 	if err != nil {
 		return nil, nil, fmt.Errorf("%w: %s", errorhandler.ErrInitialFileClient, err.Error())
 	}
-	return NewFile(media, cloudStorage), fn, nil
+	keys, err := NewStaticKeys(csek, media.PrefixPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	file := NewFile(media, cloudStorage)
+	if keys != nil {
+		file.WithKeys(keys)
+	}
+	return file, fn, nil
 }
 
 var fileClauseFn = map[storage.FileEnumType]func(source storage.Query, condition *gs.Query) error{
@@ -61,6 +81,16 @@ var fileClauseFn = map[storage.FileEnumType]func(source storage.Query, condition
 	storage.FileCloudStartOffset: withFileStartOffset,
 	storage.FileCloudEndOffset:   withFileEndOffset,
 	storage.FileCloudProjection:  withFileProjection,
+	storage.FilePageSize:         withFilePageSize,
+	storage.FilePageToken:        withFilePageToken,
+	storage.FileNameGlob:         withFileNameGlob,
+	storage.FileNameRegex:        withFileFilter,
+	storage.FileContentType:      withFileFilter,
+	storage.FileSizeRange:        withFileFilter,
+	storage.FileCreatedRange:     withFileFilter,
+	storage.FileUpdatedRange:     withFileFilter,
+	storage.FileSort:             withFileFilter,
+	storage.FileMetadata:         withFileFilter,
 }
 
 func withFileDelimiter(source storage.Query, condition *gs.Query) error {
@@ -112,6 +142,43 @@ func withFileProjection(source storage.Query, condition *gs.Query) error {
 	return nil
 }
 
+// withFilePageSize only validates, page size is applied by the pager of ListPage
+func withFilePageSize(source storage.Query, condition *gs.Query) error {
+	return validator.New().Var(source.PageSize, fmt.Sprintf(`min=1,max=%d`, MaxPageSize))
+}
+
+// withFilePageToken only validates, page token is applied by the pager of ListPage
+func withFilePageToken(source storage.Query, condition *gs.Query) error {
+	return validator.New().Var(source.PageToken, `required`)
+}
+
+// withFileNameGlob pushes the literal head of the glob down as the listing prefix,
+// the glob itself is matched by storage.Filter
+func withFileNameGlob(source storage.Query, condition *gs.Query) error {
+	if err := validator.New().Var(source.NameGlob, `required`); err != nil {
+		return err
+	}
+	if _, err := path.Match(source.NameGlob, ""); err != nil {
+		return fmt.Errorf("%w: %s", errorhandler.ErrInvalidQuery, err.Error())
+	}
+	if source.CloudDelimiter != "" {
+		return nil
+	}
+	literal := source.NameGlob
+	if i := strings.IndexAny(literal, `*?[\`); i >= 0 {
+		literal = literal[:i]
+	}
+	if strings.HasPrefix(literal, source.CloudPrefix) {
+		condition.Prefix = literal
+	}
+	return nil
+}
+
+// withFileFilter leaves the portable filters Cloud Storage can't serve to storage.Filter and storage.Sort
+func withFileFilter(source storage.Query, condition *gs.Query) error {
+	return nil
+}
+
 func toFileClauses(source storage.Query) (*gs.Query, error) {
 	q := &gs.Query{}
 	for _, op := range source.Fields {
@@ -119,9 +186,29 @@ func toFileClauses(source storage.Query) (*gs.Query, error) {
 			return q, err
 		}
 	}
+	// the glob prefix is narrower than the prefix field whatever the fields order
+	if source.Has(storage.FileNameGlob) {
+		if err := withFileNameGlob(source, q); err != nil {
+			return q, err
+		}
+	}
 	return q, nil
 }
 
+// toFileHandler applies the portable filters on h, objects are listed by name
+// so sorting by name ascending needs no buffering.
+func toFileHandler(source storage.Query, h storage.IterHandler) (storage.IterHandler, func() error, error) {
+	flush := func() error { return nil }
+	if source.Has(storage.FileSort) && (source.SortBy != storage.SortByName || source.SortDesc) {
+		h, flush = storage.Sort(source, h)
+	}
+	h, err := storage.Filter(source, h)
+	if err != nil {
+		return nil, nil, err
+	}
+	return h, flush, nil
+}
+
 // NewFile method
 func NewFile(env configTool.Media, session cloud.ISession) *Cloud {
 	return &Cloud{
@@ -133,35 +220,130 @@ func NewFile(env configTool.Media, session cloud.ISession) *Cloud {
 type Cloud struct {
 	env     configTool.Media
 	session cloud.ISession
+	keys    IKeyLookup
 }
 
-func (st *Cloud) Upload(ctx context.Context, prefix string, f io.ReadCloser) (string, error) {
-	mediaID, err := uuid.NewUUID()
-	if err != nil {
-		return "", fmt.Errorf("%w: %s", errorhandler.ErrFailGenerateUUID, err.Error())
+// WithKeys encrypts the objects by the customer-supplied keys of keys.
+func (st *Cloud) WithKeys(keys IKeyLookup) *Cloud {
+	st.keys = keys
+	return st
+}
+
+func (st *Cloud) object(ctx context.Context, bucket *gs.BucketHandle, route string) (*gs.ObjectHandle, error) {
+	return keyedObject(ctx, st.keys, bucket, route)
+}
+
+// keyed hands the listed files the key lookup reading their content.
+func (st *Cloud) keyed(h storage.IterHandler) storage.IterHandler {
+	return func(file storage.File) error {
+		if f, ok := file.(*File); ok {
+			f.Keys = st.keys
+		}
+		return h(file)
+	}
+}
+
+func (st *Cloud) Upload(ctx context.Context, prefix string, f io.ReadCloser, attrs ...storage.UploadAttrs) (string, error) {
+	merged := storage.MergeUploadAttrs(attrs...)
+	name := merged.Name
+	if strings.Contains(name, "/") {
+		return "", fmt.Errorf("%w: invalid name %s", errorhandler.ErrFileUpload, name)
 	}
-	pt := mediaID.String()
-	if st.env.PrefixPath != "" {
-		pt = path.Join(st.env.PrefixPath, prefix, mediaID.String())
+	if name == "" {
+		mediaID, err := uuid.NewUUID()
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", errorhandler.ErrFailGenerateUUID, err.Error())
+		}
+		name = mediaID.String()
 	}
+	// prefix is kept without PrefixPath too, so decorators find the objects where they asked for them
+	pt := path.Join(st.env.PrefixPath, prefix, name)
 	if err := verifyPath(pt); err != nil {
 		return "", fmt.Errorf("%w: %s", errorhandler.ErrFileUpload, err.Error())
 	}
-	wc := st.session.Bucket(st.env.BucketName).Object(pt).NewWriter(ctx)
-	if _, err := io.Copy(wc, f); err != nil {
+	// a canceled context aborts the writer, a failed copy never commits a partial object
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	object, err := st.object(ctx, st.session.Bucket(st.env.BucketName), pt)
+	if err != nil {
+		return "", err
+	}
+	cond := merged.Precondition
+	target := withConditions(object, cond)
+	if merged.Name == "" {
+		if cond.GenerationMatch != 0 {
+			return "", fmt.Errorf("%w: %s", errorhandler.ErrPreconditionFailed, pt)
+		}
+		// a generated name never overwrites
+		target = object.If(gs.Conditions{DoesNotExist: true})
+	}
+	wc := target.NewWriter(ctx)
+	wc.Metadata = merged.Metadata
+	wc.ContentType = merged.ContentType
+	expected := merged.Checksums
+	if err := sendChecksums(wc, expected); err != nil {
+		return "", fmt.Errorf("%w: %s", errorhandler.ErrFileUpload, err.Error())
+	}
+	hasher := storage.NewHasher()
+	if _, err := io.Copy(io.MultiWriter(wc, hasher), f); err != nil {
 		return "", fmt.Errorf("%w: %s", errorhandler.ErrFileUpload, err.Error())
 	}
 	if err := wc.Close(); err != nil {
+		if isChecksumRejected(err) {
+			return "", fmt.Errorf("%w: %s", errorhandler.ErrChecksumMismatch, err.Error())
+		}
+		if isPreconditionFailed(err) && merged.Name != "" && !cond.IsZero() {
+			return "", fmt.Errorf("%w: %s", errorhandler.ErrPreconditionFailed, pt)
+		}
+		if isPreconditionFailed(err) {
+			return "", fmt.Errorf("%w: %s", errorhandler.ErrFileExist, pt)
+		}
+		if isLocked(err) {
+			return "", fmt.Errorf("%w: %s", errorhandler.ErrObjectLocked, pt)
+		}
 		return "", fmt.Errorf("%w: %s", errorhandler.ErrFailCloseSession, err.Error())
 	}
+	// digests not sent up front are only checked on the committed object, which is removed when it differs
+	sums, stored := hasher.Sum(), wc.Attrs()
+	if (expected.CRC32C == "" && storage.EncodeCRC32C(stored.CRC32C) != sums.CRC32C) ||
+		(expected.MD5 == "" && len(stored.MD5) > 0 && base64.StdEncoding.EncodeToString(stored.MD5) != sums.MD5) {
+		if err := object.If(gs.Conditions{GenerationMatch: stored.Generation}).Delete(ctx); err != nil {
+			return "", fmt.Errorf("%w: stored checksums differ from the streamed content of %s, removing it: %s", errorhandler.ErrFileUpload, pt, err.Error())
+		}
+		return "", fmt.Errorf("%w: stored checksums differ from the streamed content of %s", errorhandler.ErrFileUpload, pt)
+	}
 	return pt, nil
 }
 
+// sendChecksums has Cloud Storage verify the content of wc against the known digests before committing it.
+func sendChecksums(wc *gs.Writer, expected storage.Checksums) error {
+	if expected.CRC32C != "" {
+		b, err := base64.StdEncoding.DecodeString(expected.CRC32C)
+		if err != nil || len(b) != 4 {
+			return fmt.Errorf("invalid crc32c %s", expected.CRC32C)
+		}
+		wc.CRC32C = binary.BigEndian.Uint32(b)
+		wc.SendCRC32C = true
+	}
+	if expected.MD5 != "" {
+		b, err := base64.StdEncoding.DecodeString(expected.MD5)
+		if err != nil || len(b) != md5.Size {
+			return fmt.Errorf("invalid md5 %s", expected.MD5)
+		}
+		wc.MD5 = b
+	}
+	return nil
+}
+
 func (st *Cloud) GetURL(ctx context.Context, route string) (string, error) {
 	if err := verifyPath(route); err != nil {
 		return "", fmt.Errorf("%w: %s", errorhandler.ErrFileUpdate, err.Error())
 	}
-	_, err := st.session.Bucket(st.env.BucketName).Object(route).Update(ctx, gs.ObjectAttrsToUpdate{
+	object, err := st.object(ctx, st.session.Bucket(st.env.BucketName), route)
+	if err != nil {
+		return "", err
+	}
+	_, err = object.Update(ctx, gs.ObjectAttrsToUpdate{
 		PredefinedACL: "publicRead",
 	})
 	if errors.Is(err, gs.ErrObjectNotExist) {
@@ -173,23 +355,644 @@ func (st *Cloud) GetURL(ctx context.Context, route string) (string, error) {
 	return getPublicURL(st.env.BucketName, route)
 }
 
-func (st *Cloud) Remove(ctx context.Context, route string) error {
+func (st *Cloud) Remove(ctx context.Context, route string, conds ...storage.Precondition) error {
 	if err := verifyPath(route); err != nil {
 		return fmt.Errorf("%w: %s", errorhandler.ErrFileRemove, err.Error())
 	}
-	if err := st.session.Bucket(st.env.BucketName).Object(route).Delete(ctx); err != nil {
+	object := st.session.Bucket(st.env.BucketName).Object(route)
+	err := withConditions(object, storage.MergePreconditions(conds...)).Delete(ctx)
+	if errors.Is(err, gs.ErrObjectNotExist) {
+		return fmt.Errorf("%w: %s", errorhandler.ErrRemoveNotExist, route)
+	}
+	if isPreconditionFailed(err) {
+		return fmt.Errorf("%w: %s", errorhandler.ErrPreconditionFailed, route)
+	}
+	if isLocked(err) {
+		return fmt.Errorf("%w: %s", errorhandler.ErrObjectLocked, route)
+	}
+	if err != nil {
 		return fmt.Errorf("%w: %s", errorhandler.ErrFileRemove, err.Error())
 	}
 	return nil
 }
 
+func (st *Cloud) Copy(ctx context.Context, src, dst string, overwrite bool, conds ...storage.Precondition) error {
+	_, err := st.copy(ctx, src, 0, dst, overwrite, conds...)
+	return err
+}
+
+// copy copies the generation of src to dst and returns the attrs of dst, generation zero copies the live version.
+func (st *Cloud) copy(ctx context.Context, src string, generation int64, dst string, overwrite bool, conds ...storage.Precondition) (*gs.ObjectAttrs, error) {
+	for _, route := range []string{src, dst} {
+		if err := verifyPath(route); err != nil {
+			return nil, fmt.Errorf("%w: %s", errorhandler.ErrFileCopy, err.Error())
+		}
+	}
+	bucket := st.session.Bucket(st.env.BucketName)
+	srcObject, err := st.object(ctx, bucket, src)
+	if err != nil {
+		return nil, err
+	}
+	if generation != 0 {
+		srcObject = srcObject.Generation(generation)
+	}
+	dstObject, err := st.object(ctx, bucket, dst)
+	if err != nil {
+		return nil, err
+	}
+	cond := storage.MergePreconditions(conds...)
+	dstObject = withConditions(dstObject, cond)
+	if cond.IsZero() && !overwrite {
+		dstObject = dstObject.If(gs.Conditions{DoesNotExist: true})
+	}
+	attrs, err := dstObject.CopierFrom(srcObject).Run(ctx)
+	if errors.Is(err, gs.ErrObjectNotExist) {
+		return nil, errorhandler.ErrFileNotExist
+	}
+	if isKeyRejected(err) {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrEncryptionKey, err.Error())
+	}
+	if isPreconditionFailed(err) && !cond.IsZero() {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrPreconditionFailed, dst)
+	}
+	if isPreconditionFailed(err) {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrFileExist, dst)
+	}
+	if isLocked(err) {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrObjectLocked, dst)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrFileCopy, err.Error())
+	}
+	return attrs, nil
+}
+
+// Move copies the live generation of src then removes src at that generation, a src changed or
+// locked meanwhile fails the remove and the copy is removed again.
+func (st *Cloud) Move(ctx context.Context, src, dst string, overwrite bool, conds ...storage.Precondition) error {
+	if src == dst {
+		return fmt.Errorf("%w: source and destination are the same", errorhandler.ErrFileCopy)
+	}
+	if err := verifyPath(src); err != nil {
+		return fmt.Errorf("%w: %s", errorhandler.ErrFileCopy, err.Error())
+	}
+	srcAttrs, err := st.session.Bucket(st.env.BucketName).Object(src).Attrs(ctx)
+	if errors.Is(err, gs.ErrObjectNotExist) {
+		return fmt.Errorf("%w: %s", errorhandler.ErrFileNotExist, src)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", errorhandler.ErrGetFile, err.Error())
+	}
+	if objectLock(srcAttrs).Locked(time.Now()) {
+		return fmt.Errorf("%w: %s", errorhandler.ErrObjectLocked, src)
+	}
+	dstAttrs, err := st.copy(ctx, src, srcAttrs.Generation, dst, overwrite, conds...)
+	if err != nil {
+		return err
+	}
+	if err := st.Remove(ctx, src, storage.IfGenerationMatch(srcAttrs.Generation)); err != nil {
+		if errRemove := st.Remove(ctx, dst, storage.IfGenerationMatch(dstAttrs.Generation)); errRemove != nil {
+			return fmt.Errorf("%w: %s", err, errRemove.Error())
+		}
+		return err
+	}
+	return nil
+}
+
+func (st *Cloud) CreateFolder(ctx context.Context, route string) (string, error) {
+	prefix, err := folderPrefix(route)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", errorhandler.ErrFileUpload, err.Error())
+	}
+	object, err := st.object(ctx, st.session.Bucket(st.env.BucketName), prefix)
+	if err != nil {
+		return "", err
+	}
+	wc := object.If(gs.Conditions{DoesNotExist: true}).NewWriter(ctx)
+	err = wc.Close()
+	if isPreconditionFailed(err) {
+		return "", fmt.Errorf("%w: %s", errorhandler.ErrFileExist, prefix)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", errorhandler.ErrFailCloseSession, err.Error())
+	}
+	return prefix, nil
+}
+
+func (st *Cloud) RemoveFolder(ctx context.Context, route string, dryRun bool) (int, error) {
+	prefix, err := folderPrefix(route)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", errorhandler.ErrFileRemove, err.Error())
+	}
+	bucket := st.session.Bucket(st.env.BucketName)
+	names, err := folderObjects(ctx, bucket, prefix)
+	if err != nil || dryRun {
+		return len(names), err
+	}
+	return len(names), storage.ForEach(ctx, storage.DefaultConcurrency, len(names), func(ctx context.Context, i int) error {
+		err := bucket.Object(names[i]).Delete(ctx)
+		if isLocked(err) {
+			return fmt.Errorf("%w: %s", errorhandler.ErrObjectLocked, names[i])
+		}
+		if err != nil && !errors.Is(err, gs.ErrObjectNotExist) {
+			return fmt.Errorf("%w: %s", errorhandler.ErrFileRemove, err.Error())
+		}
+		return nil
+	})
+}
+
+// RenameFolder moves every object under src to dst as Move does, a locked or changed object
+// fails and is left at src.
+func (st *Cloud) RenameFolder(ctx context.Context, src, dst string, progress storage.ProgressFn) (int, error) {
+	srcPrefix, err := folderPrefix(src)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", errorhandler.ErrFileCopy, err.Error())
+	}
+	dstPrefix, err := folderPrefix(dst)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", errorhandler.ErrFileCopy, err.Error())
+	}
+	if strings.HasPrefix(dstPrefix, srcPrefix) || strings.HasPrefix(srcPrefix, dstPrefix) {
+		return 0, fmt.Errorf("%w: %s and %s overlap", errorhandler.ErrFileCopy, srcPrefix, dstPrefix)
+	}
+	bucket := st.session.Bucket(st.env.BucketName)
+	names, err := folderObjects(ctx, bucket, srcPrefix)
+	if err != nil {
+		return 0, err
+	}
+	if len(names) == 0 {
+		return 0, fmt.Errorf("%w: %s", errorhandler.ErrFolderNotExist, srcPrefix)
+	}
+	var done int64
+	err = storage.ForEach(ctx, storage.DefaultConcurrency, len(names), func(ctx context.Context, i int) error {
+		target := dstPrefix + strings.TrimPrefix(names[i], srcPrefix)
+		if err := st.moveObject(ctx, bucket, names[i], target); err != nil {
+			return err
+		}
+		if progress != nil {
+			progress(int(atomic.AddInt64(&done, 1)), len(names))
+		}
+		return nil
+	})
+	return int(atomic.LoadInt64(&done)), err
+}
+
+// moveObject moves src to the absent dst as Move does, folder placeholders included: a locked
+// src fails before copying, src is removed at the copied generation and dst is removed again
+// when src can't be removed.
+func (st *Cloud) moveObject(ctx context.Context, bucket *gs.BucketHandle, src, dst string) error {
+	srcObject, err := st.object(ctx, bucket, src)
+	if err != nil {
+		return err
+	}
+	srcAttrs, err := srcObject.Attrs(ctx)
+	if errors.Is(err, gs.ErrObjectNotExist) {
+		return fmt.Errorf("%w: %s", errorhandler.ErrFileNotExist, src)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", errorhandler.ErrGetFile, err.Error())
+	}
+	if objectLock(srcAttrs).Locked(time.Now()) {
+		return fmt.Errorf("%w: %s", errorhandler.ErrObjectLocked, src)
+	}
+	dstObject, err := st.object(ctx, bucket, dst)
+	if err != nil {
+		return err
+	}
+	dstAttrs, err := dstObject.If(gs.Conditions{DoesNotExist: true}).CopierFrom(srcObject.Generation(srcAttrs.Generation)).Run(ctx)
+	if isPreconditionFailed(err) {
+		return fmt.Errorf("%w: %s", errorhandler.ErrFileExist, dst)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", errorhandler.ErrFileCopy, err.Error())
+	}
+	err = bucket.Object(src).If(gs.Conditions{GenerationMatch: srcAttrs.Generation}).Delete(ctx)
+	if err == nil {
+		return nil
+	}
+	switch {
+	case isPreconditionFailed(err), errors.Is(err, gs.ErrObjectNotExist):
+		err = fmt.Errorf("%w: %s", errorhandler.ErrPreconditionFailed, src)
+	case isLocked(err):
+		err = fmt.Errorf("%w: %s", errorhandler.ErrObjectLocked, src)
+	default:
+		err = fmt.Errorf("%w: %s", errorhandler.ErrFileRemove, err.Error())
+	}
+	if errRemove := bucket.Object(dst).If(gs.Conditions{GenerationMatch: dstAttrs.Generation}).Delete(ctx); errRemove != nil {
+		return fmt.Errorf("%w: %s", err, errRemove.Error())
+	}
+	return err
+}
+
 func (st *Cloud) List(ctx context.Context, query storage.Query, h storage.IterHandler) error {
 	q, err := toFileClauses(query)
 	if err != nil {
 		return err
 	}
+	h, flush, err := toFileHandler(query, h)
+	if err != nil {
+		return err
+	}
+	bucket := st.session.Bucket(st.env.BucketName)
+	if err := iterFiles(ctx, bucket, q, st.keyed(h)); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return fmt.Errorf("%w: %s", errorhandler.ErrGetFile, err.Error())
+	}
+	return nil
+}
+
+func (st *Cloud) ListPage(ctx context.Context, query storage.Query, h storage.IterHandler) (string, error) {
+	q, err := toFileClauses(query)
+	if err != nil {
+		return "", err
+	}
+	pageSize := query.PageSize
+	if pageSize <= 0 {
+		pageSize = MaxPageSize
+	}
+	sorted := query.Has(storage.FileSort) && (query.SortBy != storage.SortByName || query.SortDesc)
+	if sorted && query.Has(storage.FilePageToken) {
+		return "", fmt.Errorf("%w: sort needs the whole listing in one page", errorhandler.ErrInvalidQuery)
+	}
+	flush := func() error { return nil }
+	if sorted {
+		h, flush = storage.Sort(query, h)
+	}
+	var count int
+	filtered, err := storage.Filter(query, func(file storage.File) error {
+		count++
+		return h(file)
+	})
+	if err != nil {
+		return "", err
+	}
+	// every Cloud Storage page asks for the items still missing, so the filtered page
+	// fills up to pageSize and always ends on a Cloud Storage page token
+	bucket := st.session.Bucket(st.env.BucketName)
+	nextPageToken := query.PageToken
+	for {
+		nextPageToken, err = iterPage(ctx, bucket, q, pageSize-count, nextPageToken, st.keyed(filtered))
+		if err != nil {
+			return "", err
+		}
+		if nextPageToken == "" || count == pageSize {
+			break
+		}
+	}
+	if sorted && nextPageToken != "" {
+		return "", fmt.Errorf("%w: sort needs the whole listing in one page", errorhandler.ErrInvalidQuery)
+	}
+	if err := flush(); err != nil {
+		return "", fmt.Errorf("%w: %s", errorhandler.ErrGetFile, err.Error())
+	}
+	return nextPageToken, nil
+}
+
+func (st *Cloud) Stat(ctx context.Context, route string) (storage.File, error) {
+	if err := verifyPath(route); err != nil {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrGetFile, err.Error())
+	}
+	bucket := st.session.Bucket(st.env.BucketName)
+	attrs, err := bucket.Object(route).Attrs(ctx)
+	if errors.Is(err, gs.ErrObjectNotExist) {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrFileNotExist, route)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrGetFile, err.Error())
+	}
+	return st.attrsFile(bucket, attrs)
+}
+
+func (st *Cloud) UpdateMetadata(ctx context.Context, route string, metadata map[string]string, conds ...storage.Precondition) (storage.File, error) {
+	if err := verifyPath(route); err != nil {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrFileUpdate, err.Error())
+	}
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	bucket := st.session.Bucket(st.env.BucketName)
+	object, err := st.object(ctx, bucket, route)
+	if err != nil {
+		return nil, err
+	}
+	var attrs *gs.ObjectAttrs
+	if removesKeys(metadata) {
+		attrs, err = replaceMetadata(ctx, object, storage.MergePreconditions(conds...), metadata)
+	} else {
+		attrs, err = withConditions(object, storage.MergePreconditions(conds...)).Update(ctx, gs.ObjectAttrsToUpdate{
+			Metadata: metadata,
+		})
+	}
+	if errors.Is(err, gs.ErrObjectNotExist) {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrFileNotExist, route)
+	}
+	if isPreconditionFailed(err) {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrPreconditionFailed, route)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrFileUpdate, err.Error())
+	}
+	return st.attrsFile(bucket, attrs)
+}
+
+// removesKeys reports whether metadata removes a key by an empty value.
+func removesKeys(metadata map[string]string) bool {
+	for _, value := range metadata {
+		if value == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceMetadata merges metadata into the metadata read from object, dropping the keys of
+// empty values. A patch only merges keys, so the metadata is cleared then written again, both
+// at the read generation and metageneration so that a concurrent change fails the update.
+func replaceMetadata(ctx context.Context, object *gs.ObjectHandle, cond storage.Precondition, metadata map[string]string) (*gs.ObjectAttrs, error) {
+	current, err := withConditions(object, cond).Attrs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	merged := map[string]string{}
+	for key, value := range current.Metadata {
+		merged[key] = value
+	}
+	for key, value := range metadata {
+		if value == "" {
+			delete(merged, key)
+		} else {
+			merged[key] = value
+		}
+	}
+	guard := gs.Conditions{GenerationMatch: current.Generation, MetagenerationMatch: current.Metageneration}
+	attrs, err := object.If(guard).Update(ctx, gs.ObjectAttrsToUpdate{Metadata: map[string]string{}})
+	if err != nil || len(merged) == 0 {
+		return attrs, err
+	}
+	guard.MetagenerationMatch = attrs.Metageneration
+	return object.If(guard).Update(ctx, gs.ObjectAttrsToUpdate{Metadata: merged})
+}
+
+func (st *Cloud) Versions(ctx context.Context, route string, h storage.IterHandler) error {
+	if err := verifyPath(route); err != nil {
+		return fmt.Errorf("%w: %s", errorhandler.ErrGetFile, err.Error())
+	}
+	var found bool
+	bucket := st.session.Bucket(st.env.BucketName)
+	q := &gs.Query{Prefix: route, Versions: true}
+	if err := iterFiles(ctx, bucket, q, st.keyed(func(file storage.File) error {
+		// the prefix also matches longer names
+		if file.Path() != route {
+			return nil
+		}
+		found = true
+		return h(file)
+	})); err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%w: %s", errorhandler.ErrFileNotExist, route)
+	}
+	return nil
+}
+
+func (st *Cloud) Download(ctx context.Context, route string, generation int64) (storage.File, io.ReadCloser, error) {
+	if err := verifyPath(route); err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", errorhandler.ErrGetFile, err.Error())
+	}
 	bucket := st.session.Bucket(st.env.BucketName)
-	return iterFiles(ctx, bucket, q, h)
+	object, err := st.object(ctx, bucket, route)
+	if err != nil {
+		return nil, nil, err
+	}
+	if generation != 0 {
+		object = object.Generation(generation)
+	}
+	attrs, err := object.Attrs(ctx)
+	if errors.Is(err, gs.ErrObjectNotExist) {
+		return nil, nil, notExist(route, generation)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", errorhandler.ErrGetFile, err.Error())
+	}
+	file, err := st.attrsFile(bucket, attrs)
+	if err != nil {
+		return nil, nil, err
+	}
+	// pin the generation read by Attrs, a concurrent write must not change the content
+	rc, err := object.Generation(attrs.Generation).NewReader(ctx)
+	if errors.Is(err, gs.ErrObjectNotExist) {
+		return nil, nil, notExist(route, generation)
+	}
+	if isKeyRejected(err) {
+		return nil, nil, fmt.Errorf("%w: %s", errorhandler.ErrEncryptionKey, err.Error())
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", errorhandler.ErrGetFile, err.Error())
+	}
+	return file, rc, nil
+}
+
+func (st *Cloud) RestoreVersion(ctx context.Context, route string, generation int64, conds ...storage.Precondition) error {
+	if err := verifyPath(route); err != nil {
+		return fmt.Errorf("%w: %s", errorhandler.ErrFileCopy, err.Error())
+	}
+	if generation <= 0 {
+		return fmt.Errorf("%w: generation is required", errorhandler.ErrFileCopy)
+	}
+	object, err := st.object(ctx, st.session.Bucket(st.env.BucketName), route)
+	if err != nil {
+		return err
+	}
+	live := withConditions(object, storage.MergePreconditions(conds...))
+	_, err = live.CopierFrom(object.Generation(generation)).Run(ctx)
+	if errors.Is(err, gs.ErrObjectNotExist) {
+		return notExist(route, generation)
+	}
+	if isPreconditionFailed(err) {
+		return fmt.Errorf("%w: %s", errorhandler.ErrPreconditionFailed, route)
+	}
+	if isLocked(err) {
+		return fmt.Errorf("%w: %s", errorhandler.ErrObjectLocked, route)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", errorhandler.ErrFileCopy, err.Error())
+	}
+	return nil
+}
+
+func (st *Cloud) RemoveVersion(ctx context.Context, route string, generation int64) error {
+	if err := verifyPath(route); err != nil {
+		return fmt.Errorf("%w: %s", errorhandler.ErrFileRemove, err.Error())
+	}
+	if generation <= 0 {
+		return fmt.Errorf("%w: generation is required", errorhandler.ErrFileRemove)
+	}
+	object := st.session.Bucket(st.env.BucketName).Object(route)
+	attrs, err := object.Attrs(ctx)
+	if err != nil && !errors.Is(err, gs.ErrObjectNotExist) {
+		return fmt.Errorf("%w: %s", errorhandler.ErrFileRemove, err.Error())
+	}
+	if attrs != nil && attrs.Generation == generation {
+		return fmt.Errorf("%w: %s#%d is live", errorhandler.ErrPreconditionFailed, route, generation)
+	}
+	err = object.Generation(generation).Delete(ctx)
+	if errors.Is(err, gs.ErrObjectNotExist) {
+		return notExist(route, generation)
+	}
+	if isLocked(err) {
+		return fmt.Errorf("%w: %s#%d", errorhandler.ErrObjectLocked, route, generation)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", errorhandler.ErrFileRemove, err.Error())
+	}
+	return nil
+}
+
+func (st *Cloud) ObjectLock(ctx context.Context, route string) (storage.ObjectLock, error) {
+	if err := verifyPath(route); err != nil {
+		return storage.ObjectLock{}, fmt.Errorf("%w: %s", errorhandler.ErrGetFile, err.Error())
+	}
+	attrs, err := st.session.Bucket(st.env.BucketName).Object(route).Attrs(ctx)
+	if errors.Is(err, gs.ErrObjectNotExist) {
+		return storage.ObjectLock{}, fmt.Errorf("%w: %s", errorhandler.ErrFileNotExist, route)
+	}
+	if err != nil {
+		return storage.ObjectLock{}, fmt.Errorf("%w: %s", errorhandler.ErrGetFile, err.Error())
+	}
+	return objectLock(attrs), nil
+}
+
+func (st *Cloud) SetHold(ctx context.Context, route string, hold storage.Hold) (storage.ObjectLock, error) {
+	if err := verifyPath(route); err != nil {
+		return storage.ObjectLock{}, fmt.Errorf("%w: %s", errorhandler.ErrFileUpdate, err.Error())
+	}
+	update := gs.ObjectAttrsToUpdate{}
+	if hold.EventBased != nil {
+		update.EventBasedHold = *hold.EventBased
+	}
+	if hold.Temporary != nil {
+		update.TemporaryHold = *hold.Temporary
+	}
+	object, err := st.object(ctx, st.session.Bucket(st.env.BucketName), route)
+	if err != nil {
+		return storage.ObjectLock{}, err
+	}
+	attrs, err := object.Update(ctx, update)
+	if errors.Is(err, gs.ErrObjectNotExist) {
+		return storage.ObjectLock{}, fmt.Errorf("%w: %s", errorhandler.ErrFileNotExist, route)
+	}
+	if err != nil {
+		return storage.ObjectLock{}, fmt.Errorf("%w: %s", errorhandler.ErrFileUpdate, err.Error())
+	}
+	return objectLock(attrs), nil
+}
+
+func (st *Cloud) Retention(ctx context.Context) (storage.Retention, error) {
+	attrs, err := st.session.Bucket(st.env.BucketName).Attrs(ctx)
+	if err != nil {
+		return storage.Retention{}, fmt.Errorf("%w: %s", errorhandler.ErrRetention, err.Error())
+	}
+	return retention(attrs.RetentionPolicy), nil
+}
+
+func (st *Cloud) SetRetention(ctx context.Context, periodSeconds int64) (storage.Retention, error) {
+	if periodSeconds < 0 {
+		return storage.Retention{}, fmt.Errorf("%w: negative retention period", errorhandler.ErrInvalidQuery)
+	}
+	attrs, err := st.session.Bucket(st.env.BucketName).Update(ctx, gs.BucketAttrsToUpdate{
+		RetentionPolicy: &gs.RetentionPolicy{RetentionPeriod: time.Duration(periodSeconds) * time.Second},
+	})
+	if isLocked(err) {
+		return storage.Retention{}, fmt.Errorf("%w: retention policy of %s", errorhandler.ErrObjectLocked, st.env.BucketName)
+	}
+	if err != nil {
+		return storage.Retention{}, fmt.Errorf("%w: %s", errorhandler.ErrRetention, err.Error())
+	}
+	return retention(attrs.RetentionPolicy), nil
+}
+
+func retention(policy *gs.RetentionPolicy) storage.Retention {
+	if policy == nil {
+		return storage.Retention{}
+	}
+	return storage.Retention{
+		PeriodSeconds: int64(policy.RetentionPeriod / time.Second),
+		Locked:        policy.IsLocked,
+	}
+}
+
+func objectLock(attrs *gs.ObjectAttrs) storage.ObjectLock {
+	return storage.ObjectLock{
+		EventBasedHold: attrs.EventBasedHold,
+		TemporaryHold:  attrs.TemporaryHold,
+		RetainUntil:    attrs.RetentionExpirationTime,
+	}
+}
+
+func (st *Cloud) Lifecycle(ctx context.Context) (storage.Lifecycle, error) {
+	attrs, err := st.session.Bucket(st.env.BucketName).Attrs(ctx)
+	if err != nil {
+		return storage.Lifecycle{}, fmt.Errorf("%w: %s", errorhandler.ErrLifecycle, err.Error())
+	}
+	lifecycle := storage.Lifecycle{Rules: []storage.LifecycleRule{}}
+	for _, rule := range attrs.Lifecycle.Rules {
+		lifecycle.Rules = append(lifecycle.Rules, storage.LifecycleRule{
+			Action:                storage.LifecycleActionType(rule.Action.Type),
+			StorageClass:          rule.Action.StorageClass,
+			AgeInDays:             rule.Condition.AgeInDays,
+			NumNewerVersions:      rule.Condition.NumNewerVersions,
+			Liveness:              livenessTypes[rule.Condition.Liveness],
+			MatchesStorageClasses: rule.Condition.MatchesStorageClasses,
+		})
+	}
+	return lifecycle, nil
+}
+
+func (st *Cloud) SetLifecycle(ctx context.Context, lifecycle storage.Lifecycle) error {
+	if err := lifecycle.Validate(); err != nil {
+		return err
+	}
+	rules := gs.Lifecycle{}
+	for _, rule := range lifecycle.Rules {
+		liveness := gs.LiveAndArchived
+		for key, value := range livenessTypes {
+			if value == rule.Liveness {
+				liveness = key
+			}
+		}
+		rules.Rules = append(rules.Rules, gs.LifecycleRule{
+			Action: gs.LifecycleAction{
+				Type:         string(rule.Action),
+				StorageClass: rule.StorageClass,
+			},
+			Condition: gs.LifecycleCondition{
+				AgeInDays:             rule.AgeInDays,
+				NumNewerVersions:      rule.NumNewerVersions,
+				Liveness:              liveness,
+				MatchesStorageClasses: rule.MatchesStorageClasses,
+			},
+		})
+	}
+	if _, err := st.session.Bucket(st.env.BucketName).Update(ctx, gs.BucketAttrsToUpdate{Lifecycle: &rules}); err != nil {
+		return fmt.Errorf("%w: %s", errorhandler.ErrLifecycle, err.Error())
+	}
+	return nil
+}
+
+var livenessTypes = map[gs.Liveness]storage.LivenessType{
+	gs.LiveAndArchived: storage.LivenessAny,
+	gs.Live:            storage.LivenessLive,
+	gs.Archived:        storage.LivenessArchived,
+}
+
+func notExist(route string, generation int64) error {
+	if generation != 0 {
+		return fmt.Errorf("%w: %s#%d", errorhandler.ErrVersionNotExist, route, generation)
+	}
+	return fmt.Errorf("%w: %s", errorhandler.ErrFileNotExist, route)
 }
 
 func iterFiles(ctx context.Context, handler *gs.BucketHandle, q *gs.Query, h storage.IterHandler) error {
@@ -202,34 +1005,136 @@ func iterFiles(ctx context.Context, handler *gs.BucketHandle, q *gs.Query, h sto
 		if err != nil {
 			return fmt.Errorf("%w: %s", errorhandler.ErrGetFile, err.Error())
 		}
-		publicURL, err := getPublicURL(attrs.Bucket, attrs.Name)
-		if err != nil {
-			return fmt.Errorf("%w: %s", errorhandler.ErrGetFile, err.Error())
+		if err := handleFile(handler, q, attrs, h); err != nil {
+			return err
 		}
-		node := &File{}
-		if attrs.Prefix == "" {
-			node.Handle = handler
-			node.FilePath = attrs.Name
-			node.PublicURL = publicURL
-			node.MediaLink = attrs.MediaLink
-			node.ContentType = attrs.ContentType
-			node.FileSize = attrs.Size
-			node.Created = attrs.Created
-			node.Updated = attrs.Updated
-		} else {
-			node.Folder = &Folder{
-				Name: strings.TrimSuffix(strings.TrimPrefix(attrs.Prefix, q.Prefix), "/"),
-				Path: attrs.Prefix,
-			}
+	}
+	return nil
+}
+
+func iterPage(ctx context.Context, handler *gs.BucketHandle, q *gs.Query, pageSize int, pageToken string, h storage.IterHandler) (string, error) {
+	var page []*gs.ObjectAttrs
+	nextPageToken, err := iterator.NewPager(handler.Objects(ctx, q), pageSize, pageToken).NextPage(&page)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", errorhandler.ErrGetFile, err.Error())
+	}
+	for _, attrs := range page {
+		if err := handleFile(handler, q, attrs, h); err != nil {
+			return "", err
 		}
-		err = h(node)
-		if err != nil {
-			return fmt.Errorf("%w: %s", errorhandler.ErrGetFile, err.Error())
+	}
+	return nextPageToken, nil
+}
+
+func handleFile(handler *gs.BucketHandle, q *gs.Query, attrs *gs.ObjectAttrs, h storage.IterHandler) error {
+	// folder placeholders are listed through their prefix only
+	if attrs.Prefix == "" && strings.HasSuffix(attrs.Name, "/") {
+		return nil
+	}
+	publicURL, err := getPublicURL(attrs.Bucket, attrs.Name)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errorhandler.ErrGetFile, err.Error())
+	}
+	node := &File{}
+	if attrs.Prefix == "" {
+		node = newFile(handler, attrs, publicURL)
+	} else {
+		node.Folder = &Folder{
+			Name: strings.TrimSuffix(strings.TrimPrefix(attrs.Prefix, q.Prefix), "/"),
+			Path: attrs.Prefix,
 		}
 	}
+	if err := h(node); err != nil {
+		return fmt.Errorf("%w: %s", errorhandler.ErrGetFile, err.Error())
+	}
 	return nil
 }
 
+func newFile(handler *gs.BucketHandle, attrs *gs.ObjectAttrs, publicURL string) *File {
+	return &File{
+		Handle:    handler,
+		FilePath:  attrs.Name,
+		PublicURL: publicURL,
+		MediaLink: attrs.MediaLink,
+		MimeType:  attrs.ContentType,
+		FileSize:  attrs.Size,
+		FileGen:   attrs.Generation,
+		FileMeta:  attrs.Metadata,
+		Created:   attrs.Created,
+		Updated:   attrs.Updated,
+	}
+}
+
+func (st *Cloud) attrsFile(handler *gs.BucketHandle, attrs *gs.ObjectAttrs) (*File, error) {
+	publicURL, err := getPublicURL(attrs.Bucket, attrs.Name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrGetFile, err.Error())
+	}
+	file := newFile(handler, attrs, publicURL)
+	file.Keys = st.keys
+	return file, nil
+}
+
+// folderObjects returns the names of every object under prefix, placeholders included.
+func folderObjects(ctx context.Context, handler *gs.BucketHandle, prefix string) ([]string, error) {
+	var names []string
+	iter := handler.Objects(ctx, &gs.Query{Prefix: prefix})
+	for {
+		attrs, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			return names, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errorhandler.ErrGetFile, err.Error())
+		}
+		names = append(names, attrs.Name)
+	}
+}
+
+// folderPrefix verifies the folder path and returns it with a single trailing slash.
+func folderPrefix(route string) (string, error) {
+	route = strings.TrimSuffix(route, "/")
+	if err := verifyPath(route); err != nil {
+		return "", err
+	}
+	return route + "/", nil
+}
+
+func withConditions(object *gs.ObjectHandle, cond storage.Precondition) *gs.ObjectHandle {
+	if cond.IsZero() {
+		return object
+	}
+	return object.If(gs.Conditions{
+		GenerationMatch: cond.GenerationMatch,
+		DoesNotExist:    cond.DoesNotExist,
+	})
+}
+
+// isChecksumRejected reports whether GCS refused content differing from the digests sent with it.
+func isChecksumRejected(err error) bool {
+	var e *googleapi.Error
+	if !errors.As(err, &e) || e.Code != http.StatusBadRequest {
+		return false
+	}
+	message := strings.ToLower(e.Message)
+	return strings.Contains(message, "md5") || strings.Contains(message, "crc32c")
+}
+
+// isLocked reports whether GCS refused the change of an object under hold or retention.
+func isLocked(err error) bool {
+	var e *googleapi.Error
+	if !errors.As(err, &e) || e.Code != http.StatusForbidden {
+		return false
+	}
+	message := strings.ToLower(e.Message)
+	return strings.Contains(message, "hold") || strings.Contains(message, "retention")
+}
+
+func isPreconditionFailed(err error) bool {
+	var e *googleapi.Error
+	return errors.As(err, &e) && e.Code == http.StatusPreconditionFailed
+}
+
 func getPublicURL(bucketName, route string) (string, error) {
 	u, err := url.Parse(StorageDomain)
 	if err != nil {
@@ -249,7 +1154,7 @@ func verifyPath(path string) error {
 		return errors.New("invalid path")
 	}
 	// soft
-	matched, err := regexp.MatchString(`^(/*[\w\-.()$%& ]+)+$`, path)
+	matched, err := regexp.MatchString(`^(/*[\w\-.()$%&@ ]+)+$`, path)
 	if err != nil {
 		return err
 	}
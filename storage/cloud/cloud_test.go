@@ -8,9 +8,14 @@ import (
 	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
 	"github.com/justdomepaul/gin-storage/storage"
 	"github.com/stretchr/testify/suite"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
+	"io"
+	"net/http"
 	"os"
+	"path"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -90,6 +95,17 @@ func (suite *CloudSuite) TestUploadMethod() {
 				Match:      true,
 			},
 		},
+		{
+			Label: "Upload media without prefix path into sub prefix",
+			Media: config.Media{
+				BucketName: "staging.megaphone.appspot.com",
+			},
+			Prefix: "sub",
+			Want: want{
+				PathPrefix: "sub/",
+				Match:      true,
+			},
+		},
 		{
 			Label: "Upload media into prefix path and sub prefix",
 			Media: config.Media{
@@ -111,6 +127,59 @@ func (suite *CloudSuite) TestUploadMethod() {
 	}
 }
 
+func (suite *CloudSuite) TestUploadChecksums() {
+	media := config.Media{
+		BucketName: "staging.megaphone.appspot.com",
+		PrefixPath: "/media/",
+	}
+	file := NewFile(media, suite.client)
+	content := "checked content"
+	hasher := storage.NewHasher()
+	_, _ = hasher.Write([]byte(content))
+	sums := hasher.Sum()
+
+	testCases := []struct {
+		Label     string
+		Checksums storage.Checksums
+		Error     error
+	}{
+		{
+			Label:     "Upload with digests",
+			Checksums: storage.Checksums{CRC32C: sums.CRC32C, MD5: sums.MD5},
+		},
+		{
+			Label:     "Upload with differing md5",
+			Checksums: storage.Checksums{MD5: "XrY7u+Ae7tCTyyK7j1rNww=="},
+			Error:     errorhandler.ErrChecksumMismatch,
+		},
+		{
+			Label:     "Upload with differing crc32c",
+			Checksums: storage.Checksums{CRC32C: storage.EncodeCRC32C(1)},
+			Error:     errorhandler.ErrChecksumMismatch,
+		},
+		{
+			Label:     "Upload with invalid md5",
+			Checksums: storage.Checksums{MD5: "md5"},
+			Error:     errorhandler.ErrFileUpload,
+		},
+	}
+	for _, tc := range testCases {
+		_, err := file.Upload(suite.ctx, "checksum", io.NopCloser(strings.NewReader(content)), storage.UploadAttrs{Checksums: tc.Checksums})
+		if tc.Error != nil {
+			suite.ErrorIs(err, tc.Error, tc.Label)
+			continue
+		}
+		suite.NoError(err, tc.Label)
+	}
+}
+
+func (suite *CloudSuite) TestIsChecksumRejected() {
+	suite.True(isChecksumRejected(&googleapi.Error{Code: http.StatusBadRequest, Message: "Provided MD5 hash does not match calculated MD5 hash."}))
+	suite.True(isChecksumRejected(&googleapi.Error{Code: http.StatusBadRequest, Message: "Provided CRC32C does not match calculated CRC32C."}))
+	suite.False(isChecksumRejected(&googleapi.Error{Code: http.StatusBadRequest, Message: "Invalid argument."}))
+	suite.False(isChecksumRejected(nil))
+}
+
 func (suite *CloudSuite) TestGetURLMethod() {
 	f, err := os.Open("./image.png")
 	suite.NoError(err)
@@ -250,6 +319,430 @@ func (suite *CloudSuite) TestRemoveMethod() {
 	}
 }
 
+func (suite *CloudSuite) TestCopyMethod() {
+	media := config.Media{
+		BucketName: "staging.megaphone.appspot.com",
+		PrefixPath: "/media/",
+	}
+	file := NewFile(media, suite.client)
+	upload := func() string {
+		f, err := os.Open("./image.png")
+		suite.NoError(err)
+		defer f.Close()
+		result, err := file.Upload(suite.ctx, "copy", f)
+		suite.NoError(err)
+		return result
+	}
+	src, dst := upload(), upload()
+
+	type want struct {
+		Error error
+	}
+
+	testCases := []struct {
+		Label     string
+		Src       string
+		Dst       string
+		Overwrite bool
+		Want      want
+	}{
+		{
+			Label: "Copy media",
+			Src:   src,
+			Dst:   src + "-copy",
+		},
+		{
+			Label: "Copy media onto existing",
+			Src:   src,
+			Dst:   dst,
+			Want: want{
+				Error: errorhandler.ErrFileExist,
+			},
+		},
+		{
+			Label:     "Copy media overwrite existing",
+			Src:       src,
+			Dst:       dst,
+			Overwrite: true,
+		},
+		{
+			Label: "Copy not exist media",
+			Src:   "/media/copy/not-exist",
+			Dst:   "/media/copy/not-exist-copy",
+			Want: want{
+				Error: errorhandler.ErrFileNotExist,
+			},
+		},
+		{
+			Label: "Copy media path format fail",
+			Src:   src,
+			Dst:   "/media/copy/",
+			Want: want{
+				Error: errorhandler.ErrFileCopy,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		err := file.Copy(suite.ctx, tc.Src, tc.Dst, tc.Overwrite)
+		if tc.Want.Error != nil {
+			suite.ErrorIs(err, tc.Want.Error, tc.Label)
+			continue
+		}
+		suite.NoError(err, tc.Label)
+		_, err = suite.client.Bucket(media.BucketName).Object(tc.Dst).Attrs(suite.ctx)
+		suite.NoError(err, tc.Label)
+	}
+}
+
+func (suite *CloudSuite) TestMoveMethod() {
+	media := config.Media{
+		BucketName: "staging.megaphone.appspot.com",
+		PrefixPath: "/media/",
+	}
+	f, err := os.Open("./image.png")
+	suite.NoError(err)
+	defer f.Close()
+	file := NewFile(media, suite.client)
+	src, err := file.Upload(suite.ctx, "move", f)
+	suite.NoError(err)
+
+	suite.ErrorIs(file.Move(suite.ctx, src, src, false), errorhandler.ErrFileCopy)
+	suite.NoError(file.Move(suite.ctx, src, src+"-moved", false))
+	_, err = suite.client.Bucket(media.BucketName).Object(src).Attrs(suite.ctx)
+	suite.ErrorIs(err, gs.ErrObjectNotExist)
+	_, err = suite.client.Bucket(media.BucketName).Object(src + "-moved").Attrs(suite.ctx)
+	suite.NoError(err)
+	suite.ErrorIs(file.Move(suite.ctx, src, src+"-again", false), errorhandler.ErrFileNotExist)
+}
+
+func (suite *CloudSuite) TestFolderMethods() {
+	media := config.Media{
+		BucketName: "staging.megaphone.appspot.com",
+		PrefixPath: "/folder/",
+	}
+	file := NewFile(media, suite.client)
+
+	prefix, err := file.CreateFolder(suite.ctx, "/folder/src")
+	suite.NoError(err)
+	suite.Equal("/folder/src/", prefix)
+	_, err = file.CreateFolder(suite.ctx, "/folder/src/")
+	suite.ErrorIs(err, errorhandler.ErrFileExist)
+	_, err = file.CreateFolder(suite.ctx, "")
+	suite.ErrorIs(err, errorhandler.ErrFileUpload)
+
+	for _, sub := range []string{"src", "src", "src/child"} {
+		f, err := os.Open("./image.png")
+		suite.NoError(err)
+		_, err = file.Upload(suite.ctx, sub, f)
+		suite.NoError(err)
+		suite.NoError(f.Close())
+	}
+
+	var folders []string
+	suite.NoError(file.List(suite.ctx, storage.WithFileCloudDelimiter(storage.WithFileCloudPrefix(storage.Query{}, "/folder/src/"), "/"), func(file storage.File) error {
+		if _, folderPath, exist := file.FolderInfo(); exist {
+			folders = append(folders, folderPath)
+		} else {
+			suite.NotEqual("/folder/src/", file.Path())
+		}
+		return nil
+	}))
+	suite.Equal([]string{"/folder/src/child/"}, folders)
+
+	count, err := file.RemoveFolder(suite.ctx, "/folder/src", true)
+	suite.NoError(err)
+	suite.Equal(4, count)
+
+	_, err = file.RenameFolder(suite.ctx, "/folder/src", "/folder/src/inner", nil)
+	suite.ErrorIs(err, errorhandler.ErrFileCopy)
+	var progress []int
+	var mu sync.Mutex
+	count, err = file.RenameFolder(suite.ctx, "/folder/src", "/folder/dst", func(done, total int) {
+		mu.Lock()
+		defer mu.Unlock()
+		suite.Equal(4, total)
+		progress = append(progress, done)
+	})
+	suite.NoError(err)
+	suite.Equal(4, count)
+	suite.ElementsMatch([]int{1, 2, 3, 4}, progress)
+	_, err = file.RenameFolder(suite.ctx, "/folder/src", "/folder/other", nil)
+	suite.ErrorIs(err, errorhandler.ErrFolderNotExist)
+
+	count, err = file.RemoveFolder(suite.ctx, "/folder/dst/", false)
+	suite.NoError(err)
+	suite.Equal(4, count)
+	count, err = file.RemoveFolder(suite.ctx, "/folder/dst", true)
+	suite.NoError(err)
+	suite.Equal(0, count)
+}
+
+func (suite *CloudSuite) TestFolderMethodsCSEK() {
+	media := config.Media{
+		BucketName: "staging.megaphone.appspot.com",
+		PrefixPath: "/keyed/",
+	}
+	keys, err := NewStaticKeys(config.CSEK{CSEKKey: csekKey('k')}, media.PrefixPath)
+	suite.NoError(err)
+	file := NewFile(media, suite.client).WithKeys(keys)
+
+	prefix, err := file.CreateFolder(suite.ctx, "/keyed/src")
+	suite.NoError(err)
+	suite.Equal("/keyed/src/", prefix)
+	pt, err := file.Upload(suite.ctx, "src", io.NopCloser(strings.NewReader("keyed content")))
+	suite.NoError(err)
+
+	count, err := file.RenameFolder(suite.ctx, "/keyed/src", "/keyed/dst", nil)
+	suite.NoError(err)
+	suite.Equal(2, count)
+	_, reader, err := file.Download(suite.ctx, "/keyed/dst/"+strings.TrimPrefix(pt, "/keyed/src/"), 0)
+	suite.NoError(err)
+	b, err := io.ReadAll(reader)
+	suite.NoError(err)
+	suite.NoError(reader.Close())
+	suite.Equal("keyed content", string(b))
+
+	count, err = file.RemoveFolder(suite.ctx, "/keyed/dst", false)
+	suite.NoError(err)
+	suite.Equal(2, count)
+}
+
+func (suite *CloudSuite) TestVersionMethods() {
+	media := config.Media{
+		BucketName: "staging.megaphone.appspot.com",
+		PrefixPath: "/media/",
+	}
+	file := NewFile(media, suite.client)
+	f, err := os.Open("./image.png")
+	suite.NoError(err)
+	defer f.Close()
+	route, err := file.Upload(suite.ctx, "versions", f)
+	suite.NoError(err)
+
+	var generations []int64
+	suite.NoError(file.Versions(suite.ctx, route, func(file storage.File) error {
+		suite.Equal(route, file.Path())
+		generations = append(generations, file.Generation())
+		return nil
+	}))
+	suite.NotEmpty(generations)
+	generation := generations[0]
+	suite.NotZero(generation)
+	suite.ErrorIs(file.Versions(suite.ctx, "/media/versions/not-exist", func(storage.File) error { return nil }), errorhandler.ErrFileNotExist)
+
+	live, reader, err := file.Download(suite.ctx, route, 0)
+	suite.NoError(err)
+	content, err := io.ReadAll(reader)
+	suite.NoError(err)
+	suite.NoError(reader.Close())
+	size, err := live.Size()
+	suite.NoError(err)
+	suite.Equal(size, int64(len(content)))
+
+	version, reader, err := file.Download(suite.ctx, route, generation)
+	suite.NoError(err)
+	suite.NoError(reader.Close())
+	suite.Equal(generation, version.Generation())
+	_, _, err = file.Download(suite.ctx, route, generation+1000)
+	suite.ErrorIs(err, errorhandler.ErrVersionNotExist)
+	_, _, err = file.Download(suite.ctx, "/media/versions/not-exist", 0)
+	suite.ErrorIs(err, errorhandler.ErrFileNotExist)
+
+	suite.NoError(file.RestoreVersion(suite.ctx, route, generation))
+	suite.ErrorIs(file.RestoreVersion(suite.ctx, route, generation+1000), errorhandler.ErrVersionNotExist)
+	suite.ErrorIs(file.RestoreVersion(suite.ctx, route, 0), errorhandler.ErrFileCopy)
+
+	live, err = file.Stat(suite.ctx, route)
+	suite.NoError(err)
+	suite.ErrorIs(file.RemoveVersion(suite.ctx, route, live.Generation()), errorhandler.ErrPreconditionFailed)
+	suite.ErrorIs(file.RemoveVersion(suite.ctx, route, generation+1000), errorhandler.ErrVersionNotExist)
+	suite.ErrorIs(file.RemoveVersion(suite.ctx, route, 0), errorhandler.ErrFileRemove)
+}
+
+func (suite *CloudSuite) TestHoldMethods() {
+	media := config.Media{
+		BucketName: "staging.megaphone.appspot.com",
+		PrefixPath: "/media/",
+	}
+	file := NewFile(media, suite.client)
+	f, err := os.Open("./image.png")
+	suite.NoError(err)
+	defer f.Close()
+	route, err := file.Upload(suite.ctx, "holds", f)
+	suite.NoError(err)
+
+	set, release := true, false
+	lock, err := file.SetHold(suite.ctx, route, storage.Hold{Temporary: &set})
+	suite.NoError(err)
+	suite.True(lock.TemporaryHold)
+	suite.False(lock.EventBasedHold)
+	lock, err = file.ObjectLock(suite.ctx, route)
+	suite.NoError(err)
+	suite.True(lock.Locked(time.Now()))
+
+	suite.ErrorIs(file.Remove(suite.ctx, route), errorhandler.ErrObjectLocked)
+	suite.ErrorIs(file.Move(suite.ctx, route, "/media/holds/moved", false), errorhandler.ErrObjectLocked)
+	_, err = file.RenameFolder(suite.ctx, "/media/holds", "/media/renamed", nil)
+	suite.ErrorIs(err, errorhandler.ErrObjectLocked)
+	_, err = file.Stat(suite.ctx, "/media/renamed/"+path.Base(route))
+	suite.ErrorIs(err, errorhandler.ErrFileNotExist)
+	_, err = file.Upload(suite.ctx, "holds", io.NopCloser(strings.NewReader("overwrite")), storage.UploadAttrs{Name: path.Base(route)})
+	suite.ErrorIs(err, errorhandler.ErrObjectLocked)
+
+	lock, err = file.SetHold(suite.ctx, route, storage.Hold{Temporary: &release})
+	suite.NoError(err)
+	suite.False(lock.Locked(time.Now()))
+	suite.NoError(file.Remove(suite.ctx, route))
+
+	_, err = file.ObjectLock(suite.ctx, route)
+	suite.ErrorIs(err, errorhandler.ErrFileNotExist)
+	_, err = file.SetHold(suite.ctx, route, storage.Hold{Temporary: &set})
+	suite.ErrorIs(err, errorhandler.ErrFileNotExist)
+}
+
+func (suite *CloudSuite) TestRetentionMethods() {
+	media := config.Media{
+		BucketName: "staging.megaphone.appspot.com",
+		PrefixPath: "/media/",
+	}
+	file := NewFile(media, suite.client)
+
+	retention, err := file.SetRetention(suite.ctx, 3600)
+	suite.NoError(err)
+	suite.Equal(storage.Retention{PeriodSeconds: 3600}, retention)
+	route, err := file.Upload(suite.ctx, "retained", io.NopCloser(strings.NewReader("retained")))
+	suite.NoError(err)
+	lock, err := file.ObjectLock(suite.ctx, route)
+	suite.NoError(err)
+	suite.True(lock.Locked(time.Now()))
+	suite.ErrorIs(file.Remove(suite.ctx, route), errorhandler.ErrObjectLocked)
+
+	retention, err = file.SetRetention(suite.ctx, 0)
+	suite.NoError(err)
+	suite.Equal(storage.Retention{}, retention)
+	retention, err = file.Retention(suite.ctx)
+	suite.NoError(err)
+	suite.Equal(storage.Retention{}, retention)
+	suite.NoError(file.Remove(suite.ctx, route))
+
+	_, err = file.SetRetention(suite.ctx, -1)
+	suite.ErrorIs(err, errorhandler.ErrInvalidQuery)
+}
+
+func (suite *CloudSuite) TestLifecycleMethods() {
+	media := config.Media{
+		BucketName: "staging.megaphone.appspot.com",
+		PrefixPath: "/media/",
+	}
+	file := NewFile(media, suite.client)
+	rules := []storage.LifecycleRule{
+		{Action: storage.LifecycleDelete, AgeInDays: 30, Liveness: storage.LivenessArchived},
+		{Action: storage.LifecycleDelete, NumNewerVersions: 3},
+		{Action: storage.LifecycleSetStorageClass, StorageClass: "COLDLINE", AgeInDays: 90},
+	}
+	suite.NoError(file.SetLifecycle(suite.ctx, storage.Lifecycle{Rules: rules}))
+	lifecycle, err := file.Lifecycle(suite.ctx)
+	suite.NoError(err)
+	suite.Equal(rules, lifecycle.Rules)
+
+	suite.ErrorIs(file.SetLifecycle(suite.ctx, storage.Lifecycle{Rules: []storage.LifecycleRule{{Action: storage.LifecycleDelete}}}), errorhandler.ErrLifecycleRule)
+	suite.NoError(file.SetLifecycle(suite.ctx, storage.Lifecycle{}))
+	lifecycle, err = file.Lifecycle(suite.ctx)
+	suite.NoError(err)
+	suite.Empty(lifecycle.Rules)
+}
+
+func (suite *CloudSuite) TestPreconditions() {
+	media := config.Media{
+		BucketName: "staging.megaphone.appspot.com",
+		PrefixPath: "/media/",
+	}
+	file := NewFile(media, suite.client)
+	upload := func() (string, int64) {
+		f, err := os.Open("./image.png")
+		suite.NoError(err)
+		defer f.Close()
+		route, err := file.Upload(suite.ctx, "precondition", f)
+		suite.NoError(err)
+		live, reader, err := file.Download(suite.ctx, route, 0)
+		suite.NoError(err)
+		suite.NoError(reader.Close())
+		return route, live.Generation()
+	}
+	src, _ := upload()
+	dst, generation := upload()
+
+	suite.ErrorIs(file.Copy(suite.ctx, src, dst, false, storage.IfGenerationMatch(generation+1)), errorhandler.ErrPreconditionFailed)
+	suite.ErrorIs(file.Copy(suite.ctx, src, dst, false, storage.IfDoesNotExist()), errorhandler.ErrPreconditionFailed)
+	suite.ErrorIs(file.Copy(suite.ctx, src, dst, false), errorhandler.ErrFileExist)
+	suite.NoError(file.Copy(suite.ctx, src, dst, false, storage.IfGenerationMatch(generation)))
+
+	suite.ErrorIs(file.Remove(suite.ctx, dst, storage.IfGenerationMatch(generation)), errorhandler.ErrPreconditionFailed)
+	live, reader, err := file.Download(suite.ctx, dst, 0)
+	suite.NoError(err)
+	suite.NoError(reader.Close())
+	suite.NoError(file.Remove(suite.ctx, dst, storage.IfGenerationMatch(live.Generation())))
+
+	uploadAt := func(cond storage.Precondition) error {
+		_, err := file.Upload(suite.ctx, "precondition", io.NopCloser(strings.NewReader("12345")), storage.UploadAttrs{
+			Name:         path.Base(src),
+			Precondition: cond,
+		})
+		return err
+	}
+	suite.ErrorIs(uploadAt(storage.IfDoesNotExist()), errorhandler.ErrPreconditionFailed)
+	suite.ErrorIs(uploadAt(storage.IfGenerationMatch(generation+1)), errorhandler.ErrPreconditionFailed)
+	live, reader, err = file.Download(suite.ctx, src, 0)
+	suite.NoError(err)
+	suite.NoError(reader.Close())
+	suite.NoError(uploadAt(storage.IfGenerationMatch(live.Generation())))
+	_, err = file.Upload(suite.ctx, "precondition", io.NopCloser(strings.NewReader("12345")), storage.UploadAttrs{
+		Precondition: storage.IfGenerationMatch(live.Generation()),
+	})
+	suite.ErrorIs(err, errorhandler.ErrPreconditionFailed)
+}
+
+func (suite *CloudSuite) TestMetadataMethods() {
+	media := config.Media{
+		BucketName: "staging.megaphone.appspot.com",
+		PrefixPath: "/media/",
+	}
+	file := NewFile(media, suite.client)
+	f, err := os.Open("./image.png")
+	suite.NoError(err)
+	defer f.Close()
+	route, err := file.Upload(suite.ctx, "metadata", f, storage.UploadAttrs{Metadata: map[string]string{"owner": "42"}})
+	suite.NoError(err)
+
+	stat, err := file.Stat(suite.ctx, route)
+	suite.NoError(err)
+	suite.Equal(map[string]string{"owner": "42"}, stat.Metadata())
+	_, err = file.Stat(suite.ctx, "/media/metadata/not-exist")
+	suite.ErrorIs(err, errorhandler.ErrFileNotExist)
+
+	updated, err := file.UpdateMetadata(suite.ctx, route, map[string]string{"type": "invoice"})
+	suite.NoError(err)
+	suite.Equal(map[string]string{"owner": "42", "type": "invoice"}, updated.Metadata())
+	_, err = file.UpdateMetadata(suite.ctx, route, map[string]string{"type": "receipt"}, storage.IfGenerationMatch(stat.Generation()+1))
+	suite.ErrorIs(err, errorhandler.ErrPreconditionFailed)
+
+	var paths []string
+	suite.NoError(file.List(suite.ctx, storage.WithFileMetadata(storage.WithFileCloudPrefix(storage.Query{}, "/media/metadata/"), "type", "invoice"), func(file storage.File) error {
+		paths = append(paths, file.Path())
+		return nil
+	}))
+	suite.Contains(paths, route)
+
+	removed, err := file.UpdateMetadata(suite.ctx, route, map[string]string{"type": ""})
+	suite.NoError(err)
+	suite.Equal(map[string]string{"owner": "42"}, removed.Metadata())
+
+	cleared, err := file.UpdateMetadata(suite.ctx, route, map[string]string{})
+	suite.NoError(err)
+	suite.Empty(cleared.Metadata())
+}
+
 func (suite *CloudSuite) TestListMethod() {
 	type want struct {
 		FolderNames []string
@@ -398,6 +891,137 @@ func (suite *CloudSuite) TestListMethod() {
 	}
 }
 
+func (suite *CloudSuite) TestListPageMethod() {
+	media := config.Media{
+		BucketName: "staging.megaphone.appspot.com",
+		PrefixPath: "/page/",
+	}
+	for i := 0; i < 5; i++ {
+		f, err := os.Open("./image.png")
+		suite.NoError(err)
+		_, err = NewFile(media, suite.client).Upload(suite.ctx, "sub", f)
+		suite.NoError(err)
+		suite.NoError(f.Close())
+	}
+
+	file := NewFile(media, suite.client)
+	var (
+		paths     []string
+		pageToken string
+		pages     int
+	)
+	for {
+		q := storage.WithFilePageSize(storage.WithFileCloudPrefix(storage.Query{}, "/page/sub/"), 2)
+		if pageToken != "" {
+			q = storage.WithFilePageToken(q, pageToken)
+		}
+		var count int
+		next, err := file.ListPage(suite.ctx, q, func(file storage.File) error {
+			paths = append(paths, file.Path())
+			count++
+			return nil
+		})
+		suite.NoError(err)
+		suite.LessOrEqual(count, 2)
+		pages++
+		if next == "" {
+			break
+		}
+		pageToken = next
+	}
+	suite.Len(paths, 5)
+	suite.Equal(3, pages)
+
+	for i := 0; i < 3; i++ {
+		_, err := file.Upload(suite.ctx, "sub", io.NopCloser(strings.NewReader("12345")), storage.UploadAttrs{ContentType: "text/plain"})
+		suite.NoError(err)
+	}
+	var counts []int
+	pageToken = ""
+	for {
+		q := storage.WithFileContentType(storage.WithFilePageSize(storage.WithFileCloudPrefix(storage.Query{}, "/page/sub/"), 2), "image/png")
+		if pageToken != "" {
+			q = storage.WithFilePageToken(q, pageToken)
+		}
+		var count int
+		next, err := file.ListPage(suite.ctx, q, func(file storage.File) error {
+			count++
+			return nil
+		})
+		suite.NoError(err)
+		counts = append(counts, count)
+		if next == "" {
+			break
+		}
+		pageToken = next
+	}
+	suite.Equal(5, counts[0]+counts[1]+counts[2])
+	suite.Equal([]int{2, 2}, counts[:2])
+
+	sorted := storage.WithFileSort(storage.WithFilePageSize(storage.WithFileCloudPrefix(storage.Query{}, "/page/sub/"), 2), storage.SortBySize, false)
+	_, err := file.ListPage(suite.ctx, sorted, func(file storage.File) error {
+		return nil
+	})
+	suite.ErrorIs(err, errorhandler.ErrInvalidQuery)
+	_, err = file.ListPage(suite.ctx, storage.WithFilePageSize(sorted, MaxPageSize), func(file storage.File) error {
+		return nil
+	})
+	suite.NoError(err)
+
+	_, err = file.ListPage(suite.ctx, storage.WithFilePageSize(storage.Query{}, MaxPageSize+1), func(file storage.File) error {
+		return nil
+	})
+	suite.Error(err)
+}
+
+func (suite *CloudSuite) TestToFileClauses() {
+	testCases := []struct {
+		Label  string
+		Query  storage.Query
+		Prefix string
+		Error  bool
+	}{
+		{
+			Label:  "Glob pushed down as prefix",
+			Query:  storage.WithFileNameGlob(storage.Query{}, "/media/sub/*.png"),
+			Prefix: "/media/sub/",
+		},
+		{
+			Label:  "Glob narrower than prefix",
+			Query:  storage.WithFileNameGlob(storage.WithFileCloudPrefix(storage.Query{}, "/media/"), "/media/sub/a?.png"),
+			Prefix: "/media/sub/a",
+		},
+		{
+			Label:  "Glob wider than prefix",
+			Query:  storage.WithFileCloudPrefix(storage.WithFileNameGlob(storage.Query{}, "/media/*"), "/media/sub/"),
+			Prefix: "/media/sub/",
+		},
+		{
+			Label:  "Glob with delimiter keeps prefix",
+			Query:  storage.WithFileCloudDelimiter(storage.WithFileCloudPrefix(storage.WithFileNameGlob(storage.Query{}, "/media/sub/*"), "/media"), "/"),
+			Prefix: "/media/",
+		},
+		{
+			Label: "Invalid glob",
+			Query: storage.WithFileNameGlob(storage.Query{}, "[a-"),
+			Error: true,
+		},
+		{
+			Label: "Portable filters",
+			Query: storage.WithFileSort(storage.WithFileSizeRange(storage.WithFileContentType(storage.Query{}, "image/*"), 1, 2), storage.SortBySize, true),
+		},
+	}
+	for _, tc := range testCases {
+		q, err := toFileClauses(tc.Query)
+		if tc.Error {
+			suite.Error(err, tc.Label)
+			continue
+		}
+		suite.NoError(err, tc.Label)
+		suite.Equal(tc.Prefix, q.Prefix, tc.Label)
+	}
+}
+
 func TestCloudSuite(t *testing.T) {
 	suite.Run(t, new(CloudSuite))
 }
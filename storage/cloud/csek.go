@@ -0,0 +1,103 @@
+package cloud
+
+import (
+	gs "cloud.google.com/go/storage"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/cockroachdb/errors"
+	configTool "github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"google.golang.org/api/googleapi"
+	"net/http"
+	"strings"
+)
+
+// IKeyLookup returns the customer-supplied encryption key of the object at route,
+// nil when the object is stored with the Google managed keys.
+type IKeyLookup interface {
+	Key(ctx context.Context, route string) ([]byte, error)
+}
+
+// NewStaticKeys reads the base64 AES-256 keys of the bucket and of the tenants, the tenant
+// of an object is the first folder under prefixPath. Nil when no key is configured.
+func NewStaticKeys(env configTool.CSEK, prefixPath string) (*StaticKeys, error) {
+	if env.CSEKKey == "" && len(env.CSEKTenantKeys) == 0 {
+		return nil, nil
+	}
+	keys := &StaticKeys{prefixPath: prefixPath, tenants: map[string][]byte{}}
+	if env.CSEKKey != "" {
+		key, err := decodeKey(env.CSEKKey)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bucket key: %s", errorhandler.ErrInitialFileClient, err.Error())
+		}
+		keys.bucket = key
+	}
+	for tenant, encoded := range env.CSEKTenantKeys {
+		key, err := decodeKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%w: key of tenant %s: %s", errorhandler.ErrInitialFileClient, tenant, err.Error())
+		}
+		keys.tenants[tenant] = key
+	}
+	return keys, nil
+}
+
+// StaticKeys serves the key of the tenant of an object, else the key of the bucket.
+// Without a bucket key, an object of a tenant without key fails with ErrEncryptionKey.
+type StaticKeys struct {
+	prefixPath string
+	bucket     []byte
+	tenants    map[string][]byte
+}
+
+func (k *StaticKeys) Key(ctx context.Context, route string) ([]byte, error) {
+	tenant := strings.Trim(strings.TrimPrefix(route, k.prefixPath), "/")
+	if i := strings.Index(tenant, "/"); i >= 0 {
+		tenant = tenant[:i]
+	}
+	if key, ok := k.tenants[tenant]; ok {
+		return key, nil
+	}
+	if k.bucket != nil {
+		return k.bucket, nil
+	}
+	return nil, fmt.Errorf("%w: no customer-supplied key for tenant %s of %s", errorhandler.ErrEncryptionKey, tenant, route)
+}
+
+// keyedObject is the handle of route, keyed by its customer-supplied encryption key if any.
+// Folder placeholders, named with a trailing slash, are empty and never keyed.
+func keyedObject(ctx context.Context, keys IKeyLookup, bucket *gs.BucketHandle, route string) (*gs.ObjectHandle, error) {
+	object := bucket.Object(route)
+	if keys == nil || strings.HasSuffix(route, "/") {
+		return object, nil
+	}
+	key, err := keys.Key(ctx, route)
+	if err != nil {
+		return nil, err
+	}
+	if key != nil {
+		object = object.Key(key)
+	}
+	return object, nil
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("key is %d bytes, AES-256 needs 32", len(key))
+	}
+	return key, nil
+}
+
+// isKeyRejected reports Cloud Storage refusing an object encrypted by another or a missing customer-supplied key.
+func isKeyRejected(err error) bool {
+	var e *googleapi.Error
+	if !errors.As(err, &e) || (e.Code != http.StatusBadRequest && e.Code != http.StatusForbidden) {
+		return false
+	}
+	return strings.Contains(strings.ToLower(e.Message), "encryption key")
+}
@@ -0,0 +1,89 @@
+package cloud
+
+import (
+	"context"
+	"encoding/base64"
+	configTool "github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/api/googleapi"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type CSEKSuite struct {
+	suite.Suite
+}
+
+func csekKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), 32)))
+}
+
+func (suite *CSEKSuite) TestNewStaticKeys() {
+	keys, err := NewStaticKeys(configTool.CSEK{}, "/media/")
+	suite.NoError(err)
+	suite.Nil(keys)
+
+	_, err = NewStaticKeys(configTool.CSEK{CSEKKey: "AAAA"}, "/media/")
+	suite.ErrorIs(err, errorhandler.ErrInitialFileClient)
+	_, err = NewStaticKeys(configTool.CSEK{CSEKTenantKeys: map[string]string{"a": "not base64"}}, "/media/")
+	suite.ErrorIs(err, errorhandler.ErrInitialFileClient)
+}
+
+func (suite *CSEKSuite) TestKey() {
+	testCases := []struct {
+		Label string
+		Env   configTool.CSEK
+		Route string
+		Key   string
+		Error error
+	}{
+		{
+			Label: "Bucket key",
+			Env:   configTool.CSEK{CSEKKey: csekKey('b')},
+			Route: "/media/tenant1/a.txt",
+			Key:   strings.Repeat("b", 32),
+		},
+		{
+			Label: "Tenant key",
+			Env:   configTool.CSEK{CSEKKey: csekKey('b'), CSEKTenantKeys: map[string]string{"tenant1": csekKey('t')}},
+			Route: "/media/tenant1/folder/a.txt",
+			Key:   strings.Repeat("t", 32),
+		},
+		{
+			Label: "Tenant falls back to bucket key",
+			Env:   configTool.CSEK{CSEKKey: csekKey('b'), CSEKTenantKeys: map[string]string{"tenant1": csekKey('t')}},
+			Route: "/media/tenant2/a.txt",
+			Key:   strings.Repeat("b", 32),
+		},
+		{
+			Label: "Tenant key missing",
+			Env:   configTool.CSEK{CSEKTenantKeys: map[string]string{"tenant1": csekKey('t')}},
+			Route: "/media/tenant2/a.txt",
+			Error: errorhandler.ErrEncryptionKey,
+		},
+	}
+	for _, tc := range testCases {
+		keys, err := NewStaticKeys(tc.Env, "/media/")
+		suite.NoError(err, tc.Label)
+		key, err := keys.Key(context.Background(), tc.Route)
+		if tc.Error != nil {
+			suite.ErrorIs(err, tc.Error, tc.Label)
+			continue
+		}
+		suite.NoError(err, tc.Label)
+		suite.Equal([]byte(tc.Key), key, tc.Label)
+	}
+}
+
+func (suite *CSEKSuite) TestIsKeyRejected() {
+	suite.True(isKeyRejected(&googleapi.Error{Code: http.StatusBadRequest, Message: "The target object is encrypted by a customer-supplied encryption key."}))
+	suite.True(isKeyRejected(&googleapi.Error{Code: http.StatusForbidden, Message: "The provided encryption key is incorrect."}))
+	suite.False(isKeyRejected(&googleapi.Error{Code: http.StatusForbidden, Message: "Object is under active temporary hold."}))
+	suite.False(isKeyRejected(nil))
+}
+
+func TestCSEKSuite(t *testing.T) {
+	suite.Run(t, new(CSEKSuite))
+}
@@ -14,15 +14,18 @@ type Folder struct {
 }
 
 type File struct {
-	Handle      *storage.BucketHandle `json:"-"`
-	FilePath    string                `json:"path,omitempty"`
-	PublicURL   string                `json:"public_url,omitempty"`
-	MediaLink   string                `json:"media_link,omitempty"`
-	ContentType string                `json:"content_type,omitempty"`
-	FileSize    int64                 `json:"size,omitempty"`
-	Created     time.Time             `json:"created,omitempty"`
-	Updated     time.Time             `json:"updated,omitempty"`
-	Folder      *Folder               `json:"folders,omitempty"`
+	Handle    *storage.BucketHandle `json:"-"`
+	FilePath  string                `json:"path,omitempty"`
+	PublicURL string                `json:"public_url,omitempty"`
+	MediaLink string                `json:"media_link,omitempty"`
+	MimeType  string                `json:"content_type,omitempty"`
+	FileSize  int64                 `json:"size,omitempty"`
+	FileGen   int64                 `json:"generation,omitempty"`
+	FileMeta  map[string]string     `json:"metadata,omitempty"`
+	Created   time.Time             `json:"created,omitempty"`
+	Updated   time.Time             `json:"updated,omitempty"`
+	Folder    *Folder               `json:"folders,omitempty"`
+	Keys      IKeyLookup            `json:"-"`
 }
 
 func (f *File) FolderInfo() (name string, path string, exist bool) {
@@ -36,6 +39,12 @@ func (f *File) Path() string { return f.FilePath }
 
 func (f *File) Name() string { return filepath.Base(f.FilePath) }
 
+func (f *File) ContentType() string { return f.MimeType }
+
+func (f *File) Generation() int64 { return f.FileGen }
+
+func (f *File) Metadata() map[string]string { return f.FileMeta }
+
 func (f *File) Size() (int64, error) { return f.FileSize, nil }
 
 func (f *File) CreatedTime() (time.Time, error) { return f.Created, nil }
@@ -43,14 +52,25 @@ func (f *File) CreatedTime() (time.Time, error) { return f.Created, nil }
 func (f *File) ModTime() (time.Time, error) { return f.Updated, nil }
 
 func (f *File) NewWriter(ctx context.Context) (writer io.WriteCloser, closeFn func() error) {
-	wc := f.Handle.Object(f.FilePath).NewWriter(ctx)
+	object, err := f.object(ctx)
+	if err != nil {
+		return failWriter{err}, func() error { return err }
+	}
+	wc := object.NewWriter(ctx)
 	return wc, func() error {
 		return wc.Close()
 	}
 }
 
 func (f *File) NewReader(ctx context.Context) (reader io.ReadCloser, closeFn func() error, err error) {
-	rc, err := f.Handle.Object(f.FilePath).NewReader(ctx)
+	object, err := f.object(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if f.FileGen != 0 {
+		object = object.Generation(f.FileGen)
+	}
+	rc, err := object.NewReader(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -59,6 +79,10 @@ func (f *File) NewReader(ctx context.Context) (reader io.ReadCloser, closeFn fun
 	}, nil
 }
 
+func (f *File) object(ctx context.Context) (*storage.ObjectHandle, error) {
+	return keyedObject(ctx, f.Keys, f.Handle, f.FilePath)
+}
+
 // Remove a file but returns ErrNotFound if not found.
 func (f *File) Remove(ctx context.Context) error {
 	return f.Handle.Object(f.FilePath).Delete(ctx)
@@ -66,3 +90,11 @@ func (f *File) Remove(ctx context.Context) error {
 
 // GetURL fetches the file URL for downloading but returns ErrNotFound if no found.
 func (f *File) GetURL() string { return f.PublicURL }
+
+type failWriter struct {
+	err error
+}
+
+func (w failWriter) Write(p []byte) (int, error) { return 0, w.err }
+
+func (w failWriter) Close() error { return w.err }
@@ -0,0 +1,85 @@
+package compression
+
+import (
+	"compress/gzip"
+	"fmt"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/klauspost/compress/zstd"
+	"io"
+)
+
+const (
+	EncodingGzip = "gzip"
+	EncodingZstd = "zstd"
+)
+
+// codec compresses and decompresses the content of an encoding.
+type codec struct {
+	writer func(w io.Writer) (io.WriteCloser, error)
+	reader func(r io.Reader) (io.ReadCloser, error)
+}
+
+var codecs = map[string]codec{
+	EncodingGzip: {
+		writer: func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil },
+		reader: func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+	},
+	EncodingZstd: {
+		writer: func(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w) },
+		reader: func(r io.Reader) (io.ReadCloser, error) {
+			decoder, err := zstd.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return decoder.IOReadCloser(), nil
+		},
+	},
+}
+
+// compress streams the content of r compressed by encoding, count is set to the
+// uncompressed size once r is drained.
+func compress(r io.Reader, encoding string, count *int64) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		w, err := codecs[encoding].writer(pw)
+		if err != nil {
+			pw.CloseWithError(fmt.Errorf("%w: %s", errorhandler.ErrCompression, err.Error()))
+			return
+		}
+		n, err := io.Copy(w, r)
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		if err := w.Close(); err != nil {
+			pw.CloseWithError(fmt.Errorf("%w: %s", errorhandler.ErrCompression, err.Error()))
+			return
+		}
+		*count = n
+		pw.Close()
+	}()
+	return pr
+}
+
+// decompress reads the content of r decompressed by encoding, closing both once read.
+func decompress(r io.ReadCloser, encoding string) (io.ReadCloser, error) {
+	c, ok := codecs[encoding]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown encoding %s", errorhandler.ErrCompression, encoding)
+	}
+	reader, err := c.reader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrCompression, err.Error())
+	}
+	return readCloser{Reader: reader, close: func() error {
+		reader.Close()
+		return r.Close()
+	}}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r readCloser) Close() error { return r.close() }
@@ -0,0 +1,278 @@
+package compression
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	zapTool "github.com/justdomepaul/toolbox/zap"
+	"github.com/kelseyhightower/envconfig"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// metadata of a compressed object, hidden from the files served by Compression
+const (
+	EncodingKey = "content_encoding"
+	SizeKey     = "uncompressed_size"
+)
+
+// sniffSize is what http.DetectContentType considers.
+const sniffSize = 512
+
+// FromEnv wraps file by the compression configured from environment.
+func FromEnv(file storage.IFile) (*Compression, error) {
+	env := config.Compression{}
+	if err := envconfig.Process("", &env); err != nil {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrInitialFileClient, err.Error())
+	}
+	return NewCompression(env, file)
+}
+
+// NewCompression method
+func NewCompression(env config.Compression, file storage.IFile) (*Compression, error) {
+	if _, ok := codecs[env.CompressionEncoding]; !ok {
+		return nil, fmt.Errorf("%w: unknown compression encoding %s", errorhandler.ErrInitialFileClient, env.CompressionEncoding)
+	}
+	return &Compression{
+		IFile: file,
+		env:   env,
+	}, nil
+}
+
+// Compression compresses uploads of the configured content types and decompresses
+// what is read back. Objects without an encoding are served as is.
+type Compression struct {
+	storage.IFile
+	env config.Compression
+}
+
+func (c *Compression) Unwrap() storage.IFile { return c.IFile }
+
+// Upload compresses the content when its type, given or detected, is eligible and it
+// is not smaller than the configured minimum, the type is kept as the content type.
+func (c *Compression) Upload(ctx context.Context, prefix string, f io.ReadCloser, attrs ...storage.UploadAttrs) (string, error) {
+	peekSize := c.env.CompressionMinBytes
+	if peekSize < sniffSize {
+		peekSize = sniffSize
+	}
+	src := bufio.NewReaderSize(f, peekSize)
+	head, err := src.Peek(peekSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return "", fmt.Errorf("%w: %s", errorhandler.ErrFileUpload, err.Error())
+	}
+	contentType := storage.MergeUploadAttrs(attrs...).ContentType
+	if contentType == "" {
+		contentType = http.DetectContentType(head)
+	}
+	if len(head) < c.env.CompressionMinBytes || !c.eligible(contentType) {
+		return c.IFile.Upload(ctx, prefix, storageReader{Reader: src, Closer: f}, attrs...)
+	}
+
+	var size int64
+	compressed := compress(src, c.env.CompressionEncoding, &size)
+	// a failed upload leaves the compressing goroutine blocked on the pipe otherwise
+	defer compressed.Close()
+	attrs = append(storage.ContentChanged(attrs), storage.UploadAttrs{
+		ContentType: contentType,
+		Metadata:    map[string]string{EncodingKey: c.env.CompressionEncoding},
+	})
+	pt, err := c.IFile.Upload(ctx, prefix, storageReader{Reader: compressed, Closer: f}, attrs...)
+	if err != nil {
+		return "", err
+	}
+	// the upload is committed, an object missing its size only reports it unknown
+	if err := c.recordSize(ctx, pt, size); err != nil {
+		zapTool.Logger.Warn("compression size", zap.String("path", pt), zap.Error(err))
+	}
+	return pt, nil
+}
+
+// recordSize records the uncompressed size of the object just uploaded at pt,
+// unless another upload replaced it since.
+func (c *Compression) recordSize(ctx context.Context, pt string, size int64) error {
+	file, err := c.IFile.Stat(ctx, pt)
+	if err != nil {
+		return err
+	}
+	if encoding(file) == "" {
+		return fmt.Errorf("%w: %s was replaced", errorhandler.ErrPreconditionFailed, pt)
+	}
+	var conds []storage.Precondition
+	if file.Generation() != 0 {
+		conds = append(conds, storage.IfGenerationMatch(file.Generation()))
+	}
+	_, err = c.IFile.UpdateMetadata(ctx, pt, map[string]string{SizeKey: strconv.FormatInt(size, 10)}, conds...)
+	return err
+}
+
+func (c *Compression) eligible(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = strings.TrimSpace(contentType[:i])
+	}
+	for _, pattern := range c.env.CompressionContentTypes {
+		pattern = strings.ToLower(pattern)
+		if contentType == pattern || (strings.HasSuffix(pattern, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(pattern, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Compression) Stat(ctx context.Context, route string) (storage.File, error) {
+	file, err := c.IFile.Stat(ctx, route)
+	if err != nil {
+		return nil, err
+	}
+	return wrap(file), nil
+}
+
+// UpdateMetadata refuses to clear or overwrite the encoding of a compressed object.
+func (c *Compression) UpdateMetadata(ctx context.Context, route string, metadata map[string]string, conds ...storage.Precondition) (storage.File, error) {
+	for key := range metadata {
+		if reserved(key) {
+			return nil, fmt.Errorf("%w: metadata %s is reserved", errorhandler.ErrInvalidQuery, key)
+		}
+	}
+	if len(metadata) == 0 {
+		file, err := c.IFile.Stat(ctx, route)
+		if err != nil {
+			return nil, err
+		}
+		if encoding(file) != "" {
+			return nil, fmt.Errorf("%w: clearing the metadata of %s drops its encoding", errorhandler.ErrInvalidQuery, route)
+		}
+	}
+	file, err := c.IFile.UpdateMetadata(ctx, route, metadata, conds...)
+	if err != nil {
+		return nil, err
+	}
+	return wrap(file), nil
+}
+
+func (c *Compression) List(ctx context.Context, q storage.Query, h storage.IterHandler) error {
+	return c.IFile.List(ctx, q, handler(h))
+}
+
+func (c *Compression) ListPage(ctx context.Context, q storage.Query, h storage.IterHandler) (string, error) {
+	return c.IFile.ListPage(ctx, q, handler(h))
+}
+
+func (c *Compression) Versions(ctx context.Context, route string, h storage.IterHandler) error {
+	return c.IFile.Versions(ctx, route, handler(h))
+}
+
+func (c *Compression) Download(ctx context.Context, route string, generation int64) (storage.File, io.ReadCloser, error) {
+	file, reader, _, err := c.DownloadEncoded(ctx, route, generation, nil)
+	return file, reader, err
+}
+
+// DownloadEncoded opens the object at route like Download, but serves the stored bytes of
+// an object compressed by one of accepted along with its encoding, empty when decompressed.
+// The stored file is returned with the encoded bytes, its size is the compressed one.
+func (c *Compression) DownloadEncoded(ctx context.Context, route string, generation int64, accepted []string) (storage.File, io.ReadCloser, string, error) {
+	file, reader, err := c.IFile.Download(ctx, route, generation)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	encoding := encoding(file)
+	if encoding == "" {
+		return file, reader, "", nil
+	}
+	for _, item := range accepted {
+		if item == encoding || item == "*" {
+			return file, reader, encoding, nil
+		}
+	}
+	decompressed, err := decompress(reader, encoding)
+	if err != nil {
+		reader.Close()
+		return nil, nil, "", err
+	}
+	return wrap(file), decompressed, "", nil
+}
+
+func handler(h storage.IterHandler) storage.IterHandler {
+	return func(file storage.File) error {
+		return h(wrap(file))
+	}
+}
+
+func wrap(file storage.File) storage.File {
+	if encoding(file) == "" {
+		return file
+	}
+	return compressedFile{File: file}
+}
+
+func encoding(file storage.File) string {
+	return file.Metadata()[EncodingKey]
+}
+
+func reserved(key string) bool {
+	return key == EncodingKey || key == SizeKey
+}
+
+// compressedFile serves the uncompressed size, metadata and content of a compressed object.
+type compressedFile struct {
+	storage.File
+}
+
+func (f compressedFile) Metadata() map[string]string {
+	metadata := map[string]string{}
+	for key, value := range f.File.Metadata() {
+		if !reserved(key) {
+			metadata[key] = value
+		}
+	}
+	return metadata
+}
+
+// Size is -1 when the upload was interrupted before recording the uncompressed size.
+func (f compressedFile) Size() (int64, error) {
+	size, err := strconv.ParseInt(f.File.Metadata()[SizeKey], 10, 64)
+	if err != nil {
+		return -1, nil
+	}
+	return size, nil
+}
+
+func (f compressedFile) NewWriter(ctx context.Context) (io.WriteCloser, func() error) {
+	err := fmt.Errorf("%w: %s is only written by Upload", errorhandler.ErrCompression, f.Path())
+	return failWriter{err}, func() error { return err }
+}
+
+func (f compressedFile) NewReader(ctx context.Context) (io.ReadCloser, func() error, error) {
+	reader, closeFn, err := f.File.NewReader(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	decompressed, err := decompress(io.NopCloser(reader), encoding(f.File))
+	if err != nil {
+		closeFn()
+		return nil, nil, err
+	}
+	return decompressed, func() error {
+		decompressed.Close()
+		return closeFn()
+	}, nil
+}
+
+type failWriter struct {
+	err error
+}
+
+func (w failWriter) Write(p []byte) (int, error) { return 0, w.err }
+
+func (w failWriter) Close() error { return w.err }
+
+type storageReader struct {
+	io.Reader
+	io.Closer
+}
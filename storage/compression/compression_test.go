@@ -0,0 +1,226 @@
+package compression
+
+import (
+	"context"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/justdomepaul/gin-storage/storage/storagetest"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"strings"
+	"testing"
+)
+
+type CompressionSuite struct {
+	suite.Suite
+	ctx     context.Context
+	env     config.Compression
+	content string
+}
+
+func (suite *CompressionSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.env = config.Compression{
+		CompressionEncoding:     EncodingGzip,
+		CompressionContentTypes: []string{"text/*", "application/json"},
+		CompressionMinBytes:     64,
+	}
+	suite.content = strings.Repeat("compressible text line\n", 100)
+}
+
+func (suite *CompressionSuite) read(file storage.File) string {
+	reader, closeFn, err := file.NewReader(suite.ctx)
+	suite.NoError(err)
+	defer closeFn()
+	b, err := io.ReadAll(reader)
+	suite.NoError(err)
+	return string(b)
+}
+
+func (suite *CompressionSuite) TestNewCompression() {
+	_, err := NewCompression(config.Compression{CompressionEncoding: "br"}, storagetest.NewMemIFile())
+	suite.ErrorIs(err, errorhandler.ErrInitialFileClient)
+}
+
+func (suite *CompressionSuite) TestUpload() {
+	testCases := []struct {
+		Label    string
+		Encoding string
+		Attrs    []storage.UploadAttrs
+	}{
+		{
+			Label:    "Upload detected text by gzip",
+			Encoding: EncodingGzip,
+		},
+		{
+			Label:    "Upload json by zstd",
+			Encoding: EncodingZstd,
+			Attrs:    []storage.UploadAttrs{{ContentType: "application/json; charset=utf-8", Metadata: map[string]string{"owner": "a"}}},
+		},
+	}
+	for _, tc := range testCases {
+		file := storagetest.NewMemIFile()
+		env := suite.env
+		env.CompressionEncoding = tc.Encoding
+		compression, err := NewCompression(env, file)
+		suite.NoError(err, tc.Label)
+
+		pt, err := compression.Upload(suite.ctx, "tenant", io.NopCloser(strings.NewReader(suite.content)), tc.Attrs...)
+		suite.NoError(err, tc.Label)
+		stored := file.Object(pt)
+		suite.Less(len(stored.Content()), len(suite.content), tc.Label)
+		suite.Equal(tc.Encoding, stored.Metadata()[EncodingKey], tc.Label)
+		suite.NotEmpty(stored.ContentType(), tc.Label)
+
+		stat, err := compression.Stat(suite.ctx, pt)
+		suite.NoError(err, tc.Label)
+		suite.NotContains(stat.Metadata(), EncodingKey, tc.Label)
+		suite.NotContains(stat.Metadata(), SizeKey, tc.Label)
+		size, err := stat.Size()
+		suite.NoError(err, tc.Label)
+		suite.Equal(int64(len(suite.content)), size, tc.Label)
+		suite.Equal(suite.content, suite.read(stat), tc.Label)
+
+		_, reader, err := compression.Download(suite.ctx, pt, 0)
+		suite.NoError(err, tc.Label)
+		b, err := io.ReadAll(reader)
+		suite.NoError(err, tc.Label)
+		suite.Equal(suite.content, string(b), tc.Label)
+
+		var listed []string
+		suite.NoError(compression.List(suite.ctx, storage.Query{}, func(file storage.File) error {
+			listed = append(listed, suite.read(file))
+			return nil
+		}), tc.Label)
+		suite.Equal([]string{suite.content}, listed, tc.Label)
+	}
+}
+
+func (suite *CompressionSuite) TestUploadSizeNotRecorded() {
+	file := storagetest.NewMemIFile()
+	file.FailMetadata = true
+	compression, err := NewCompression(suite.env, file)
+	suite.NoError(err)
+
+	pt, err := compression.Upload(suite.ctx, "tenant", io.NopCloser(strings.NewReader(suite.content)))
+	suite.NoError(err)
+	stat, err := compression.Stat(suite.ctx, pt)
+	suite.NoError(err)
+	size, err := stat.Size()
+	suite.NoError(err)
+	suite.Equal(int64(-1), size)
+	suite.Equal(suite.content, suite.read(stat))
+}
+
+func (suite *CompressionSuite) TestUploadRaw() {
+	testCases := []struct {
+		Label   string
+		Content string
+		Attrs   []storage.UploadAttrs
+	}{
+		{
+			Label:   "Upload small text",
+			Content: "short",
+		},
+		{
+			Label:   "Upload ineligible type",
+			Content: suite.content,
+			Attrs:   []storage.UploadAttrs{{ContentType: "image/png"}},
+		},
+	}
+	for _, tc := range testCases {
+		file := storagetest.NewMemIFile()
+		compression, err := NewCompression(suite.env, file)
+		suite.NoError(err, tc.Label)
+
+		pt, err := compression.Upload(suite.ctx, "tenant", io.NopCloser(strings.NewReader(tc.Content)), tc.Attrs...)
+		suite.NoError(err, tc.Label)
+		suite.Equal(tc.Content, string(file.Object(pt).Content()), tc.Label)
+		suite.NotContains(file.Object(pt).Metadata(), EncodingKey, tc.Label)
+	}
+}
+
+func (suite *CompressionSuite) TestDownloadEncoded() {
+	file := storagetest.NewMemIFile()
+	compression, err := NewCompression(suite.env, file)
+	suite.NoError(err)
+	pt, err := compression.Upload(suite.ctx, "tenant", io.NopCloser(strings.NewReader(suite.content)))
+	suite.NoError(err)
+
+	testCases := []struct {
+		Label    string
+		Accepted []string
+		Encoding string
+	}{
+		{
+			Label:    "Accept gzip",
+			Accepted: []string{"br", EncodingGzip},
+			Encoding: EncodingGzip,
+		},
+		{
+			Label:    "Accept any",
+			Accepted: []string{"*"},
+			Encoding: EncodingGzip,
+		},
+		{
+			Label:    "Accept identity",
+			Accepted: []string{"identity"},
+		},
+	}
+	for _, tc := range testCases {
+		_, reader, encoding, err := compression.DownloadEncoded(suite.ctx, pt, 0, tc.Accepted)
+		suite.NoError(err, tc.Label)
+		suite.Equal(tc.Encoding, encoding, tc.Label)
+		b, err := io.ReadAll(reader)
+		suite.NoError(err, tc.Label)
+		if tc.Encoding != "" {
+			suite.Equal(file.Object(pt).Content(), b, tc.Label)
+			continue
+		}
+		suite.Equal(suite.content, string(b), tc.Label)
+	}
+}
+
+func (suite *CompressionSuite) TestUpdateMetadata() {
+	file := storagetest.NewMemIFile()
+	compression, err := NewCompression(suite.env, file)
+	suite.NoError(err)
+	pt, err := compression.Upload(suite.ctx, "tenant", io.NopCloser(strings.NewReader(suite.content)))
+	suite.NoError(err)
+
+	testCases := []struct {
+		Label    string
+		Metadata map[string]string
+		Error    error
+	}{
+		{
+			Label:    "Update metadata",
+			Metadata: map[string]string{"owner": "b"},
+		},
+		{
+			Label:    "Overwrite encoding",
+			Metadata: map[string]string{EncodingKey: "identity"},
+			Error:    errorhandler.ErrInvalidQuery,
+		},
+		{
+			Label:    "Clear metadata",
+			Metadata: map[string]string{},
+			Error:    errorhandler.ErrInvalidQuery,
+		},
+	}
+	for _, tc := range testCases {
+		updated, err := compression.UpdateMetadata(suite.ctx, pt, tc.Metadata)
+		if tc.Error != nil {
+			suite.ErrorIs(err, tc.Error, tc.Label)
+			continue
+		}
+		suite.NoError(err, tc.Label)
+		suite.Equal(tc.Metadata, updated.Metadata(), tc.Label)
+		suite.Equal(suite.content, suite.read(updated), tc.Label)
+	}
+}
+
+func TestCompressionSuite(t *testing.T) {
+	suite.Run(t, new(CompressionSuite))
+}
@@ -0,0 +1,22 @@
+package storage
+
+// IWrapper is implemented by IFile decorators which wrap another IFile.
+type IWrapper interface {
+	Unwrap() IFile
+}
+
+// Unwrap walks the decorator chain of f and returns the first layer of type T.
+func Unwrap[T any](f IFile) (T, bool) {
+	for f != nil {
+		if layer, ok := f.(T); ok {
+			return layer, true
+		}
+		wrapper, ok := f.(IWrapper)
+		if !ok {
+			break
+		}
+		f = wrapper.Unwrap()
+	}
+	var empty T
+	return empty, false
+}
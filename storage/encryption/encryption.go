@@ -0,0 +1,307 @@
+package encryption
+
+import (
+	"context"
+	"fmt"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/kelseyhightower/envconfig"
+	"io"
+	"strconv"
+)
+
+// metadata of an encrypted object, hidden from the files served by Encryption
+const (
+	KeyMetadataKey       = "encryption_key"
+	KeyIDMetadataKey     = "encryption_key_id"
+	ChunkSizeMetadataKey = "encryption_chunk_size"
+)
+
+// FromEnv wraps file by the encryption configured from environment, master keys are read
+// from ENCRYPTION_KEYS_PATH when set and from ENCRYPTION_KEYS otherwise.
+func FromEnv(file storage.IFile) (*Encryption, error) {
+	env := config.Encryption{}
+	if err := envconfig.Process("", &env); err != nil {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrInitialFileClient, err.Error())
+	}
+	var (
+		keys IKeyProvider
+		err  error
+	)
+	if env.EncryptionKeysPath != "" {
+		keys, err = NewFileProvider(env.EncryptionKeysPath)
+	} else {
+		keys, err = NewEnvProvider(env)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewEncryption(env, file, keys)
+}
+
+// NewEncryption method
+func NewEncryption(env config.Encryption, file storage.IFile, keys IKeyProvider) (*Encryption, error) {
+	if env.EncryptionChunkSize <= 0 {
+		return nil, fmt.Errorf("%w: invalid encryption chunk size of %d", errorhandler.ErrInitialFileClient, env.EncryptionChunkSize)
+	}
+	return &Encryption{
+		IFile: file,
+		env:   env,
+		keys:  keys,
+	}, nil
+}
+
+// Encryption encrypts uploads by AES-GCM with a data key per object, wrapped by a master
+// key and kept in the object metadata, and decrypts what is read back. Objects without
+// a wrapped key are served as is.
+type Encryption struct {
+	storage.IFile
+	env  config.Encryption
+	keys IKeyProvider
+}
+
+func (e *Encryption) Unwrap() storage.IFile { return e.IFile }
+
+func (e *Encryption) Upload(ctx context.Context, prefix string, f io.ReadCloser, attrs ...storage.UploadAttrs) (string, error) {
+	id, master, err := e.keys.Current(ctx)
+	if err != nil {
+		return "", err
+	}
+	dataKey, err := newDataKey()
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := wrapKey(master, id, dataKey)
+	if err != nil {
+		return "", err
+	}
+	reader, err := newEncryptReader(f, dataKey, e.env.EncryptionChunkSize)
+	if err != nil {
+		return "", err
+	}
+	attrs = append(storage.ContentChanged(attrs), storage.UploadAttrs{Metadata: map[string]string{
+		KeyMetadataKey:       wrapped,
+		KeyIDMetadataKey:     id,
+		ChunkSizeMetadataKey: strconv.Itoa(e.env.EncryptionChunkSize),
+	}})
+	return e.IFile.Upload(ctx, prefix, readCloser{Reader: reader, Closer: f}, attrs...)
+}
+
+func (e *Encryption) Stat(ctx context.Context, route string) (storage.File, error) {
+	file, err := e.IFile.Stat(ctx, route)
+	if err != nil {
+		return nil, err
+	}
+	return e.file(file), nil
+}
+
+// UpdateMetadata refuses to clear or overwrite the wrapped key of an encrypted object.
+func (e *Encryption) UpdateMetadata(ctx context.Context, route string, metadata map[string]string, conds ...storage.Precondition) (storage.File, error) {
+	for key := range metadata {
+		if reserved(key) {
+			return nil, fmt.Errorf("%w: metadata %s is reserved", errorhandler.ErrInvalidQuery, key)
+		}
+	}
+	if len(metadata) == 0 {
+		file, err := e.IFile.Stat(ctx, route)
+		if err != nil {
+			return nil, err
+		}
+		if encrypted(file) {
+			return nil, fmt.Errorf("%w: clearing the metadata of %s drops its encryption key", errorhandler.ErrInvalidQuery, route)
+		}
+	}
+	file, err := e.IFile.UpdateMetadata(ctx, route, metadata, conds...)
+	if err != nil {
+		return nil, err
+	}
+	return e.file(file), nil
+}
+
+func (e *Encryption) List(ctx context.Context, q storage.Query, h storage.IterHandler) error {
+	return e.IFile.List(ctx, q, e.handler(h))
+}
+
+func (e *Encryption) ListPage(ctx context.Context, q storage.Query, h storage.IterHandler) (string, error) {
+	return e.IFile.ListPage(ctx, q, e.handler(h))
+}
+
+func (e *Encryption) Versions(ctx context.Context, route string, h storage.IterHandler) error {
+	return e.IFile.Versions(ctx, route, e.handler(h))
+}
+
+func (e *Encryption) Download(ctx context.Context, route string, generation int64) (storage.File, io.ReadCloser, error) {
+	file, reader, err := e.IFile.Download(ctx, route, generation)
+	if err != nil || !encrypted(file) {
+		return file, reader, err
+	}
+	decrypted, err := e.decrypt(ctx, file, reader)
+	if err != nil {
+		reader.Close()
+		return nil, nil, err
+	}
+	return e.file(file), decrypted, nil
+}
+
+// Rewrap wraps the data key of the object at route by the current master key and
+// reports whether it was wrapped by another one. Only the live version is rewrapped,
+// noncurrent versions keep their key and can't be read once it is retired.
+func (e *Encryption) Rewrap(ctx context.Context, route string) (bool, error) {
+	file, err := e.IFile.Stat(ctx, route)
+	if err != nil {
+		return false, err
+	}
+	id, master, err := e.keys.Current(ctx)
+	if err != nil {
+		return false, err
+	}
+	if !encrypted(file) || file.Metadata()[KeyIDMetadataKey] == id {
+		return false, nil
+	}
+	dataKey, err := e.dataKey(ctx, file)
+	if err != nil {
+		return false, err
+	}
+	wrapped, err := wrapKey(master, id, dataKey)
+	if err != nil {
+		return false, err
+	}
+	if _, err := e.IFile.UpdateMetadata(ctx, route, map[string]string{
+		KeyMetadataKey:   wrapped,
+		KeyIDMetadataKey: id,
+	}, storage.IfGenerationMatch(file.Generation())); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RewrapAll rewraps every live object of q and returns how many were rewrapped,
+// noncurrent versions are left as Rewrap does.
+func (e *Encryption) RewrapAll(ctx context.Context, q storage.Query) (int, error) {
+	var routes []string
+	if err := e.IFile.List(ctx, q, func(file storage.File) error {
+		if _, _, exist := file.FolderInfo(); !exist && encrypted(file) {
+			routes = append(routes, file.Path())
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	rewrapped := make([]bool, len(routes))
+	if err := storage.ForEach(ctx, storage.DefaultConcurrency, len(routes), func(ctx context.Context, i int) error {
+		var err error
+		rewrapped[i], err = e.Rewrap(ctx, routes[i])
+		return err
+	}); err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, ok := range rewrapped {
+		if ok {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (e *Encryption) handler(h storage.IterHandler) storage.IterHandler {
+	return func(file storage.File) error {
+		return h(e.file(file))
+	}
+}
+
+func (e *Encryption) file(file storage.File) storage.File {
+	if !encrypted(file) {
+		return file
+	}
+	return encryptedFile{File: file, e: e}
+}
+
+func (e *Encryption) dataKey(ctx context.Context, file storage.File) ([]byte, error) {
+	id := file.Metadata()[KeyIDMetadataKey]
+	master, err := e.keys.Key(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return unwrapKey(master, id, file.Metadata()[KeyMetadataKey])
+}
+
+func (e *Encryption) decrypt(ctx context.Context, file storage.File, reader io.ReadCloser) (io.ReadCloser, error) {
+	dataKey, err := e.dataKey(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	decrypted, err := newDecryptReader(reader, dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return readCloser{Reader: decrypted, Closer: reader}, nil
+}
+
+func encrypted(file storage.File) bool {
+	return file.Metadata()[KeyMetadataKey] != ""
+}
+
+func reserved(key string) bool {
+	return key == KeyMetadataKey || key == KeyIDMetadataKey || key == ChunkSizeMetadataKey
+}
+
+// encryptedFile serves the plain size, metadata and content of an encrypted object.
+type encryptedFile struct {
+	storage.File
+	e *Encryption
+}
+
+func (f encryptedFile) Metadata() map[string]string {
+	metadata := map[string]string{}
+	for key, value := range f.File.Metadata() {
+		if !reserved(key) {
+			metadata[key] = value
+		}
+	}
+	return metadata
+}
+
+func (f encryptedFile) Size() (int64, error) {
+	size, err := f.File.Size()
+	if err != nil {
+		return 0, err
+	}
+	chunkSize, err := strconv.Atoi(f.File.Metadata()[ChunkSizeMetadataKey])
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid chunk size of %s", errorhandler.ErrDecryption, f.Path())
+	}
+	return PlainSize(size, chunkSize), nil
+}
+
+func (f encryptedFile) NewWriter(ctx context.Context) (io.WriteCloser, func() error) {
+	err := fmt.Errorf("%w: %s is only written by Upload", errorhandler.ErrEncryption, f.Path())
+	return failWriter{err}, func() error { return err }
+}
+
+func (f encryptedFile) NewReader(ctx context.Context) (io.ReadCloser, func() error, error) {
+	reader, closeFn, err := f.File.NewReader(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	decrypted, err := f.e.decrypt(ctx, f.File, reader)
+	if err != nil {
+		closeFn()
+		return nil, nil, err
+	}
+	return decrypted, closeFn, nil
+}
+
+type failWriter struct {
+	err error
+}
+
+func (w failWriter) Write(p []byte) (int, error) { return 0, w.err }
+
+func (w failWriter) Close() error { return w.err }
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,172 @@
+package encryption
+
+import (
+	"context"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/justdomepaul/gin-storage/storage/storagetest"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"strings"
+	"testing"
+)
+
+type EncryptionSuite struct {
+	suite.Suite
+	ctx  context.Context
+	env  config.Encryption
+	keys *StaticProvider
+}
+
+func (suite *EncryptionSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.env = config.Encryption{EncryptionChunkSize: 16}
+	keys, err := NewStaticProvider("k1", map[string]string{"k1": masterKey('a')})
+	suite.NoError(err)
+	suite.keys = keys
+}
+
+func (suite *EncryptionSuite) read(file storage.File) string {
+	reader, closeFn, err := file.NewReader(suite.ctx)
+	suite.NoError(err)
+	defer closeFn()
+	b, err := io.ReadAll(reader)
+	suite.NoError(err)
+	return string(b)
+}
+
+func (suite *EncryptionSuite) newEncryption(file storage.IFile, keys IKeyProvider) *Encryption {
+	encryption, err := NewEncryption(suite.env, file, keys)
+	suite.NoError(err)
+	return encryption
+}
+
+func (suite *EncryptionSuite) TestNewEncryption() {
+	for _, size := range []int{0, -1} {
+		_, err := NewEncryption(config.Encryption{EncryptionChunkSize: size}, storagetest.NewMemIFile(), suite.keys)
+		suite.ErrorIs(err, errorhandler.ErrInitialFileClient, size)
+	}
+}
+
+func (suite *EncryptionSuite) TestUpload() {
+	file := storagetest.NewMemIFile()
+	encryption := suite.newEncryption(file, suite.keys)
+	content := "personal data, stored encrypted"
+
+	pt, err := encryption.Upload(suite.ctx, "tenant", io.NopCloser(strings.NewReader(content)), storage.UploadAttrs{Metadata: map[string]string{"owner": "a"}})
+	suite.NoError(err)
+	stored := file.Object(pt)
+	suite.NotContains(string(stored.Content()), content)
+	suite.Equal("k1", stored.Metadata()[KeyIDMetadataKey])
+	suite.Equal("16", stored.Metadata()[ChunkSizeMetadataKey])
+
+	stat, err := encryption.Stat(suite.ctx, pt)
+	suite.NoError(err)
+	suite.Equal(map[string]string{"owner": "a"}, stat.Metadata())
+	size, err := stat.Size()
+	suite.NoError(err)
+	suite.Equal(int64(len(content)), size)
+	suite.Equal(content, suite.read(stat))
+
+	_, reader, err := encryption.Download(suite.ctx, pt, 0)
+	suite.NoError(err)
+	b, err := io.ReadAll(reader)
+	suite.NoError(err)
+	suite.Equal(content, string(b))
+
+	var listed []string
+	suite.NoError(encryption.List(suite.ctx, storage.Query{}, func(file storage.File) error {
+		listed = append(listed, suite.read(file))
+		return nil
+	}))
+	suite.Equal([]string{content}, listed)
+
+	_, err = suite.newEncryption(file, &StaticProvider{current: "k2", keys: map[string][]byte{}}).Stat(suite.ctx, pt)
+	suite.NoError(err)
+	_, _, err = suite.newEncryption(file, &StaticProvider{current: "k2", keys: map[string][]byte{}}).Download(suite.ctx, pt, 0)
+	suite.ErrorIs(err, errorhandler.ErrEncryptionKey)
+}
+
+func (suite *EncryptionSuite) TestPlainObject() {
+	file := storagetest.NewMemIFile()
+	pt, err := file.Upload(suite.ctx, "tenant", io.NopCloser(strings.NewReader("plain")))
+	suite.NoError(err)
+	encryption := suite.newEncryption(file, suite.keys)
+
+	_, reader, err := encryption.Download(suite.ctx, pt, 0)
+	suite.NoError(err)
+	b, err := io.ReadAll(reader)
+	suite.NoError(err)
+	suite.Equal("plain", string(b))
+	_, err = encryption.UpdateMetadata(suite.ctx, pt, map[string]string{})
+	suite.NoError(err)
+}
+
+func (suite *EncryptionSuite) TestUpdateMetadata() {
+	file := storagetest.NewMemIFile()
+	encryption := suite.newEncryption(file, suite.keys)
+	pt, err := encryption.Upload(suite.ctx, "tenant", io.NopCloser(strings.NewReader("content")))
+	suite.NoError(err)
+
+	testCases := []struct {
+		Label    string
+		Metadata map[string]string
+		Error    error
+	}{
+		{
+			Label:    "Update metadata",
+			Metadata: map[string]string{"owner": "b"},
+		},
+		{
+			Label:    "Overwrite wrapped key",
+			Metadata: map[string]string{KeyMetadataKey: "x"},
+			Error:    errorhandler.ErrInvalidQuery,
+		},
+		{
+			Label:    "Clear metadata",
+			Metadata: map[string]string{},
+			Error:    errorhandler.ErrInvalidQuery,
+		},
+	}
+	for _, tc := range testCases {
+		updated, err := encryption.UpdateMetadata(suite.ctx, pt, tc.Metadata)
+		if tc.Error != nil {
+			suite.ErrorIs(err, tc.Error, tc.Label)
+			continue
+		}
+		suite.NoError(err, tc.Label)
+		suite.Equal(tc.Metadata, updated.Metadata(), tc.Label)
+		suite.Equal("content", suite.read(updated), tc.Label)
+	}
+}
+
+func (suite *EncryptionSuite) TestRewrap() {
+	file := storagetest.NewMemIFile()
+	pt, err := suite.newEncryption(file, suite.keys).Upload(suite.ctx, "tenant", io.NopCloser(strings.NewReader("content")))
+	suite.NoError(err)
+	_, err = file.Upload(suite.ctx, "tenant", io.NopCloser(strings.NewReader("plain")))
+	suite.NoError(err)
+
+	rotated, err := NewStaticProvider("k2", map[string]string{"k1": masterKey('a'), "k2": masterKey('b')})
+	suite.NoError(err)
+	encryption := suite.newEncryption(file, rotated)
+	count, err := encryption.RewrapAll(suite.ctx, storage.Query{})
+	suite.NoError(err)
+	suite.Equal(1, count)
+	suite.Equal("k2", file.Object(pt).Metadata()[KeyIDMetadataKey])
+
+	count, err = encryption.RewrapAll(suite.ctx, storage.Query{})
+	suite.NoError(err)
+	suite.Equal(0, count)
+
+	retired, err := NewStaticProvider("k2", map[string]string{"k2": masterKey('b')})
+	suite.NoError(err)
+	stat, err := suite.newEncryption(file, retired).Stat(suite.ctx, pt)
+	suite.NoError(err)
+	suite.Equal("content", suite.read(stat))
+}
+
+func TestEncryptionSuite(t *testing.T) {
+	suite.Run(t, new(EncryptionSuite))
+}
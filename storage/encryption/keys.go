@@ -0,0 +1,113 @@
+package encryption
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"os"
+)
+
+// IKeyProvider serves the master keys wrapping the data keys of the objects.
+type IKeyProvider interface {
+	// Current returns the id and the master key wrapping new data keys.
+	Current(ctx context.Context) (id string, key []byte, err error)
+	// Key returns the master key of id, an unknown id fails with ErrEncryptionKey.
+	Key(ctx context.Context, id string) ([]byte, error)
+}
+
+// NewStaticProvider serves the base64 master keys by id, current wraps the new data keys.
+func NewStaticProvider(current string, keys map[string]string) (*StaticProvider, error) {
+	provider := &StaticProvider{current: current, keys: map[string][]byte{}}
+	for id, encoded := range keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != keySize {
+			return nil, fmt.Errorf("%w: master key %s is not 32 bytes of base64", errorhandler.ErrInitialFileClient, id)
+		}
+		provider.keys[id] = key
+	}
+	if _, ok := provider.keys[current]; !ok {
+		return nil, fmt.Errorf("%w: current master key %s", errorhandler.ErrEncryptionKey, current)
+	}
+	return provider, nil
+}
+
+// NewEnvProvider serves the master keys of ENCRYPTION_KEYS as id:base64 pairs.
+func NewEnvProvider(env config.Encryption) (*StaticProvider, error) {
+	return NewStaticProvider(env.EncryptionCurrentKey, env.EncryptionKeys)
+}
+
+// NewFileProvider reads the master keys of a JSON file such as {"current": "k2", "keys": {"k1": "<base64>", "k2": "<base64>"}}.
+func NewFileProvider(path string) (*StaticProvider, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrInitialFileClient, err.Error())
+	}
+	file := struct {
+		Current string            `json:"current"`
+		Keys    map[string]string `json:"keys"`
+	}{}
+	if err := json.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrInitialFileClient, err.Error())
+	}
+	return NewStaticProvider(file.Current, file.Keys)
+}
+
+// StaticProvider keeps the master keys in memory, as read from environment or a file.
+type StaticProvider struct {
+	current string
+	keys    map[string][]byte
+}
+
+func (p *StaticProvider) Current(ctx context.Context) (string, []byte, error) {
+	return p.current, p.keys[p.current], nil
+}
+
+func (p *StaticProvider) Key(ctx context.Context, id string) ([]byte, error) {
+	key, ok := p.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrEncryptionKey, id)
+	}
+	return key, nil
+}
+
+// newDataKey generates the random key of an object.
+func newDataKey() ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrEncryption, err.Error())
+	}
+	return key, nil
+}
+
+// wrapKey seals the data key by the master key of id as base64 of the nonce and the sealed key.
+func wrapKey(master []byte, id string, dataKey []byte) (string, error) {
+	aead, err := newAEAD(master)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", errorhandler.ErrEncryption, err.Error())
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("%w: %s", errorhandler.ErrEncryption, err.Error())
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, dataKey, []byte(id))), nil
+}
+
+func unwrapKey(master []byte, id string, wrapped string) ([]byte, error) {
+	aead, err := newAEAD(master)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrDecryption, err.Error())
+	}
+	b, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil || len(b) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: invalid wrapped key", errorhandler.ErrDecryption)
+	}
+	dataKey, err := aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("%w: unwrap by %s: %s", errorhandler.ErrDecryption, id, err.Error())
+	}
+	return dataKey, nil
+}
@@ -0,0 +1,91 @@
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type KeysSuite struct {
+	suite.Suite
+}
+
+func masterKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), keySize)))
+}
+
+func (suite *KeysSuite) TestEnvProvider() {
+	testCases := []struct {
+		Label string
+		Env   config.Encryption
+		Error error
+	}{
+		{
+			Label: "Env keys",
+			Env:   config.Encryption{EncryptionCurrentKey: "k2", EncryptionKeys: map[string]string{"k1": masterKey('a'), "k2": masterKey('b')}},
+		},
+		{
+			Label: "Current key missing",
+			Env:   config.Encryption{EncryptionCurrentKey: "k3", EncryptionKeys: map[string]string{"k1": masterKey('a')}},
+			Error: errorhandler.ErrEncryptionKey,
+		},
+		{
+			Label: "Key too short",
+			Env:   config.Encryption{EncryptionCurrentKey: "k1", EncryptionKeys: map[string]string{"k1": "AAAA"}},
+			Error: errorhandler.ErrInitialFileClient,
+		},
+	}
+	for _, tc := range testCases {
+		provider, err := NewEnvProvider(tc.Env)
+		if tc.Error != nil {
+			suite.ErrorIs(err, tc.Error, tc.Label)
+			continue
+		}
+		suite.NoError(err, tc.Label)
+		id, key, err := provider.Current(context.Background())
+		suite.NoError(err, tc.Label)
+		suite.Equal("k2", id, tc.Label)
+		suite.Equal([]byte(strings.Repeat("b", keySize)), key, tc.Label)
+		_, err = provider.Key(context.Background(), "k3")
+		suite.ErrorIs(err, errorhandler.ErrEncryptionKey, tc.Label)
+	}
+}
+
+func (suite *KeysSuite) TestFileProvider() {
+	path := filepath.Join(suite.T().TempDir(), "keys.json")
+	suite.NoError(os.WriteFile(path, []byte(`{"current": "k1", "keys": {"k1": "`+masterKey('a')+`"}}`), 0600))
+	provider, err := NewFileProvider(path)
+	suite.NoError(err)
+	key, err := provider.Key(context.Background(), "k1")
+	suite.NoError(err)
+	suite.Equal([]byte(strings.Repeat("a", keySize)), key)
+
+	_, err = NewFileProvider(filepath.Join(suite.T().TempDir(), "missing.json"))
+	suite.ErrorIs(err, errorhandler.ErrInitialFileClient)
+}
+
+func (suite *KeysSuite) TestWrapKey() {
+	master := []byte(strings.Repeat("a", keySize))
+	dataKey, err := newDataKey()
+	suite.NoError(err)
+	wrapped, err := wrapKey(master, "k1", dataKey)
+	suite.NoError(err)
+
+	unwrapped, err := unwrapKey(master, "k1", wrapped)
+	suite.NoError(err)
+	suite.Equal(dataKey, unwrapped)
+	_, err = unwrapKey(master, "k2", wrapped)
+	suite.ErrorIs(err, errorhandler.ErrDecryption)
+	_, err = unwrapKey([]byte(strings.Repeat("b", keySize)), "k1", wrapped)
+	suite.ErrorIs(err, errorhandler.ErrDecryption)
+}
+
+func TestKeysSuite(t *testing.T) {
+	suite.Run(t, new(KeysSuite))
+}
@@ -0,0 +1,189 @@
+package encryption
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"io"
+)
+
+// The encrypted content is a header of the magic, the chunk size and a random nonce prefix,
+// followed by the chunks sealed by AES-GCM. The nonce of a chunk is the prefix, its index
+// and a flag set on the last chunk only, so that a truncated or reordered content fails to open.
+const (
+	magic        = "GSE1"
+	prefixSize   = 7
+	headerSize   = len(magic) + 4 + prefixSize
+	tagSize      = 16
+	keySize      = 32
+	lastChunkBit = 1
+)
+
+// PlainSize is the size of the content encrypted into size bytes in chunks of chunkSize.
+func PlainSize(size int64, chunkSize int) int64 {
+	body := size - int64(headerSize)
+	if body < tagSize || chunkSize <= 0 {
+		return 0
+	}
+	chunks := (body + int64(chunkSize+tagSize) - 1) / int64(chunkSize+tagSize)
+	return body - chunks*tagSize
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(prefix []byte, index uint32, last bool) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[prefixSize:], index)
+	if last {
+		nonce[11] = lastChunkBit
+	}
+	return nonce
+}
+
+// encryptReader reads the encrypted content of src.
+type encryptReader struct {
+	src    *bufio.Reader
+	aead   cipher.AEAD
+	prefix []byte
+	chunk  []byte
+	index  uint32
+	out    []byte
+	done   bool
+}
+
+func newEncryptReader(src io.Reader, key []byte, chunkSize int) (*encryptReader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrEncryption, err.Error())
+	}
+	prefix := make([]byte, prefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrEncryption, err.Error())
+	}
+	header := append([]byte(magic), 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(header[len(magic):], uint32(chunkSize))
+	return &encryptReader{
+		src:    bufio.NewReaderSize(src, chunkSize+1),
+		aead:   aead,
+		prefix: prefix,
+		chunk:  make([]byte, chunkSize),
+		out:    append(header, prefix...),
+	}, nil
+}
+
+func (r *encryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.seal(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// seal encrypts the next chunk, peeking the source to tell whether it is the last.
+func (r *encryptReader) seal() error {
+	n, err := io.ReadFull(r.src, r.chunk)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	last := err != nil
+	if !last {
+		if _, err := r.src.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+	r.out = r.aead.Seal(r.out[:0], chunkNonce(r.prefix, r.index, last), r.chunk[:n], nil)
+	r.index++
+	r.done = last
+	return nil
+}
+
+// decryptReader reads the content decrypted from the encrypted src.
+type decryptReader struct {
+	src    *bufio.Reader
+	aead   cipher.AEAD
+	prefix []byte
+	chunk  []byte
+	index  uint32
+	out    []byte
+	done   bool
+}
+
+func newDecryptReader(src io.Reader, key []byte) (*decryptReader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrDecryption, err.Error())
+	}
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrDecryption, err.Error())
+	}
+	if string(header[:len(magic)]) != magic {
+		return nil, fmt.Errorf("%w: unknown format", errorhandler.ErrDecryption)
+	}
+	chunkSize := int(binary.BigEndian.Uint32(header[len(magic):]))
+	if chunkSize <= 0 || chunkSize > 64<<20 {
+		return nil, fmt.Errorf("%w: invalid chunk size %d", errorhandler.ErrDecryption, chunkSize)
+	}
+	return &decryptReader{
+		src:    bufio.NewReaderSize(src, chunkSize+tagSize+1),
+		aead:   aead,
+		prefix: header[len(magic)+4:],
+		chunk:  make([]byte, chunkSize+tagSize),
+	}, nil
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+func (r *decryptReader) open() error {
+	n, err := io.ReadFull(r.src, r.chunk)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	last := err != nil
+	if !last {
+		if _, err := r.src.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+	out, err := r.aead.Open(r.chunk[:0], chunkNonce(r.prefix, r.index, last), r.chunk[:n], nil)
+	if err != nil {
+		return fmt.Errorf("%w: chunk %d: %s", errorhandler.ErrDecryption, r.index, err.Error())
+	}
+	r.out = out
+	r.index++
+	r.done = last
+	return nil
+}
@@ -0,0 +1,94 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"testing"
+)
+
+type StreamSuite struct {
+	suite.Suite
+	key []byte
+}
+
+func (suite *StreamSuite) SetupTest() {
+	suite.key = make([]byte, keySize)
+	_, err := rand.Read(suite.key)
+	suite.NoError(err)
+}
+
+func (suite *StreamSuite) encrypt(plain []byte, chunkSize int) []byte {
+	reader, err := newEncryptReader(bytes.NewReader(plain), suite.key, chunkSize)
+	suite.NoError(err)
+	sealed, err := io.ReadAll(reader)
+	suite.NoError(err)
+	return sealed
+}
+
+func (suite *StreamSuite) TestRoundTrip() {
+	testCases := []struct {
+		Label     string
+		Size      int
+		ChunkSize int
+	}{
+		{Label: "Empty content", Size: 0, ChunkSize: 16},
+		{Label: "Shorter than a chunk", Size: 10, ChunkSize: 16},
+		{Label: "Exact chunks", Size: 64, ChunkSize: 16},
+		{Label: "Partial last chunk", Size: 100, ChunkSize: 16},
+		{Label: "Default chunk size", Size: 200000, ChunkSize: 65536},
+	}
+	for _, tc := range testCases {
+		plain := make([]byte, tc.Size)
+		_, err := rand.Read(plain)
+		suite.NoError(err, tc.Label)
+
+		sealed := suite.encrypt(plain, tc.ChunkSize)
+		suite.Equal(int64(tc.Size), PlainSize(int64(len(sealed)), tc.ChunkSize), tc.Label)
+		if tc.Size > 0 {
+			suite.False(bytes.Contains(sealed, plain), tc.Label)
+		}
+
+		reader, err := newDecryptReader(bytes.NewReader(sealed), suite.key)
+		suite.NoError(err, tc.Label)
+		opened, err := io.ReadAll(reader)
+		suite.NoError(err, tc.Label)
+		suite.Equal(plain, opened, tc.Label)
+	}
+}
+
+func (suite *StreamSuite) TestTampered() {
+	sealed := suite.encrypt(bytes.Repeat([]byte("a"), 100), 16)
+	testCases := []struct {
+		Label   string
+		Content []byte
+	}{
+		{
+			Label:   "Flipped byte",
+			Content: append(append([]byte{}, sealed[:40]...), append([]byte{sealed[40] ^ 1}, sealed[41:]...)...),
+		},
+		{
+			Label:   "Truncated at a chunk",
+			Content: sealed[:headerSize+2*(16+tagSize)],
+		},
+		{
+			Label:   "Chunks reordered",
+			Content: append(append(append([]byte{}, sealed[:headerSize]...), sealed[headerSize+32:headerSize+64]...), append(append([]byte{}, sealed[headerSize:headerSize+32]...), sealed[headerSize+64:]...)...),
+		},
+	}
+	for _, tc := range testCases {
+		reader, err := newDecryptReader(bytes.NewReader(tc.Content), suite.key)
+		suite.NoError(err, tc.Label)
+		_, err = io.ReadAll(reader)
+		suite.ErrorIs(err, errorhandler.ErrDecryption, tc.Label)
+	}
+
+	_, err := newDecryptReader(bytes.NewReader([]byte("plain text content")), suite.key)
+	suite.ErrorIs(err, errorhandler.ErrDecryption)
+}
+
+func TestStreamSuite(t *testing.T) {
+	suite.Run(t, new(StreamSuite))
+}
@@ -0,0 +1,116 @@
+package expiry
+
+import (
+	"context"
+	"fmt"
+	"github.com/cockroachdb/errors"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/kelseyhightower/envconfig"
+	"time"
+)
+
+// FromEnv builds the sweeper of file configured from environment.
+func FromEnv(file storage.IFile) (*Sweeper, error) {
+	media := config.Media{}
+	env := config.Expiry{}
+	for _, item := range []interface{}{&media, &env} {
+		if err := envconfig.Process("", item); err != nil {
+			return nil, fmt.Errorf("%w: %s", errorhandler.ErrInitialFileClient, err.Error())
+		}
+	}
+	return NewSweeper(media, env, file), nil
+}
+
+// NewSweeper method
+func NewSweeper(media config.Media, env config.Expiry, file storage.IFile) *Sweeper {
+	return &Sweeper{
+		file:  file,
+		media: media,
+		env:   env,
+		now:   time.Now,
+	}
+}
+
+// Sweeper removes the objects whose expiry metadata is in the past. Committing
+// an object removes its expiry, an empty one left by older commits never expires either.
+type Sweeper struct {
+	file  storage.IFile
+	media config.Media
+	env   config.Expiry
+	now   func() time.Time
+}
+
+// Metadata is the object metadata expiring at t under key.
+func Metadata(key string, t time.Time) map[string]string {
+	return map[string]string{key: Format(t)}
+}
+
+// Format formats t as the expiry metadata value.
+func Format(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// ExpiresAt returns the expiry of file under key, false when it doesn't expire.
+func ExpiresAt(file storage.File, key string) (time.Time, bool) {
+	value := file.Metadata()[key]
+	if value == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// Sweep removes the expired objects and returns their count. The removes are
+// guarded by the listed generation so an object replaced meanwhile is kept.
+func (s *Sweeper) Sweep(ctx context.Context) (int, error) {
+	query := storage.WithFileMetadata(storage.Query{}, s.env.ExpiryMetadataKey, "")
+	if s.media.PrefixPath != "" {
+		query = storage.WithFileCloudPrefix(query, s.media.PrefixPath)
+	}
+	now := s.now()
+	var expired []storage.File
+	if err := s.file.List(ctx, query, func(file storage.File) error {
+		if _, _, exist := file.FolderInfo(); exist {
+			return nil
+		}
+		if t, ok := ExpiresAt(file, s.env.ExpiryMetadataKey); ok && !t.After(now) {
+			expired = append(expired, file)
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	var count int
+	removed := make([]bool, len(expired))
+	err := storage.ForEach(ctx, storage.DefaultConcurrency, len(expired), func(ctx context.Context, i int) error {
+		var conds []storage.Precondition
+		if generation := expired[i].Generation(); generation > 0 {
+			conds = append(conds, storage.IfGenerationMatch(generation))
+		}
+		err := s.file.Remove(ctx, expired[i].Path(), conds...)
+		if errors.Is(err, errorhandler.ErrRemoveNotExist) || errors.Is(err, errorhandler.ErrPreconditionFailed) {
+			return nil
+		}
+		removed[i] = err == nil
+		return err
+	})
+	for _, ok := range removed {
+		if ok {
+			count++
+		}
+	}
+	return count, err
+}
+
+// Start runs Sweep every ExpirySweepInterval until ctx is done or stop is called.
+func (s *Sweeper) Start(ctx context.Context) (stop func()) {
+	return storage.Every(ctx, s.env.ExpirySweepInterval, "expiry sweeper", func(ctx context.Context) error {
+		_, err := s.Sweep(ctx)
+		return err
+	})
+}
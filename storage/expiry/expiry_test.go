@@ -0,0 +1,122 @@
+package expiry
+
+import (
+	"context"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/justdomepaul/gin-storage/storage/storagetest"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type ExpirySuite struct {
+	suite.Suite
+	ctx context.Context
+	now time.Time
+	env config.Expiry
+}
+
+func (suite *ExpirySuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.now = time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC)
+	suite.env = config.Expiry{ExpiryMetadataKey: "expires_at", ExpirySweepInterval: 10 * time.Millisecond}
+}
+
+func (suite *ExpirySuite) newSweeper(file storage.IFile) *Sweeper {
+	s := NewSweeper(config.Media{PrefixPath: "/media/"}, suite.env, file)
+	s.now = func() time.Time { return suite.now }
+	return s
+}
+
+func (suite *ExpirySuite) TestExpiresAt() {
+	testCases := []struct {
+		Label    string
+		Metadata map[string]string
+		Want     time.Time
+		Expiring bool
+	}{
+		{
+			Label:    "Expiry set",
+			Metadata: Metadata("expires_at", suite.now),
+			Want:     suite.now,
+			Expiring: true,
+		},
+		{
+			Label:    "Committed",
+			Metadata: map[string]string{"expires_at": ""},
+		},
+		{
+			Label: "No metadata",
+		},
+		{
+			Label:    "Malformed expiry",
+			Metadata: map[string]string{"expires_at": "tomorrow"},
+		},
+	}
+	for _, tc := range testCases {
+		t, ok := ExpiresAt(storagetest.File{Meta: tc.Metadata}, "expires_at")
+		suite.Equal(tc.Expiring, ok, tc.Label)
+		suite.True(tc.Want.Equal(t), tc.Label)
+	}
+}
+
+func (suite *ExpirySuite) TestSweep() {
+	file := &storagetest.IFile{}
+	q := storage.WithFileCloudPrefix(storage.WithFileMetadata(storage.Query{}, "expires_at", ""), "/media/")
+	file.On("List", mock.Anything, q, mock.Anything).Return([]storage.File{
+		storagetest.File{Route: "/media/dir/", Folder: true},
+		storagetest.File{Route: "/media/expired", Gen: 3, Meta: Metadata("expires_at", suite.now.Add(-time.Minute))},
+		storagetest.File{Route: "/media/replaced", Gen: 3, Meta: Metadata("expires_at", suite.now.Add(-time.Minute))},
+		storagetest.File{Route: "/media/gone", Gen: 3, Meta: Metadata("expires_at", suite.now)},
+		storagetest.File{Route: "/media/pending", Gen: 3, Meta: Metadata("expires_at", suite.now.Add(time.Minute))},
+		storagetest.File{Route: "/media/committed", Gen: 3, Meta: map[string]string{"owner": "a"}},
+		storagetest.File{Route: "/media/blank", Gen: 3, Meta: map[string]string{"expires_at": ""}},
+	}, nil)
+	conds := []storage.Precondition{storage.IfGenerationMatch(3)}
+	file.On("Remove", mock.Anything, "/media/expired", conds).Return(nil)
+	file.On("Remove", mock.Anything, "/media/replaced", conds).Return(errorhandler.ErrPreconditionFailed)
+	file.On("Remove", mock.Anything, "/media/gone", conds).Return(errorhandler.ErrRemoveNotExist)
+
+	count, err := suite.newSweeper(file).Sweep(suite.ctx)
+	suite.NoError(err)
+	suite.Equal(1, count)
+	file.AssertExpectations(suite.T())
+}
+
+func (suite *ExpirySuite) TestSweepError() {
+	file := &storagetest.IFile{}
+	file.On("List", mock.Anything, mock.Anything, mock.Anything).Return([]storage.File{
+		storagetest.File{Route: "/media/expired", Gen: 3, Meta: Metadata("expires_at", suite.now.Add(-time.Minute))},
+	}, nil)
+	file.On("Remove", mock.Anything, "/media/expired", mock.Anything).Return(errorhandler.ErrFileRemove)
+
+	_, err := suite.newSweeper(file).Sweep(suite.ctx)
+	suite.ErrorIs(err, errorhandler.ErrFileRemove)
+}
+
+func (suite *ExpirySuite) TestStart() {
+	file := &storagetest.IFile{}
+	swept := make(chan struct{}, 1)
+	file.On("List", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			select {
+			case swept <- struct{}{}:
+			default:
+			}
+		}).Return([]storage.File{}, nil)
+
+	stop := suite.newSweeper(file).Start(suite.ctx)
+	defer stop()
+	select {
+	case <-swept:
+	case <-time.After(time.Second):
+		suite.Fail("sweeper not run")
+	}
+}
+
+func TestExpirySuite(t *testing.T) {
+	suite.Run(t, new(ExpirySuite))
+}
@@ -16,6 +16,24 @@ const (
 	FileCloudStartOffset
 	FileCloudEndOffset
 	FileCloudProjection
+	FilePageSize
+	FilePageToken
+	FileNameGlob
+	FileNameRegex
+	FileContentType
+	FileSizeRange
+	FileCreatedRange
+	FileUpdatedRange
+	FileSort
+	FileMetadata
+)
+
+type SortEnumType int
+
+const (
+	SortByName SortEnumType = iota
+	SortBySize
+	SortByTime
 )
 
 type Query struct {
@@ -26,6 +44,20 @@ type Query struct {
 	CloudStartOffset string        // google cloud field
 	CloudEndOffset   string        // google cloud field
 	CloudProjection  gs.Projection // google cloud field
+	PageSize         int
+	PageToken        string
+	NameGlob         string // matched against the whole path, * does not cross /
+	NameRegex        string
+	ContentType      string // exact, or a major type such as image/*
+	MinSize          int64
+	MaxSize          int64 // zero for unbounded
+	CreatedAfter     time.Time
+	CreatedBefore    time.Time
+	UpdatedAfter     time.Time
+	UpdatedBefore    time.Time
+	SortBy           SortEnumType
+	SortDesc         bool
+	Metadata         map[string]string // every key must match, an empty value only requires the key
 }
 
 func WithFileCloudDelimiter(condition Query, delimiter string) Query {
@@ -64,10 +96,94 @@ func WithFileCloudProjection(condition Query, projection gs.Projection) Query {
 	return condition
 }
 
+func WithFilePageSize(condition Query, pageSize int) Query {
+	condition.Fields = append(condition.Fields, FilePageSize)
+	condition.PageSize = pageSize
+	return condition
+}
+
+func WithFilePageToken(condition Query, pageToken string) Query {
+	condition.Fields = append(condition.Fields, FilePageToken)
+	condition.PageToken = pageToken
+	return condition
+}
+
+func WithFileNameGlob(condition Query, pattern string) Query {
+	condition.Fields = append(condition.Fields, FileNameGlob)
+	condition.NameGlob = pattern
+	return condition
+}
+
+func WithFileNameRegex(condition Query, pattern string) Query {
+	condition.Fields = append(condition.Fields, FileNameRegex)
+	condition.NameRegex = pattern
+	return condition
+}
+
+func WithFileContentType(condition Query, contentType string) Query {
+	condition.Fields = append(condition.Fields, FileContentType)
+	condition.ContentType = contentType
+	return condition
+}
+
+func WithFileSizeRange(condition Query, min, max int64) Query {
+	condition.Fields = append(condition.Fields, FileSizeRange)
+	condition.MinSize = min
+	condition.MaxSize = max
+	return condition
+}
+
+func WithFileCreatedRange(condition Query, after, before time.Time) Query {
+	condition.Fields = append(condition.Fields, FileCreatedRange)
+	condition.CreatedAfter = after
+	condition.CreatedBefore = before
+	return condition
+}
+
+func WithFileUpdatedRange(condition Query, after, before time.Time) Query {
+	condition.Fields = append(condition.Fields, FileUpdatedRange)
+	condition.UpdatedAfter = after
+	condition.UpdatedBefore = before
+	return condition
+}
+
+func WithFileSort(condition Query, sortBy SortEnumType, desc bool) Query {
+	condition.Fields = append(condition.Fields, FileSort)
+	condition.SortBy = sortBy
+	condition.SortDesc = desc
+	return condition
+}
+
+func WithFileMetadata(condition Query, key, value string) Query {
+	if !condition.Has(FileMetadata) {
+		condition.Fields = append(condition.Fields, FileMetadata)
+	}
+	metadata := make(map[string]string, len(condition.Metadata)+1)
+	for k, v := range condition.Metadata {
+		metadata[k] = v
+	}
+	metadata[key] = value
+	condition.Metadata = metadata
+	return condition
+}
+
+func (q Query) Has(field FileEnumType) bool {
+	for _, item := range q.Fields {
+		if item == field {
+			return true
+		}
+	}
+	return false
+}
+
 type File interface {
 	FolderInfo() (name string, path string, exist bool)
 	Path() string
 	Name() string
+	ContentType() string
+	// Generation identifies the version of the object, zero when the driver does not keep versions.
+	Generation() int64
+	Metadata() map[string]string
 	Size() (int64, error)
 	CreatedTime() (time.Time, error)
 	ModTime() (time.Time, error)
@@ -79,9 +195,52 @@ type File interface {
 
 type IterHandler func(file File) error
 
+// ProgressFn reports done of total objects processed, it may be called from several goroutines.
+type ProgressFn func(done, total int)
+
 type IFile interface {
-	Upload(ctx context.Context, prefix string, f io.ReadCloser) (string, error)
+	Upload(ctx context.Context, prefix string, f io.ReadCloser, attrs ...UploadAttrs) (string, error)
 	GetURL(ctx context.Context, path string) (string, error)
-	Remove(ctx context.Context, path string) error
+	// Remove removes the object at path, conds guard the removed object and fail with ErrPreconditionFailed.
+	// Removing, moving or overwriting a locked object fails with ErrObjectLocked.
+	Remove(ctx context.Context, path string, conds ...Precondition) error
+	// Copy copies src to dst server side, an existing dst fails with ErrFileExist unless overwrite.
+	// conds guard dst and take over overwrite.
+	Copy(ctx context.Context, src, dst string, overwrite bool, conds ...Precondition) error
+	// Move copies src to dst then removes src, conds guard dst as with Copy. src is removed at the
+	// copied generation, a src changed meanwhile fails with ErrPreconditionFailed and dst is removed again.
+	Move(ctx context.Context, src, dst string, overwrite bool, conds ...Precondition) error
+	// CreateFolder writes the placeholder object of the folder at path and returns the folder prefix.
+	CreateFolder(ctx context.Context, path string) (string, error)
+	// RemoveFolder removes every object under the folder at path, dryRun only counts them.
+	RemoveFolder(ctx context.Context, path string, dryRun bool) (int, error)
+	// RenameFolder moves every object under the folder src to dst and returns how many moved.
+	RenameFolder(ctx context.Context, src, dst string, progress ProgressFn) (int, error)
+	// Stat returns the live object at path.
+	Stat(ctx context.Context, path string) (File, error)
+	// UpdateMetadata merges metadata into the custom metadata of the object at path, an empty value
+	// removes its key and an empty map clears it.
+	UpdateMetadata(ctx context.Context, path string, metadata map[string]string, conds ...Precondition) (File, error)
 	List(ctx context.Context, q Query, h IterHandler) error
+	// Versions iterates every kept generation of the object at path, the live one included.
+	Versions(ctx context.Context, path string, h IterHandler) error
+	// Download opens the object at path, generation zero opens the live version.
+	Download(ctx context.Context, path string, generation int64) (File, io.ReadCloser, error)
+	// RemoveVersion removes a noncurrent generation of the object at path, the live one fails with ErrPreconditionFailed.
+	RemoveVersion(ctx context.Context, path string, generation int64) error
+	// RestoreVersion copies the generation of the object at path over its live version, conds guard the live version.
+	RestoreVersion(ctx context.Context, path string, generation int64, conds ...Precondition) error
+	// ObjectLock returns the holds and retention of the object at path.
+	ObjectLock(ctx context.Context, path string) (ObjectLock, error)
+	// SetHold sets or releases the holds of the object at path.
+	SetHold(ctx context.Context, path string, hold Hold) (ObjectLock, error)
+	// Retention returns the retention policy of the bucket, which sets RetainUntil of every object.
+	Retention(ctx context.Context) (Retention, error)
+	// SetRetention sets the retention period of the bucket, zero removes the policy. Removing or
+	// shortening a locked policy fails with ErrObjectLocked.
+	SetRetention(ctx context.Context, periodSeconds int64) (Retention, error)
+	// ListPage iterates a page of up to PageSize objects matching q and returns the token of the next page,
+	// empty on the last page. A sort other than by name ascending fails with ErrInvalidQuery unless the
+	// whole listing fits in one page.
+	ListPage(ctx context.Context, q Query, h IterHandler) (nextPageToken string, err error)
 }
@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+type matcher func(file File) (bool, error)
+
+var fileFilterFn = map[FileEnumType]func(source Query) (matcher, error){
+	FileNameGlob:     filterNameGlob,
+	FileNameRegex:    filterNameRegex,
+	FileContentType:  filterContentType,
+	FileSizeRange:    filterSizeRange,
+	FileCreatedRange: filterCreatedRange,
+	FileUpdatedRange: filterUpdatedRange,
+	FileMetadata:     filterMetadata,
+}
+
+func filterNameGlob(source Query) (matcher, error) {
+	if _, err := path.Match(source.NameGlob, ""); err != nil {
+		return nil, err
+	}
+	return func(file File) (bool, error) {
+		return path.Match(source.NameGlob, file.Path())
+	}, nil
+}
+
+func filterNameRegex(source Query) (matcher, error) {
+	re, err := regexp.Compile(source.NameRegex)
+	if err != nil {
+		return nil, err
+	}
+	return func(file File) (bool, error) {
+		return re.MatchString(file.Path()), nil
+	}, nil
+}
+
+func filterContentType(source Query) (matcher, error) {
+	want := strings.ToLower(source.ContentType)
+	return func(file File) (bool, error) {
+		contentType := strings.ToLower(file.ContentType())
+		if i := strings.Index(contentType, ";"); i >= 0 {
+			contentType = strings.TrimSpace(contentType[:i])
+		}
+		if strings.HasSuffix(want, "/*") {
+			return strings.HasPrefix(contentType, strings.TrimSuffix(want, "*")), nil
+		}
+		return contentType == want, nil
+	}, nil
+}
+
+func filterSizeRange(source Query) (matcher, error) {
+	if source.MaxSize > 0 && source.MinSize > source.MaxSize {
+		return nil, fmt.Errorf("min size %d greater than max size %d", source.MinSize, source.MaxSize)
+	}
+	return func(file File) (bool, error) {
+		size, err := file.Size()
+		if err != nil {
+			return false, err
+		}
+		return size >= source.MinSize && (source.MaxSize <= 0 || size <= source.MaxSize), nil
+	}, nil
+}
+
+func filterCreatedRange(source Query) (matcher, error) {
+	return timeRange(source.CreatedAfter, source.CreatedBefore, File.CreatedTime)
+}
+
+func filterUpdatedRange(source Query) (matcher, error) {
+	return timeRange(source.UpdatedAfter, source.UpdatedBefore, File.ModTime)
+}
+
+func filterMetadata(source Query) (matcher, error) {
+	return func(file File) (bool, error) {
+		metadata := file.Metadata()
+		for key, want := range source.Metadata {
+			value, ok := metadata[key]
+			if !ok || (want != "" && value != want) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}, nil
+}
+
+// timeRange matches times within [after, before), a zero bound is unbounded.
+func timeRange(after, before time.Time, get func(File) (time.Time, error)) (matcher, error) {
+	if !after.IsZero() && !before.IsZero() && !after.Before(before) {
+		return nil, fmt.Errorf("time range %s not before %s", after, before)
+	}
+	return func(file File) (bool, error) {
+		t, err := get(file)
+		if err != nil {
+			return false, err
+		}
+		return (after.IsZero() || !t.Before(after)) && (before.IsZero() || t.Before(before)), nil
+	}, nil
+}
+
+// Filter wraps h so only the files matching the portable filters of q reach it,
+// folders always pass. Drivers apply it for the filters their backend can't push down.
+func Filter(q Query, h IterHandler) (IterHandler, error) {
+	var matchers []matcher
+	for _, op := range q.Fields {
+		fn, ok := fileFilterFn[op]
+		if !ok {
+			continue
+		}
+		m, err := fn(q)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errorhandler.ErrInvalidQuery, err.Error())
+		}
+		matchers = append(matchers, m)
+	}
+	if len(matchers) == 0 {
+		return h, nil
+	}
+	return func(file File) error {
+		if _, _, exist := file.FolderInfo(); !exist {
+			for _, m := range matchers {
+				ok, err := m(file)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return nil
+				}
+			}
+		}
+		return h(file)
+	}, nil
+}
+
+// Sort buffers the files reaching the returned handler until flush passes them
+// to h in the order of q, folders first. Without a sort field h is returned as is.
+func Sort(q Query, h IterHandler) (handler IterHandler, flush func() error) {
+	if !q.Has(FileSort) {
+		return h, func() error { return nil }
+	}
+	var files []File
+	handler = func(file File) error {
+		files = append(files, file)
+		return nil
+	}
+	flush = func() error {
+		keys := make([]sortKey, len(files))
+		for i, file := range files {
+			key, err := newSortKey(q.SortBy, file)
+			if err != nil {
+				return err
+			}
+			keys[i] = key
+		}
+		sort.Stable(sortFiles{files: files, keys: keys, desc: q.SortDesc})
+		for _, file := range files {
+			if err := h(file); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return handler, flush
+}
+
+type sortFiles struct {
+	files []File
+	keys  []sortKey
+	desc  bool
+}
+
+func (s sortFiles) Len() int { return len(s.files) }
+
+func (s sortFiles) Less(i, j int) bool {
+	if s.keys[i].folder != s.keys[j].folder {
+		return s.keys[i].folder
+	}
+	if s.desc {
+		return s.keys[j].less(s.keys[i])
+	}
+	return s.keys[i].less(s.keys[j])
+}
+
+func (s sortFiles) Swap(i, j int) {
+	s.files[i], s.files[j] = s.files[j], s.files[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
+
+type sortKey struct {
+	folder bool
+	name   string
+	size   int64
+	time   time.Time
+}
+
+func newSortKey(sortBy SortEnumType, file File) (sortKey, error) {
+	if _, folderPath, exist := file.FolderInfo(); exist {
+		return sortKey{folder: true, name: folderPath}, nil
+	}
+	key := sortKey{name: file.Path()}
+	var err error
+	switch sortBy {
+	case SortBySize:
+		key.size, err = file.Size()
+	case SortByTime:
+		key.time, err = file.ModTime()
+	}
+	return key, err
+}
+
+func (k sortKey) less(other sortKey) bool {
+	switch {
+	case k.size != other.size:
+		return k.size < other.size
+	case !k.time.Equal(other.time):
+		return k.time.Before(other.time)
+	}
+	return k.name < other.name
+}
@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type testFile struct {
+	File
+	path        string
+	contentType string
+	size        int64
+	updated     time.Time
+	folder      bool
+	metadata    map[string]string
+}
+
+func (t testFile) FolderInfo() (string, string, bool) {
+	if t.folder {
+		return t.path, t.path, true
+	}
+	return "", "", false
+}
+
+func (t testFile) Path() string { return t.path }
+
+func (t testFile) ContentType() string { return t.contentType }
+
+func (t testFile) Metadata() map[string]string { return t.metadata }
+
+func (t testFile) Size() (int64, error) { return t.size, nil }
+
+func (t testFile) CreatedTime() (time.Time, error) { return t.updated, nil }
+
+func (t testFile) ModTime() (time.Time, error) { return t.updated, nil }
+
+type FilterSuite struct {
+	suite.Suite
+	files []File
+	day   time.Time
+}
+
+func (suite *FilterSuite) SetupTest() {
+	suite.day = time.Date(2022, 3, 10, 0, 0, 0, 0, time.UTC)
+	suite.files = []File{
+		testFile{path: "/media/b.png", contentType: "image/png", size: 30, updated: suite.day.Add(2 * time.Hour), metadata: map[string]string{"owner": "42", "type": "photo"}},
+		testFile{path: "/media/sub/", folder: true},
+		testFile{path: "/media/a.pdf", contentType: "application/pdf", size: 10, updated: suite.day.Add(3 * time.Hour), metadata: map[string]string{"owner": "7"}},
+		testFile{path: "/media/c.jpg", contentType: "image/jpeg; charset=binary", size: 20, updated: suite.day.Add(time.Hour)},
+	}
+}
+
+func (suite *FilterSuite) collect(q Query) ([]string, error) {
+	var paths []string
+	h, flush := Sort(q, func(file File) error {
+		paths = append(paths, file.Path())
+		return nil
+	})
+	h, err := Filter(q, h)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range suite.files {
+		if err := h(file); err != nil {
+			return nil, err
+		}
+	}
+	return paths, flush()
+}
+
+func (suite *FilterSuite) TestFilter() {
+	testCases := []struct {
+		Label string
+		Query Query
+		Want  []string
+	}{
+		{
+			Label: "No filter",
+			Query: Query{},
+			Want:  []string{"/media/b.png", "/media/sub/", "/media/a.pdf", "/media/c.jpg"},
+		},
+		{
+			Label: "Glob",
+			Query: WithFileNameGlob(Query{}, "/media/*.p*"),
+			Want:  []string{"/media/b.png", "/media/sub/", "/media/a.pdf"},
+		},
+		{
+			Label: "Regex",
+			Query: WithFileNameRegex(Query{}, `\.(jpg|png)$`),
+			Want:  []string{"/media/b.png", "/media/sub/", "/media/c.jpg"},
+		},
+		{
+			Label: "Content type major",
+			Query: WithFileContentType(Query{}, "image/*"),
+			Want:  []string{"/media/b.png", "/media/sub/", "/media/c.jpg"},
+		},
+		{
+			Label: "Content type exact",
+			Query: WithFileContentType(Query{}, "image/jpeg"),
+			Want:  []string{"/media/sub/", "/media/c.jpg"},
+		},
+		{
+			Label: "Size range",
+			Query: WithFileSizeRange(Query{}, 15, 25),
+			Want:  []string{"/media/sub/", "/media/c.jpg"},
+		},
+		{
+			Label: "Size minimum",
+			Query: WithFileSizeRange(Query{}, 20, 0),
+			Want:  []string{"/media/b.png", "/media/sub/", "/media/c.jpg"},
+		},
+		{
+			Label: "Updated range",
+			Query: WithFileUpdatedRange(Query{}, suite.day.Add(2*time.Hour), suite.day.Add(3*time.Hour)),
+			Want:  []string{"/media/b.png", "/media/sub/"},
+		},
+		{
+			Label: "Created after",
+			Query: WithFileCreatedRange(Query{}, suite.day.Add(2*time.Hour), time.Time{}),
+			Want:  []string{"/media/b.png", "/media/sub/", "/media/a.pdf"},
+		},
+		{
+			Label: "Metadata value",
+			Query: WithFileMetadata(Query{}, "owner", "42"),
+			Want:  []string{"/media/b.png", "/media/sub/"},
+		},
+		{
+			Label: "Metadata key",
+			Query: WithFileMetadata(Query{}, "owner", ""),
+			Want:  []string{"/media/b.png", "/media/sub/", "/media/a.pdf"},
+		},
+		{
+			Label: "Metadata every key",
+			Query: WithFileMetadata(WithFileMetadata(Query{}, "owner", ""), "type", "photo"),
+			Want:  []string{"/media/b.png", "/media/sub/"},
+		},
+		{
+			Label: "Sort by name",
+			Query: WithFileSort(Query{}, SortByName, false),
+			Want:  []string{"/media/sub/", "/media/a.pdf", "/media/b.png", "/media/c.jpg"},
+		},
+		{
+			Label: "Sort by size descending",
+			Query: WithFileSort(Query{}, SortBySize, true),
+			Want:  []string{"/media/sub/", "/media/b.png", "/media/c.jpg", "/media/a.pdf"},
+		},
+		{
+			Label: "Sort by time and filter",
+			Query: WithFileSort(WithFileContentType(Query{}, "image/*"), SortByTime, false),
+			Want:  []string{"/media/sub/", "/media/c.jpg", "/media/b.png"},
+		},
+	}
+	for _, tc := range testCases {
+		paths, err := suite.collect(tc.Query)
+		suite.NoError(err, tc.Label)
+		suite.Equal(tc.Want, paths, tc.Label)
+	}
+}
+
+func (suite *FilterSuite) TestFilterError() {
+	testCases := []struct {
+		Label string
+		Query Query
+	}{
+		{
+			Label: "Invalid glob",
+			Query: WithFileNameGlob(Query{}, "[a-"),
+		},
+		{
+			Label: "Invalid regex",
+			Query: WithFileNameRegex(Query{}, "(a"),
+		},
+		{
+			Label: "Inverted size range",
+			Query: WithFileSizeRange(Query{}, 20, 10),
+		},
+		{
+			Label: "Inverted time range",
+			Query: WithFileUpdatedRange(Query{}, suite.day, suite.day),
+		},
+	}
+	for _, tc := range testCases {
+		_, err := suite.collect(tc.Query)
+		suite.ErrorIs(err, errorhandler.ErrInvalidQuery, tc.Label)
+	}
+}
+
+func TestFilterSuite(t *testing.T) {
+	suite.Run(t, new(FilterSuite))
+}
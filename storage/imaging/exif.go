@@ -0,0 +1,205 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"strconv"
+)
+
+// metadata keys recorded on uploaded images
+const (
+	WidthKey       = "image_width"
+	HeightKey      = "image_height"
+	FormatKey      = "image_format"
+	OrientationKey = "image_orientation"
+)
+
+// Info describes an image, Width and Height are as displayed once Orientation applies.
+type Info struct {
+	Format      string
+	Width       int
+	Height      int
+	Orientation int
+}
+
+// Inspect reads the format, dimensions and EXIF orientation of a JPEG, PNG or GIF image.
+func Inspect(b []byte) (Info, bool) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return Info{}, false
+	}
+	info := Info{Format: format, Width: cfg.Width, Height: cfg.Height, Orientation: 1}
+	if format == "jpeg" {
+		info.Orientation = jpegOrientation(b)
+	}
+	if info.Orientation >= 5 {
+		info.Width, info.Height = info.Height, info.Width
+	}
+	return info, true
+}
+
+func (i Info) Metadata() map[string]string {
+	return map[string]string{
+		WidthKey:       strconv.Itoa(i.Width),
+		HeightKey:      strconv.Itoa(i.Height),
+		FormatKey:      i.Format,
+		OrientationKey: strconv.Itoa(i.Orientation),
+	}
+}
+
+var (
+	exifHeader = []byte("Exif\x00\x00")
+	// markers dropped by StripJPEG, APP1 carries EXIF and XMP, APP13 carries IPTC
+	strippedMarkers = map[byte]bool{0xE1: true, 0xED: true}
+)
+
+// StripJPEG drops the EXIF, XMP and IPTC segments of a JPEG and writes back an EXIF
+// segment holding only orientation when it is not the default. A malformed JPEG is returned as is.
+func StripJPEG(b []byte, orientation int) []byte {
+	if len(b) < 4 || b[0] != 0xFF || b[1] != 0xD8 {
+		return b
+	}
+	out := bytes.NewBuffer(make([]byte, 0, len(b)))
+	out.Write(b[:2])
+	inserted := orientation <= 1
+	for i := 2; i < len(b); {
+		if b[i] != 0xFF || i+1 >= len(b) {
+			return b
+		}
+		marker := b[i+1]
+		if marker == 0xFF {
+			i++
+			continue
+		}
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			out.Write(b[i : i+2])
+			i += 2
+			continue
+		}
+		if i+4 > len(b) {
+			return b
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(b[i+2:i+4]))
+		if end < i+4 || end > len(b) {
+			return b
+		}
+		if !inserted && marker != 0xE0 {
+			out.Write(orientationSegment(orientation))
+			inserted = true
+		}
+		if marker == 0xDA {
+			// the entropy coded data runs to the end of the image
+			out.Write(b[i:])
+			return out.Bytes()
+		}
+		if !strippedMarkers[marker] {
+			out.Write(b[i:end])
+		}
+		i = end
+	}
+	return out.Bytes()
+}
+
+// orientationSegment is an APP1 segment whose EXIF holds a single orientation tag.
+func orientationSegment(orientation int) []byte {
+	payload := append([]byte{}, exifHeader...)
+	payload = append(payload, 'M', 'M', 0, 42, 0, 0, 0, 8, 0, 1)
+	payload = append(payload, 0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, byte(orientation), 0, 0)
+	payload = append(payload, 0, 0, 0, 0)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// jpegOrientation reads the EXIF orientation of a JPEG, 1 when absent.
+func jpegOrientation(b []byte) int {
+	for i := 2; i+4 <= len(b) && b[i] == 0xFF; {
+		marker := b[i+1]
+		end := i + 2 + int(binary.BigEndian.Uint16(b[i+2:i+4]))
+		// a segment length counts its own two bytes
+		if marker == 0xDA || end < i+4 || end > len(b) {
+			break
+		}
+		if marker == 0xE1 && bytes.HasPrefix(b[i+4:end], exifHeader) {
+			if o := tiffOrientation(b[i+4+len(exifHeader) : end]); o > 0 {
+				return o
+			}
+		}
+		i = end
+	}
+	return 1
+}
+
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[ifd : ifd+2]))
+	for n := 0; n < count; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			if o := int(order.Uint16(tiff[entry+8 : entry+10])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// Orient turns img upright from its EXIF orientation.
+func Orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	dstW, dstH := w, h
+	if orientation >= 5 {
+		dstW, dstH = h, w
+	}
+	src := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(x, y):src.PixOffset(x, y)+4])
+		}
+	}
+	return dst
+}
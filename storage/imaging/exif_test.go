@@ -0,0 +1,178 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/justdomepaul/gin-storage/storage/storagetest"
+	"github.com/stretchr/testify/suite"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"testing"
+)
+
+type ExifSuite struct {
+	suite.Suite
+}
+
+// jpeg encodes a width x height JPEG carrying an EXIF orientation, a GPS secret and an XMP packet.
+func (suite *ExifSuite) jpeg(width, height, orientation int) []byte {
+	var buf bytes.Buffer
+	suite.NoError(jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height)), nil))
+	b := buf.Bytes()
+
+	tiff := []byte{'I', 'I', 42, 0, 8, 0, 0, 0, 2, 0}
+	tiff = append(tiff, 0x12, 0x01, 3, 0, 1, 0, 0, 0, byte(orientation), 0, 0, 0)
+	tiff = append(tiff, 0x25, 0x88, 4, 0, 1, 0, 0, 0, 38, 0, 0, 0)
+	tiff = append(tiff, 0, 0, 0, 0)
+	tiff = append(tiff, []byte("GPS-SECRET")...)
+	exif := append(append([]byte{}, exifHeader...), tiff...)
+	xmp := append([]byte("http://ns.adobe.com/xap/1.0/\x00"), []byte("<x:xmpmeta>XMP-SECRET</x:xmpmeta>")...)
+
+	out := append([]byte{}, b[:2]...)
+	for _, payload := range [][]byte{exif, xmp} {
+		segment := []byte{0xFF, 0xE1, 0, 0}
+		binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+		out = append(out, append(segment, payload...)...)
+	}
+	return append(out, b[2:]...)
+}
+
+func (suite *ExifSuite) TestInspect() {
+	testCases := []struct {
+		Label   string
+		Content []byte
+		Info    Info
+		Ok      bool
+	}{
+		{
+			Label:   "Inspect jpeg",
+			Content: suite.jpeg(40, 20, 1),
+			Info:    Info{Format: "jpeg", Width: 40, Height: 20, Orientation: 1},
+			Ok:      true,
+		},
+		{
+			Label:   "Inspect rotated jpeg",
+			Content: suite.jpeg(40, 20, 6),
+			Info:    Info{Format: "jpeg", Width: 20, Height: 40, Orientation: 6},
+			Ok:      true,
+		},
+		{
+			Label:   "Inspect text",
+			Content: []byte("plain text"),
+		},
+	}
+	for _, tc := range testCases {
+		info, ok := Inspect(tc.Content)
+		suite.Equal(tc.Ok, ok, tc.Label)
+		suite.Equal(tc.Info, info, tc.Label)
+	}
+}
+
+// shortSegment inserts an APP1 segment declaring a length too short for itself after the frame header
+// of a JFIF, where image.DecodeConfig no longer reads.
+func (suite *ExifSuite) shortSegment() []byte {
+	jfif := []byte{0xFF, 0xE0, 0, 16, 'J', 'F', 'I', 'F', 0, 1, 1, 0, 0, 1, 0, 1, 0, 0}
+	var buf bytes.Buffer
+	suite.NoError(jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 40, 20)), nil))
+	b := buf.Bytes()
+	b = append(append(append([]byte{}, b[:2]...), jfif...), b[2:]...)
+	end := 2
+	for b[end+1] != 0xC0 {
+		end += 2 + int(binary.BigEndian.Uint16(b[end+2:end+4]))
+	}
+	end += 2 + int(binary.BigEndian.Uint16(b[end+2:end+4]))
+	return append(append(append([]byte{}, b[:end]...), 0xFF, 0xE1, 0, 0), b[end:]...)
+}
+
+func (suite *ExifSuite) TestInspectShortSegment() {
+	b := suite.shortSegment()
+	info, ok := Inspect(b)
+	suite.True(ok)
+	suite.Equal(Info{Format: "jpeg", Width: 40, Height: 20, Orientation: 1}, info)
+	suite.Equal(b, StripJPEG(b, 1))
+}
+
+func (suite *ExifSuite) TestStripJPEG() {
+	testCases := []struct {
+		Label       string
+		Orientation int
+	}{
+		{
+			Label:       "Strip default orientation",
+			Orientation: 1,
+		},
+		{
+			Label:       "Strip keeps orientation",
+			Orientation: 6,
+		},
+	}
+	for _, tc := range testCases {
+		b := StripJPEG(suite.jpeg(40, 20, tc.Orientation), tc.Orientation)
+		suite.NotContains(string(b), "GPS-SECRET", tc.Label)
+		suite.NotContains(string(b), "XMP-SECRET", tc.Label)
+		suite.Equal(tc.Orientation, jpegOrientation(b), tc.Label)
+		_, err := jpeg.Decode(bytes.NewReader(b))
+		suite.NoError(err, tc.Label)
+	}
+	suite.Equal([]byte("plain text"), StripJPEG([]byte("plain text"), 6))
+}
+
+func (suite *ExifSuite) TestOrient() {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	img.Set(1, 0, color.RGBA{B: 255, A: 255})
+
+	oriented := Orient(img, 6)
+	suite.Equal(image.Rect(0, 0, 1, 2), oriented.Bounds())
+	suite.Equal(color.RGBA{R: 255, A: 255}, oriented.At(0, 0))
+	suite.Equal(color.RGBA{B: 255, A: 255}, oriented.At(0, 1))
+
+	oriented = Orient(img, 3)
+	suite.Equal(color.RGBA{B: 255, A: 255}, oriented.At(0, 0))
+	suite.Equal(img, Orient(img, 1))
+}
+
+func (suite *ExifSuite) TestUpload() {
+	testCases := []struct {
+		Label    string
+		Strip    bool
+		Metadata map[string]string
+		Secret   bool
+	}{
+		{
+			Label:    "Upload strips metadata",
+			Strip:    true,
+			Metadata: map[string]string{"owner": "a", WidthKey: "20", HeightKey: "40", FormatKey: "jpeg", OrientationKey: "6"},
+		},
+		{
+			Label:    "Upload keeps metadata",
+			Metadata: map[string]string{"owner": "a", WidthKey: "20", HeightKey: "40", FormatKey: "jpeg", OrientationKey: "6"},
+			Secret:   true,
+		},
+	}
+	for _, tc := range testCases {
+		file := storagetest.NewMemIFile()
+		env := config.Image{ImageWidths: []int{10}, ImageMaxPixels: 1000000, ImageMaxBytes: 1 << 20, ImageQuality: 80, ImageStripMetadata: tc.Strip}
+		pt, err := NewImaging(config.Media{PrefixPath: "/media/"}, env, file).Upload(context.Background(), "tenant", io.NopCloser(bytes.NewReader(suite.jpeg(40, 20, 6))), storage.UploadAttrs{Metadata: map[string]string{"owner": "a", WidthKey: "1"}})
+		suite.NoError(err, tc.Label)
+		object, err := file.Stat(context.Background(), pt)
+		suite.NoError(err, tc.Label)
+		suite.Equal(tc.Metadata, object.Metadata(), tc.Label)
+		suite.Equal(tc.Secret, bytes.Contains(object.(storagetest.MemFile).Content(), []byte("GPS-SECRET")), tc.Label)
+		derivative, err := file.Stat(context.Background(), pt+"@w10")
+		suite.NoError(err, tc.Label)
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(derivative.(storagetest.MemFile).Content()))
+		suite.NoError(err, tc.Label)
+		suite.Equal(10, cfg.Width, tc.Label)
+		suite.Equal(20, cfg.Height, tc.Label)
+	}
+}
+
+func TestExifSuite(t *testing.T) {
+	suite.Run(t, new(ExifSuite))
+}
@@ -0,0 +1,244 @@
+package imaging
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/cockroachdb/errors"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	zapTool "github.com/justdomepaul/toolbox/zap"
+	"github.com/kelseyhightower/envconfig"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// SourceGenerationKey is the metadata of a derivative naming the generation of its original.
+const SourceGenerationKey = "source_generation"
+
+// sniffSize is what http.DetectContentType considers.
+const sniffSize = 512
+
+var imageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// FromEnv wraps file by the image pipeline configured from environment.
+func FromEnv(file storage.IFile) (*Imaging, error) {
+	media := config.Media{}
+	env := config.Image{}
+	for _, item := range []interface{}{&media, &env} {
+		if err := envconfig.Process("", item); err != nil {
+			return nil, fmt.Errorf("%w: %s", errorhandler.ErrInitialFileClient, err.Error())
+		}
+	}
+	return NewImaging(media, env, file), nil
+}
+
+// NewImaging method
+func NewImaging(media config.Media, env config.Image, file storage.IFile) *Imaging {
+	return &Imaging{
+		IFile: file,
+		media: media,
+		env:   env,
+	}
+}
+
+// Imaging stores resized derivatives of the uploaded images next to their original,
+// at the original path followed by the suffix of the resize.
+type Imaging struct {
+	storage.IFile
+	media config.Media
+	env   config.Image
+}
+
+func (i *Imaging) Unwrap() storage.IFile { return i.IFile }
+
+// Upload records the dimensions, format and orientation of JPEG, PNG and GIF uploads as metadata,
+// strips the EXIF, XMP and IPTC of JPEGs and derives the configured widths. Other uploads
+// and images over the configured size are stored as is.
+func (i *Imaging) Upload(ctx context.Context, prefix string, f io.ReadCloser, attrs ...storage.UploadAttrs) (string, error) {
+	src := bufio.NewReaderSize(f, sniffSize)
+	head, err := src.Peek(sniffSize)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("%w: %s", errorhandler.ErrFileUpload, err.Error())
+	}
+	// only the formats decoded here are buffered
+	if !imageTypes[http.DetectContentType(head)] {
+		return i.IFile.Upload(ctx, prefix, readCloser{Reader: src, Closer: f}, attrs...)
+	}
+	b, err := io.ReadAll(io.LimitReader(src, i.env.ImageMaxBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", errorhandler.ErrFileUpload, err.Error())
+	}
+	if int64(len(b)) > i.env.ImageMaxBytes {
+		return i.IFile.Upload(ctx, prefix, readCloser{Reader: io.MultiReader(bytes.NewReader(b), src), Closer: f}, attrs...)
+	}
+	info, ok := Inspect(b)
+	if !ok {
+		return i.IFile.Upload(ctx, prefix, readCloser{Reader: bytes.NewReader(b), Closer: f}, attrs...)
+	}
+	if info.Format == "jpeg" && i.env.ImageStripMetadata {
+		b = StripJPEG(b, info.Orientation)
+		attrs = storage.ContentChanged(attrs)
+	}
+	attrs = append(attrs, storage.UploadAttrs{Metadata: info.Metadata()})
+	pt, err := i.IFile.Upload(ctx, prefix, readCloser{Reader: bytes.NewReader(b), Closer: f}, attrs...)
+	if err != nil || len(i.env.ImageWidths) == 0 {
+		return pt, err
+	}
+	// the original is committed, derivatives are best effort and resized on demand by Image
+	if err := i.derive(ctx, pt, b); err != nil {
+		zapTool.Logger.Warn("image derivatives", zap.String("path", pt), zap.Error(err))
+	}
+	return pt, nil
+}
+
+// derive stores the configured widths of the image b uploaded at pt.
+func (i *Imaging) derive(ctx context.Context, pt string, b []byte) error {
+	img, format, err := Decode(b, i.env)
+	if err != nil {
+		return err
+	}
+	source, err := i.IFile.Stat(ctx, pt)
+	if err != nil {
+		return err
+	}
+	for _, width := range i.env.ImageWidths {
+		if width <= 0 || width >= img.Bounds().Dx() {
+			continue
+		}
+		b, err := Encode(Scale(img, Resize{Width: width}), format, i.env)
+		if err != nil {
+			return err
+		}
+		if _, err := i.store(ctx, source, Resize{Width: width}, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remove removes the derivatives of the object at route along with it, the objects named
+// by a resize suffix and recording the generation of their original.
+func (i *Imaging) Remove(ctx context.Context, route string, conds ...storage.Precondition) error {
+	if err := i.IFile.Remove(ctx, route, conds...); err != nil {
+		return err
+	}
+	return i.removeDerivatives(ctx, route+"@", func(pt string) string { return strings.TrimPrefix(pt, route) })
+}
+
+// Move removes the derivatives of the object at src once it is moved, dst is resized again
+// on demand.
+func (i *Imaging) Move(ctx context.Context, src, dst string, overwrite bool, conds ...storage.Precondition) error {
+	if err := i.IFile.Move(ctx, src, dst, overwrite, conds...); err != nil {
+		return err
+	}
+	return i.removeDerivatives(ctx, src+"@", func(pt string) string { return strings.TrimPrefix(pt, src) })
+}
+
+// RenameFolder removes the derivatives under src before renaming it, so only the originals
+// are moved and resized again under dst on demand.
+func (i *Imaging) RenameFolder(ctx context.Context, src, dst string, progress storage.ProgressFn) (int, error) {
+	if err := i.removeDerivatives(ctx, strings.TrimSuffix(src, "/")+"/", func(pt string) string {
+		if n := strings.LastIndex(pt, "@"); n >= 0 {
+			return pt[n:]
+		}
+		return ""
+	}); err != nil {
+		return 0, err
+	}
+	return i.IFile.RenameFolder(ctx, src, dst, progress)
+}
+
+// removeDerivatives removes the derivatives listed under prefix, suffix returning what
+// follows the name of their original.
+func (i *Imaging) removeDerivatives(ctx context.Context, prefix string, suffix func(pt string) string) error {
+	var derivatives []string
+	if err := i.IFile.List(ctx, storage.WithFileCloudPrefix(storage.Query{}, prefix), func(file storage.File) error {
+		// user objects named like the original followed by @ are kept
+		if _, ok := file.Metadata()[SourceGenerationKey]; ok && IsSuffix(suffix(file.Path())) {
+			derivatives = append(derivatives, file.Path())
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return storage.ForEach(ctx, storage.DefaultConcurrency, len(derivatives), func(ctx context.Context, n int) error {
+		if err := i.IFile.Remove(ctx, derivatives[n]); err != nil && !errors.Is(err, errorhandler.ErrRemoveNotExist) {
+			return err
+		}
+		return nil
+	})
+}
+
+// Image opens the derivative of the object at route resized to r clamped to the configured
+// max dimension, resizing the original and caching the derivative when it is missing or
+// older than the original. Only the configured sizes are resized to when they are set.
+func (i *Imaging) Image(ctx context.Context, route string, r Resize) (storage.File, io.ReadCloser, error) {
+	r = r.Clamp(i.env.ImageMaxDimension)
+	if err := r.Validate(i.env.ImageSizes); err != nil {
+		return nil, nil, err
+	}
+	source, err := i.IFile.Stat(ctx, route)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, reader, err := i.IFile.Download(ctx, route+r.Suffix(), 0)
+	if err == nil {
+		if file.Metadata()[SourceGenerationKey] == strconv.FormatInt(source.Generation(), 10) {
+			return file, reader, nil
+		}
+		reader.Close()
+	} else if !errors.Is(err, errorhandler.ErrFileNotExist) {
+		return nil, nil, err
+	}
+
+	_, reader, err = i.IFile.Download(ctx, route, source.Generation())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer reader.Close()
+	b, err := io.ReadAll(io.LimitReader(reader, i.env.ImageMaxBytes+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", errorhandler.ErrGetFile, err.Error())
+	}
+	if int64(len(b)) > i.env.ImageMaxBytes {
+		return nil, nil, fmt.Errorf("%w: larger than %d bytes", errorhandler.ErrImageProcess, i.env.ImageMaxBytes)
+	}
+	img, format, err := Decode(b, i.env)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err = Encode(Scale(img, r), format, i.env)
+	if err != nil {
+		return nil, nil, err
+	}
+	pt, err := i.store(ctx, source, r, b)
+	if err != nil {
+		return nil, nil, err
+	}
+	return i.IFile.Download(ctx, pt, 0)
+}
+
+// store uploads the derivative of source next to it and returns its path.
+func (i *Imaging) store(ctx context.Context, source storage.File, r Resize, b []byte) (string, error) {
+	relative := strings.TrimPrefix(source.Path(), i.media.PrefixPath)
+	return i.IFile.Upload(ctx, path.Dir(relative), io.NopCloser(bytes.NewReader(b)), storage.UploadAttrs{
+		Name:     path.Base(source.Path()) + r.Suffix(),
+		Metadata: map[string]string{SourceGenerationKey: strconv.FormatInt(source.Generation(), 10)},
+	})
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
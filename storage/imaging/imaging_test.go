@@ -0,0 +1,335 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage/storagetest"
+	"github.com/stretchr/testify/suite"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"testing"
+)
+
+type ImagingSuite struct {
+	suite.Suite
+	ctx context.Context
+	env config.Image
+}
+
+func (suite *ImagingSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.env = config.Image{ImageWidths: []int{40, 400}, ImageMaxDimension: 1000, ImageMaxPixels: 1000000, ImageMaxBytes: 1 << 20, ImageQuality: 80}
+}
+
+func (suite *ImagingSuite) png(width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	suite.NoError(png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func (suite *ImagingSuite) size(b []byte) (int, int) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(b))
+	suite.NoError(err)
+	return cfg.Width, cfg.Height
+}
+
+func (suite *ImagingSuite) TestUpload() {
+	file := storagetest.NewMemIFile()
+	imaging := NewImaging(config.Media{PrefixPath: "/media/"}, suite.env, file)
+
+	pt, err := imaging.Upload(suite.ctx, "tenant", io.NopCloser(bytes.NewReader(suite.png(200, 100))))
+	suite.NoError(err)
+	suite.Equal("/media/tenant/uuid1", pt)
+	suite.Equal([]string{"/media/tenant/uuid1", "/media/tenant/uuid1@w40"}, file.Paths())
+	derivative, err := file.Stat(suite.ctx, "/media/tenant/uuid1@w40")
+	suite.NoError(err)
+	suite.Equal(map[string]string{SourceGenerationKey: "1"}, derivative.Metadata())
+	w, h := suite.size(derivative.(storagetest.MemFile).Content())
+	suite.Equal(40, w)
+	suite.Equal(20, h)
+
+	file.Put("/media/tenant/uuid1@backup", []byte("user object"), nil)
+	file.Put("/media/tenant/uuid1@w80", []byte("user object"), nil)
+	suite.NoError(imaging.Remove(suite.ctx, pt))
+	suite.Equal([]string{"/media/tenant/uuid1@backup", "/media/tenant/uuid1@w80"}, file.Paths())
+}
+
+func (suite *ImagingSuite) TestIsSuffix() {
+	testCases := []struct {
+		Label  string
+		Suffix string
+		Want   bool
+	}{
+		{Label: "Width", Suffix: Resize{Width: 40}.Suffix(), Want: true},
+		{Label: "Height", Suffix: Resize{Height: 40}.Suffix(), Want: true},
+		{Label: "Box", Suffix: Resize{Width: 40, Height: 20}.Suffix(), Want: true},
+		{Label: "Box with fit", Suffix: Resize{Width: 40, Height: 20, Fit: FitCover}.Suffix(), Want: true},
+		{Label: "Fit without box", Suffix: "@w40-cover"},
+		{Label: "User name", Suffix: "@backup"},
+		{Label: "Nested", Suffix: "@w40/a"},
+		{Label: "Zero width", Suffix: "@w0"},
+	}
+	for _, tc := range testCases {
+		suite.Equal(tc.Want, IsSuffix(tc.Suffix), tc.Label)
+	}
+}
+
+func (suite *ImagingSuite) TestUploadWithoutPrefixPath() {
+	file := storagetest.NewMemIFile()
+	file.Root = ""
+	imaging := NewImaging(config.Media{}, suite.env, file)
+
+	pt, err := imaging.Upload(suite.ctx, "tenant", io.NopCloser(bytes.NewReader(suite.png(200, 100))))
+	suite.NoError(err)
+	suite.Equal("tenant/uuid1", pt)
+	suite.Equal([]string{"tenant/uuid1", "tenant/uuid1@w40"}, file.Paths())
+	cached, _, err := imaging.Image(suite.ctx, pt, Resize{Width: 40})
+	suite.NoError(err)
+	suite.Equal(int64(2), cached.Generation())
+
+	suite.NoError(imaging.Remove(suite.ctx, pt))
+	suite.Empty(file.Paths())
+}
+
+func (suite *ImagingSuite) TestUploadDerivativeError() {
+	file := storagetest.NewMemIFile()
+	file.FailSuffix = "@w40"
+	imaging := NewImaging(config.Media{PrefixPath: "/media/"}, suite.env, file)
+
+	pt, err := imaging.Upload(suite.ctx, "tenant", io.NopCloser(bytes.NewReader(suite.png(200, 100))))
+	suite.NoError(err)
+	suite.Equal("/media/tenant/uuid1", pt)
+	suite.Equal([]string{"/media/tenant/uuid1"}, file.Paths())
+}
+
+func (suite *ImagingSuite) TestUploadNotImage() {
+	testCases := []struct {
+		Label   string
+		Env     config.Image
+		Content []byte
+	}{
+		{
+			Label:   "Upload text",
+			Env:     suite.env,
+			Content: []byte("plain text"),
+		},
+		{
+			Label:   "Upload text over size",
+			Env:     config.Image{ImageWidths: []int{40}, ImageMaxBytes: 10},
+			Content: bytes.Repeat([]byte("plain text "), 100),
+		},
+		{
+			Label:   "Upload image over size",
+			Env:     config.Image{ImageWidths: []int{40}, ImageMaxBytes: 10},
+			Content: suite.png(200, 100),
+		},
+		{
+			Label:   "Upload image over pixels",
+			Env:     config.Image{ImageWidths: []int{40}, ImageMaxBytes: 1 << 20, ImageMaxPixels: 100},
+			Content: suite.png(200, 100),
+		},
+	}
+	for _, tc := range testCases {
+		file := storagetest.NewMemIFile()
+		_, err := NewImaging(config.Media{PrefixPath: "/media/"}, tc.Env, file).Upload(suite.ctx, "tenant", io.NopCloser(bytes.NewReader(tc.Content)))
+		suite.NoError(err, tc.Label)
+		suite.Equal([]string{"/media/tenant/uuid1"}, file.Paths(), tc.Label)
+		object, err := file.Stat(suite.ctx, "/media/tenant/uuid1")
+		suite.NoError(err, tc.Label)
+		suite.Equal(tc.Content, object.(storagetest.MemFile).Content(), tc.Label)
+	}
+}
+
+func (suite *ImagingSuite) TestImage() {
+	testCases := []struct {
+		Label  string
+		Resize Resize
+		Suffix string
+		Width  int
+		Height int
+		Error  error
+	}{
+		{
+			Label:  "Resize width",
+			Resize: Resize{Width: 50},
+			Suffix: "@w50",
+			Width:  50,
+			Height: 25,
+		},
+		{
+			Label:  "Contain in box",
+			Resize: Resize{Width: 50, Height: 50},
+			Suffix: "@w50h50",
+			Width:  50,
+			Height: 25,
+		},
+		{
+			Label:  "Cover box",
+			Resize: Resize{Width: 50, Height: 50, Fit: FitCover},
+			Suffix: "@w50h50-cover",
+			Width:  50,
+			Height: 50,
+		},
+		{
+			Label:  "Fill box",
+			Resize: Resize{Width: 50, Height: 50, Fit: FitFill},
+			Suffix: "@w50h50-fill",
+			Width:  50,
+			Height: 50,
+		},
+		{
+			Label:  "Never upscale",
+			Resize: Resize{Height: 500},
+			Suffix: "@h500",
+			Width:  200,
+			Height: 100,
+		},
+		{
+			Label:  "Without dimension",
+			Resize: Resize{},
+			Error:  errorhandler.ErrInvalidQuery,
+		},
+		{
+			Label:  "Clamp to max dimension",
+			Resize: Resize{Width: 2000, Height: 50},
+			Suffix: "@w1000h50",
+			Width:  100,
+			Height: 50,
+		},
+		{
+			Label:  "Unknown fit",
+			Resize: Resize{Width: 50, Height: 50, Fit: "stretch"},
+			Error:  errorhandler.ErrInvalidQuery,
+		},
+	}
+	for _, tc := range testCases {
+		file := storagetest.NewMemIFile()
+		file.Put("/media/tenant/uuid1", suite.png(200, 100), nil)
+		imaging := NewImaging(config.Media{PrefixPath: "/media/"}, suite.env, file)
+
+		derivative, reader, err := imaging.Image(suite.ctx, "/media/tenant/uuid1", tc.Resize)
+		if tc.Error != nil {
+			suite.ErrorIs(err, tc.Error, tc.Label)
+			continue
+		}
+		suite.NoError(err, tc.Label)
+		b, err := io.ReadAll(reader)
+		suite.NoError(err, tc.Label)
+		suite.Equal("/media/tenant/uuid1"+tc.Suffix, derivative.Path(), tc.Label)
+		w, h := suite.size(b)
+		suite.Equal(tc.Width, w, tc.Label)
+		suite.Equal(tc.Height, h, tc.Label)
+	}
+}
+
+func (suite *ImagingSuite) TestImageSizes() {
+	env := suite.env
+	env.ImageSizes = []int{50, 1000}
+	testCases := []struct {
+		Label  string
+		Resize Resize
+		Suffix string
+		Error  error
+	}{
+		{
+			Label:  "Allowed size",
+			Resize: Resize{Width: 50},
+			Suffix: "@w50",
+		},
+		{
+			Label:  "Allowed once clamped",
+			Resize: Resize{Width: 5000},
+			Suffix: "@w1000",
+		},
+		{
+			Label:  "Size not allowed",
+			Resize: Resize{Width: 50, Height: 60},
+			Error:  errorhandler.ErrInvalidQuery,
+		},
+	}
+	for _, tc := range testCases {
+		file := storagetest.NewMemIFile()
+		file.Put("/media/tenant/uuid1", suite.png(200, 100), nil)
+
+		derivative, _, err := NewImaging(config.Media{PrefixPath: "/media/"}, env, file).Image(suite.ctx, "/media/tenant/uuid1", tc.Resize)
+		if tc.Error != nil {
+			suite.ErrorIs(err, tc.Error, tc.Label)
+			continue
+		}
+		suite.NoError(err, tc.Label)
+		suite.Equal("/media/tenant/uuid1"+tc.Suffix, derivative.Path(), tc.Label)
+	}
+}
+
+func (suite *ImagingSuite) TestMove() {
+	file := storagetest.NewMemIFile()
+	imaging := NewImaging(config.Media{PrefixPath: "/media/"}, suite.env, file)
+	pt, err := imaging.Upload(suite.ctx, "tenant", io.NopCloser(bytes.NewReader(suite.png(200, 100))))
+	suite.NoError(err)
+	file.Put(pt+"@backup", []byte("user object"), nil)
+	suite.Equal([]string{"/media/tenant/uuid1", "/media/tenant/uuid1@backup", "/media/tenant/uuid1@w40"}, file.Paths())
+
+	suite.NoError(imaging.Move(suite.ctx, pt, "/media/tenant/moved", false))
+	suite.Equal([]string{"/media/tenant/moved", "/media/tenant/uuid1@backup"}, file.Paths())
+	suite.ErrorIs(imaging.Move(suite.ctx, pt, "/media/tenant/moved", false), errorhandler.ErrFileNotExist)
+}
+
+func (suite *ImagingSuite) TestRenameFolder() {
+	file := storagetest.NewMemIFile()
+	imaging := NewImaging(config.Media{PrefixPath: "/media/"}, suite.env, file)
+	_, err := imaging.Upload(suite.ctx, "tenant/folder", io.NopCloser(bytes.NewReader(suite.png(200, 100))))
+	suite.NoError(err)
+	file.Put("/media/tenant/folder/sub/b@w40", []byte("user object"), nil)
+
+	count, err := imaging.RenameFolder(suite.ctx, "/media/tenant/folder", "/media/tenant/renamed", nil)
+	suite.NoError(err)
+	suite.Equal(2, count)
+	suite.Equal([]string{"/media/tenant/renamed/sub/b@w40", "/media/tenant/renamed/uuid1"}, file.Paths())
+}
+
+func (suite *ImagingSuite) TestImageCache() {
+	file := storagetest.NewMemIFile()
+	file.Put("/media/tenant/uuid1", suite.png(200, 100), nil)
+	imaging := NewImaging(config.Media{PrefixPath: "/media/"}, suite.env, file)
+
+	first, _, err := imaging.Image(suite.ctx, "/media/tenant/uuid1", Resize{Width: 50})
+	suite.NoError(err)
+	cached, _, err := imaging.Image(suite.ctx, "/media/tenant/uuid1", Resize{Width: 50})
+	suite.NoError(err)
+	suite.Equal(first.Generation(), cached.Generation())
+
+	file.Put("/media/tenant/uuid1", suite.png(100, 100), nil)
+	refreshed, reader, err := imaging.Image(suite.ctx, "/media/tenant/uuid1", Resize{Width: 50})
+	suite.NoError(err)
+	suite.NotEqual(first.Generation(), refreshed.Generation())
+	b, err := io.ReadAll(reader)
+	suite.NoError(err)
+	w, h := suite.size(b)
+	suite.Equal(50, w)
+	suite.Equal(50, h)
+}
+
+func (suite *ImagingSuite) TestImageError() {
+	file := storagetest.NewMemIFile()
+	file.Put("/media/tenant/text", []byte("plain text"), nil)
+	imaging := NewImaging(config.Media{PrefixPath: "/media/"}, suite.env, file)
+
+	_, _, err := imaging.Image(suite.ctx, "/media/tenant/text", Resize{Width: 50})
+	suite.ErrorIs(err, errorhandler.ErrImageFormat)
+	_, _, err = imaging.Image(suite.ctx, "/media/tenant/none", Resize{Width: 50})
+	suite.ErrorIs(err, errorhandler.ErrFileNotExist)
+}
+
+func TestImagingSuite(t *testing.T) {
+	suite.Run(t, new(ImagingSuite))
+}
@@ -0,0 +1,174 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"golang.org/x/image/draw"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+type FitType string
+
+const (
+	// FitContain scales the image to fit within the box, keeping its aspect ratio.
+	FitContain FitType = "contain"
+	// FitCover scales the image to cover the box and crops the overflow from the center.
+	FitCover FitType = "cover"
+	// FitFill stretches the image to the box.
+	FitFill FitType = "fill"
+)
+
+// Resize is the box a derivative is resized to, a zero dimension follows the aspect ratio.
+type Resize struct {
+	Width  int
+	Height int
+	Fit    FitType
+}
+
+// Clamp limits the width and height of r to maxDimension, unless it is 0.
+func (r Resize) Clamp(maxDimension int) Resize {
+	if maxDimension > 0 && r.Width > maxDimension {
+		r.Width = maxDimension
+	}
+	if maxDimension > 0 && r.Height > maxDimension {
+		r.Height = maxDimension
+	}
+	return r
+}
+
+// Validate checks r has a dimension, one of sizes when given, and a known fit.
+func (r Resize) Validate(sizes []int) error {
+	if r.Width < 0 || r.Height < 0 || (r.Width == 0 && r.Height == 0) {
+		return fmt.Errorf("%w: width or height required", errorhandler.ErrInvalidQuery)
+	}
+	for _, dimension := range []int{r.Width, r.Height} {
+		if dimension > 0 && len(sizes) > 0 && !allowed(sizes, dimension) {
+			return fmt.Errorf("%w: size %d not one of %v", errorhandler.ErrInvalidQuery, dimension, sizes)
+		}
+	}
+	switch r.Fit {
+	case "", FitContain, FitCover, FitFill:
+	default:
+		return fmt.Errorf("%w: unknown fit %s", errorhandler.ErrInvalidQuery, r.Fit)
+	}
+	return nil
+}
+
+func allowed(sizes []int, dimension int) bool {
+	for _, size := range sizes {
+		if size == dimension {
+			return true
+		}
+	}
+	return false
+}
+
+// Suffix names the derivative of r next to its original, as @w320, @h200 or @w320h200-cover.
+func (r Resize) Suffix() string {
+	suffix := "@"
+	if r.Width > 0 {
+		suffix += "w" + strconv.Itoa(r.Width)
+	}
+	if r.Height > 0 {
+		suffix += "h" + strconv.Itoa(r.Height)
+	}
+	if r.Width > 0 && r.Height > 0 && r.Fit != "" && r.Fit != FitContain {
+		suffix += "-" + string(r.Fit)
+	}
+	return suffix
+}
+
+var derivativeSuffix = regexp.MustCompile(`^@(w[1-9][0-9]*|h[1-9][0-9]*|w[1-9][0-9]*h[1-9][0-9]*(-(cover|fill))?)$`)
+
+// IsSuffix reports whether suffix is one Suffix produces.
+func IsSuffix(suffix string) bool {
+	return derivativeSuffix.MatchString(suffix)
+}
+
+// Decode decodes a JPEG, PNG or GIF image turned upright from its EXIF orientation,
+// refusing images over the configured pixel count.
+func Decode(b []byte, env config.Image) (image.Image, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %s", errorhandler.ErrImageFormat, err.Error())
+	}
+	if env.ImageMaxPixels > 0 && cfg.Width*cfg.Height > env.ImageMaxPixels {
+		return nil, "", fmt.Errorf("%w: %dx%d larger than %d pixels", errorhandler.ErrImageProcess, cfg.Width, cfg.Height, env.ImageMaxPixels)
+	}
+	img, format, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %s", errorhandler.ErrImageProcess, err.Error())
+	}
+	if format == "jpeg" {
+		img = Orient(img, jpegOrientation(b))
+	}
+	return img, format, nil
+}
+
+// Encode encodes img in format, JPEG at the configured quality.
+func Encode(img image.Image, format string, env config.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: env.ImageQuality})
+	case "png":
+		err = png.Encode(&buf, img)
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	default:
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrImageFormat, format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrImageProcess, err.Error())
+	}
+	return buf.Bytes(), nil
+}
+
+// Scale resizes img into the box of r, never upscaling it.
+func Scale(img image.Image, r Resize) image.Image {
+	bounds := img.Bounds()
+	w, h := float64(bounds.Dx()), float64(bounds.Dy())
+	scaleX, scaleY := math.Inf(1), math.Inf(1)
+	if r.Width > 0 {
+		scaleX = float64(r.Width) / w
+	}
+	if r.Height > 0 {
+		scaleY = float64(r.Height) / h
+	}
+	src := bounds
+	var dstW, dstH float64
+	switch {
+	case r.Width > 0 && r.Height > 0 && r.Fit == FitFill:
+		dstW, dstH = math.Min(float64(r.Width), w), math.Min(float64(r.Height), h)
+	case r.Width > 0 && r.Height > 0 && r.Fit == FitCover:
+		scale := math.Min(math.Max(scaleX, scaleY), 1)
+		dstW, dstH = math.Min(float64(r.Width), w*scale), math.Min(float64(r.Height), h*scale)
+		// crop the source to the aspect ratio of the box, centered
+		cropW, cropH := int(math.Round(dstW/scale)), int(math.Round(dstH/scale))
+		x0 := bounds.Min.X + (bounds.Dx()-cropW)/2
+		y0 := bounds.Min.Y + (bounds.Dy()-cropH)/2
+		src = image.Rect(x0, y0, x0+cropW, y0+cropH)
+	default:
+		scale := math.Min(math.Min(scaleX, scaleY), 1)
+		dstW, dstH = w*scale, h*scale
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, max1(dstW), max1(dstH)))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, src, draw.Src, nil)
+	return dst
+}
+
+func max1(v float64) int {
+	if n := int(math.Round(v)); n > 1 {
+		return n
+	}
+	return 1
+}
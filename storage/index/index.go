@@ -0,0 +1,247 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	zapTool "github.com/justdomepaul/toolbox/zap"
+	"github.com/kelseyhightower/envconfig"
+	"go.uber.org/zap"
+	"io"
+	"strings"
+)
+
+// FromEnv wraps file by the search index configured from environment, records are
+// kept in a BoltDB file when INDEX_PATH is set and in memory otherwise.
+func FromEnv(file storage.IFile) (*Index, error) {
+	media := config.Media{}
+	env := config.Index{}
+	for _, item := range []interface{}{&media, &env} {
+		if err := envconfig.Process("", item); err != nil {
+			return nil, fmt.Errorf("%w: %s", errorhandler.ErrInitialFileClient, err.Error())
+		}
+	}
+	var store IIndexStore = NewMemoryStore()
+	if env.IndexPath != "" {
+		boltStore, err := NewBoltStore(env.IndexPath)
+		if err != nil {
+			return nil, err
+		}
+		store = boltStore
+	}
+	return NewIndex(media, file, store), nil
+}
+
+// NewIndex method
+func NewIndex(media config.Media, file storage.IFile, store IIndexStore) *Index {
+	return &Index{
+		IFile: file,
+		media: media,
+		store: store,
+	}
+}
+
+// Index keeps a searchable record of every object written through the wrapped IFile.
+// A failed index update is logged and never fails a committed storage change, Rebuild fixes the drift.
+type Index struct {
+	storage.IFile
+	media config.Media
+	store IIndexStore
+}
+
+var errPageFull = errors.New("page full")
+
+// putBatch is how many records a listing writes to the store at once.
+const putBatch = 500
+
+func (i *Index) Unwrap() storage.IFile { return i.IFile }
+
+func (i *Index) Upload(ctx context.Context, prefix string, f io.ReadCloser, attrs ...storage.UploadAttrs) (string, error) {
+	pt, err := i.IFile.Upload(ctx, prefix, f, attrs...)
+	if err != nil {
+		return "", err
+	}
+	i.warn(pt, i.refresh(ctx, pt))
+	return pt, nil
+}
+
+func (i *Index) Remove(ctx context.Context, route string, conds ...storage.Precondition) error {
+	if err := i.IFile.Remove(ctx, route, conds...); err != nil {
+		return err
+	}
+	i.warn(route, i.store.Delete(ctx, route))
+	return nil
+}
+
+func (i *Index) Copy(ctx context.Context, src, dst string, overwrite bool, conds ...storage.Precondition) error {
+	if err := i.IFile.Copy(ctx, src, dst, overwrite, conds...); err != nil {
+		return err
+	}
+	i.warn(dst, i.refresh(ctx, dst))
+	return nil
+}
+
+func (i *Index) Move(ctx context.Context, src, dst string, overwrite bool, conds ...storage.Precondition) error {
+	if err := i.IFile.Move(ctx, src, dst, overwrite, conds...); err != nil {
+		return err
+	}
+	i.warn(src, i.store.Delete(ctx, src))
+	i.warn(dst, i.refresh(ctx, dst))
+	return nil
+}
+
+func (i *Index) RemoveFolder(ctx context.Context, route string, dryRun bool) (int, error) {
+	count, err := i.IFile.RemoveFolder(ctx, route, dryRun)
+	if err != nil || dryRun {
+		return count, err
+	}
+	i.warn(route, i.store.DeletePrefix(ctx, folderPrefix(route)))
+	return count, nil
+}
+
+// RenameFolder drops the records under src and indexes what dst holds once renamed,
+// a partial rename keeps the records of the objects it moved.
+func (i *Index) RenameFolder(ctx context.Context, src, dst string, progress storage.ProgressFn) (int, error) {
+	count, err := i.IFile.RenameFolder(ctx, src, dst, progress)
+	if count == 0 && err != nil {
+		return count, err
+	}
+	i.warn(src, i.store.DeletePrefix(ctx, folderPrefix(src)))
+	_, errPut := i.put(ctx, storage.WithFileCloudPrefix(storage.Query{}, folderPrefix(dst)))
+	i.warn(dst, errPut)
+	return count, err
+}
+
+func (i *Index) UpdateMetadata(ctx context.Context, route string, metadata map[string]string, conds ...storage.Precondition) (storage.File, error) {
+	file, err := i.IFile.UpdateMetadata(ctx, route, metadata, conds...)
+	if err != nil {
+		return nil, err
+	}
+	i.warn(route, i.index(ctx, file))
+	return file, nil
+}
+
+func (i *Index) RestoreVersion(ctx context.Context, route string, generation int64, conds ...storage.Precondition) error {
+	if err := i.IFile.RestoreVersion(ctx, route, generation, conds...); err != nil {
+		return err
+	}
+	i.warn(route, i.refresh(ctx, route))
+	return nil
+}
+
+// Search iterates a page of the records matching the prefix and portable filters of q
+// in path order and returns the token of the next page. As with ListPage, a sort other
+// than by name ascending fails with ErrInvalidQuery unless the whole result fits in one page.
+func (i *Index) Search(ctx context.Context, q storage.Query, h storage.IterHandler) (string, error) {
+	sorts := q.Has(storage.FileSort) && (q.SortBy != storage.SortByName || q.SortDesc)
+	if sorts && q.Has(storage.FilePageToken) {
+		return "", fmt.Errorf("%w: sort needs the whole result in one page", errorhandler.ErrInvalidQuery)
+	}
+	prefix := i.media.PrefixPath
+	if q.Has(storage.FileCloudPrefix) {
+		prefix = q.CloudPrefix
+	}
+	after := ""
+	if q.Has(storage.FilePageToken) {
+		after = q.PageToken
+	}
+	sorted, flush := storage.Sort(q, h)
+	var (
+		count int
+		last  string
+		next  string
+	)
+	filtered, err := storage.Filter(q, func(file storage.File) error {
+		if q.PageSize > 0 && count == q.PageSize {
+			next = last
+			return errPageFull
+		}
+		count++
+		last = file.Path()
+		return sorted(file)
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := i.store.Scan(ctx, prefix, after, func(record Record) error {
+		return filtered(record.File())
+	}); err != nil && !errors.Is(err, errPageFull) {
+		return "", err
+	}
+	if sorts && next != "" {
+		return "", fmt.Errorf("%w: sort needs the whole result in one page", errorhandler.ErrInvalidQuery)
+	}
+	return next, flush()
+}
+
+// Rebuild replaces every record by the objects listed by the wrapped IFile and returns how many it indexed.
+func (i *Index) Rebuild(ctx context.Context) (int, error) {
+	if err := i.store.Reset(ctx); err != nil {
+		return 0, err
+	}
+	query := storage.Query{}
+	if i.media.PrefixPath != "" {
+		query = storage.WithFileCloudPrefix(query, i.media.PrefixPath)
+	}
+	return i.put(ctx, query)
+}
+
+func (i *Index) Close() error { return i.store.Close() }
+
+// refresh indexes the live object at route.
+func (i *Index) refresh(ctx context.Context, route string) error {
+	file, err := i.IFile.Stat(ctx, route)
+	if err != nil {
+		return err
+	}
+	return i.index(ctx, file)
+}
+
+func (i *Index) index(ctx context.Context, file storage.File) error {
+	record, ok, err := NewRecord(file)
+	if err != nil || !ok {
+		return err
+	}
+	return i.store.Put(ctx, record)
+}
+
+// warn logs the index update of route failed after its storage change committed.
+func (i *Index) warn(route string, err error) {
+	if err != nil {
+		zapTool.Logger.Warn("index", zap.String("path", route), zap.Error(err))
+	}
+}
+
+// put indexes the objects listed by q in batches of putBatch records and returns how many it indexed.
+func (i *Index) put(ctx context.Context, q storage.Query) (int, error) {
+	var (
+		count int
+		batch []Record
+	)
+	if err := i.IFile.List(ctx, q, func(file storage.File) error {
+		record, ok, err := NewRecord(file)
+		if err != nil || !ok {
+			return err
+		}
+		if batch = append(batch, record); len(batch) < putBatch {
+			return nil
+		}
+		count += len(batch)
+		err = i.store.Put(ctx, batch...)
+		batch = batch[:0]
+		return err
+	}); err != nil {
+		return count, err
+	}
+	if len(batch) == 0 {
+		return count, nil
+	}
+	return count + len(batch), i.store.Put(ctx, batch...)
+}
+
+func folderPrefix(route string) string {
+	return strings.TrimSuffix(route, "/") + "/"
+}
@@ -0,0 +1,267 @@
+package index
+
+import (
+	"context"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/justdomepaul/gin-storage/storage/storagetest"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"strings"
+	"testing"
+)
+
+type IndexSuite struct {
+	suite.Suite
+	ctx   context.Context
+	media config.Media
+}
+
+func (suite *IndexSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.media = config.Media{PrefixPath: "/media/"}
+}
+
+func (suite *IndexSuite) paths(store IIndexStore) []string {
+	var paths []string
+	suite.NoError(store.Scan(suite.ctx, "", "", func(record Record) error {
+		paths = append(paths, record.Path)
+		return nil
+	}))
+	return paths
+}
+
+func (suite *IndexSuite) TestUpload() {
+	store := NewMemoryStore()
+	file := &storagetest.IFile{}
+	file.On("Upload", mock.Anything, "tenant", mock.Anything).Return("/media/tenant/uuid", nil)
+	file.On("Stat", mock.Anything, "/media/tenant/uuid").Return(storagetest.File{Route: "/media/tenant/uuid", Bytes: 5}, nil)
+
+	pt, err := NewIndex(suite.media, file, store).Upload(suite.ctx, "tenant", io.NopCloser(strings.NewReader("12345")))
+	suite.NoError(err)
+	suite.Equal("/media/tenant/uuid", pt)
+	suite.Equal([]string{"/media/tenant/uuid"}, suite.paths(store))
+}
+
+func (suite *IndexSuite) TestUploadError() {
+	store := NewMemoryStore()
+	file := &storagetest.IFile{}
+	file.On("Upload", mock.Anything, "tenant", mock.Anything).Return("", errorhandler.ErrFileUpload)
+
+	_, err := NewIndex(suite.media, file, store).Upload(suite.ctx, "tenant", io.NopCloser(strings.NewReader("12345")))
+	suite.ErrorIs(err, errorhandler.ErrFileUpload)
+	suite.Empty(suite.paths(store))
+}
+
+func (suite *IndexSuite) TestUploadNotIndexed() {
+	store := NewMemoryStore()
+	file := &storagetest.IFile{}
+	file.On("Upload", mock.Anything, "tenant", mock.Anything).Return("/media/tenant/uuid", nil)
+	file.On("Stat", mock.Anything, "/media/tenant/uuid").Return(nil, errorhandler.ErrGetFile)
+
+	pt, err := NewIndex(suite.media, file, store).Upload(suite.ctx, "tenant", io.NopCloser(strings.NewReader("12345")))
+	suite.NoError(err)
+	suite.Equal("/media/tenant/uuid", pt)
+	suite.Empty(suite.paths(store))
+}
+
+func (suite *IndexSuite) TestRemove() {
+	store := NewMemoryStore()
+	suite.NoError(store.Put(suite.ctx, Record{Path: "/media/a"}, Record{Path: "/media/b"}))
+	file := &storagetest.IFile{}
+	file.On("Remove", mock.Anything, "/media/a").Return(nil)
+	file.On("Remove", mock.Anything, "/media/b").Return(errorhandler.ErrFileRemove)
+
+	index := NewIndex(suite.media, file, store)
+	suite.NoError(index.Remove(suite.ctx, "/media/a"))
+	suite.ErrorIs(index.Remove(suite.ctx, "/media/b"), errorhandler.ErrFileRemove)
+	suite.Equal([]string{"/media/b"}, suite.paths(store))
+}
+
+func (suite *IndexSuite) TestTransfer() {
+	testCases := []struct {
+		Label  string
+		Method string
+		Want   []string
+	}{
+		{
+			Label:  "Copy indexes dst",
+			Method: "Copy",
+			Want:   []string{"/media/a", "/media/b"},
+		},
+		{
+			Label:  "Move indexes dst and drops src",
+			Method: "Move",
+			Want:   []string{"/media/b"},
+		},
+	}
+	for _, tc := range testCases {
+		store := NewMemoryStore()
+		suite.NoError(store.Put(suite.ctx, Record{Path: "/media/a"}))
+		file := &storagetest.IFile{}
+		file.On(tc.Method, mock.Anything, "/media/a", "/media/b", false).Return(nil)
+		file.On("Stat", mock.Anything, "/media/b").Return(storagetest.File{Route: "/media/b"}, nil)
+
+		index := NewIndex(suite.media, file, store)
+		var err error
+		if tc.Method == "Copy" {
+			err = index.Copy(suite.ctx, "/media/a", "/media/b", false)
+		} else {
+			err = index.Move(suite.ctx, "/media/a", "/media/b", false)
+		}
+		suite.NoError(err, tc.Label)
+		suite.Equal(tc.Want, suite.paths(store), tc.Label)
+	}
+}
+
+func (suite *IndexSuite) TestFolder() {
+	store := NewMemoryStore()
+	suite.NoError(store.Put(suite.ctx, Record{Path: "/media/a/1"}, Record{Path: "/media/ab"}, Record{Path: "/media/c/1"}))
+	file := &storagetest.IFile{}
+	file.On("RemoveFolder", mock.Anything, "/media/c", mock.Anything).Return(1, nil)
+	file.On("RenameFolder", mock.Anything, "/media/a", "/media/d", mock.Anything).Return(1, nil)
+	file.On("List", mock.Anything, storage.WithFileCloudPrefix(storage.Query{}, "/media/d/"), mock.Anything).
+		Return([]storage.File{storagetest.File{Route: "/media/d/", Folder: true}, storagetest.File{Route: "/media/d/1"}}, nil)
+
+	index := NewIndex(suite.media, file, store)
+	count, err := index.RemoveFolder(suite.ctx, "/media/c", true)
+	suite.NoError(err)
+	suite.Equal(1, count)
+	suite.Equal([]string{"/media/a/1", "/media/ab", "/media/c/1"}, suite.paths(store))
+
+	_, err = index.RemoveFolder(suite.ctx, "/media/c", false)
+	suite.NoError(err)
+	_, err = index.RenameFolder(suite.ctx, "/media/a", "/media/d", nil)
+	suite.NoError(err)
+	suite.Equal([]string{"/media/ab", "/media/d/1"}, suite.paths(store))
+}
+
+func (suite *IndexSuite) TestUpdateMetadata() {
+	store := NewMemoryStore()
+	metadata := map[string]string{"owner": "alice"}
+	file := &storagetest.IFile{}
+	file.On("UpdateMetadata", mock.Anything, "/media/a", metadata).Return(storagetest.File{Route: "/media/a", Meta: metadata}, nil)
+
+	_, err := NewIndex(suite.media, file, store).UpdateMetadata(suite.ctx, "/media/a", metadata)
+	suite.NoError(err)
+	suite.NoError(store.Scan(suite.ctx, "", "", func(record Record) error {
+		suite.Equal(metadata, record.Metadata)
+		return nil
+	}))
+}
+
+func (suite *IndexSuite) TestSearch() {
+	store := NewMemoryStore()
+	suite.NoError(store.Put(suite.ctx,
+		Record{Path: "/media/a.png", ContentType: "image/png", Size: 30},
+		Record{Path: "/media/b.txt", ContentType: "text/plain", Size: 10},
+		Record{Path: "/media/c.png", ContentType: "image/png", Size: 20, Metadata: map[string]string{"owner": "alice"}},
+		Record{Path: "/media/d.png", ContentType: "image/png", Size: 10},
+		Record{Path: "/other/e.png", ContentType: "image/png"},
+	))
+	testCases := []struct {
+		Label string
+		Query storage.Query
+		Want  []string
+		Next  string
+		Error error
+	}{
+		{
+			Label: "Search under media prefix",
+			Query: storage.Query{},
+			Want:  []string{"/media/a.png", "/media/b.txt", "/media/c.png", "/media/d.png"},
+		},
+		{
+			Label: "Search content type",
+			Query: storage.WithFileContentType(storage.Query{}, "image/*"),
+			Want:  []string{"/media/a.png", "/media/c.png", "/media/d.png"},
+		},
+		{
+			Label: "Search metadata",
+			Query: storage.WithFileMetadata(storage.Query{}, "owner", "alice"),
+			Want:  []string{"/media/c.png"},
+		},
+		{
+			Label: "Search first page",
+			Query: storage.WithFilePageSize(storage.WithFileContentType(storage.Query{}, "image/png"), 2),
+			Want:  []string{"/media/a.png", "/media/c.png"},
+			Next:  "/media/c.png",
+		},
+		{
+			Label: "Search last page",
+			Query: storage.WithFilePageToken(storage.WithFilePageSize(storage.WithFileContentType(storage.Query{}, "image/png"), 2), "/media/c.png"),
+			Want:  []string{"/media/d.png"},
+		},
+		{
+			Label: "Search sorted by size",
+			Query: storage.WithFileSort(storage.WithFileSizeRange(storage.Query{}, 15, 0), storage.SortBySize, false),
+			Want:  []string{"/media/c.png", "/media/a.png"},
+		},
+		{
+			Label: "Search sorted after page token",
+			Query: storage.WithFilePageToken(storage.WithFileSort(storage.Query{}, storage.SortBySize, false), "/media/a.png"),
+			Error: errorhandler.ErrInvalidQuery,
+		},
+		{
+			Label: "Search sorted over pages",
+			Query: storage.WithFilePageSize(storage.WithFileSort(storage.Query{}, storage.SortBySize, false), 2),
+			Error: errorhandler.ErrInvalidQuery,
+		},
+		{
+			Label: "Search other prefix",
+			Query: storage.WithFileCloudPrefix(storage.Query{}, "/other/"),
+			Want:  []string{"/other/e.png"},
+		},
+		{
+			Label: "Search invalid regex",
+			Query: storage.WithFileNameRegex(storage.Query{}, "("),
+			Error: errorhandler.ErrInvalidQuery,
+		},
+	}
+	index := NewIndex(suite.media, &storagetest.IFile{}, store)
+	for _, tc := range testCases {
+		var paths []string
+		next, err := index.Search(suite.ctx, tc.Query, func(file storage.File) error {
+			paths = append(paths, file.Path())
+			return nil
+		})
+		if tc.Error != nil {
+			suite.ErrorIs(err, tc.Error, tc.Label)
+			continue
+		}
+		suite.NoError(err, tc.Label)
+		suite.Equal(tc.Want, paths, tc.Label)
+		suite.Equal(tc.Next, next, tc.Label)
+	}
+}
+
+// countingStore counts the writes to the store.
+type countingStore struct {
+	*MemoryStore
+	puts int
+}
+
+func (c *countingStore) Put(ctx context.Context, records ...Record) error {
+	c.puts++
+	return c.MemoryStore.Put(ctx, records...)
+}
+
+func (suite *IndexSuite) TestRebuild() {
+	store := &countingStore{MemoryStore: NewMemoryStore()}
+	suite.NoError(store.Put(suite.ctx, Record{Path: "/media/stale"}))
+	file := &storagetest.IFile{}
+	file.On("List", mock.Anything, storage.WithFileCloudPrefix(storage.Query{}, "/media/"), mock.Anything).
+		Return([]storage.File{storagetest.File{Route: "/media/a/", Folder: true}, storagetest.File{Route: "/media/a/1"}, storagetest.File{Route: "/media/b"}}, nil)
+
+	count, err := NewIndex(suite.media, file, store).Rebuild(suite.ctx)
+	suite.NoError(err)
+	suite.Equal(2, count)
+	suite.Equal(2, store.puts)
+	suite.Equal([]string{"/media/a/1", "/media/b"}, suite.paths(store))
+}
+
+func TestIndexSuite(t *testing.T) {
+	suite.Run(t, new(IndexSuite))
+}
@@ -0,0 +1,93 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"io"
+	"path"
+	"time"
+)
+
+// Record is the indexed attributes of a stored object.
+type Record struct {
+	Path        string            `json:"path"`
+	ContentType string            `json:"content_type,omitempty"`
+	Size        int64             `json:"size"`
+	Created     time.Time         `json:"created"`
+	Updated     time.Time         `json:"updated"`
+	Generation  int64             `json:"generation,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	PublicURL   string            `json:"public_url,omitempty"`
+}
+
+// NewRecord copies the attributes of file, folders have no record.
+func NewRecord(file storage.File) (Record, bool, error) {
+	if _, _, exist := file.FolderInfo(); exist {
+		return Record{}, false, nil
+	}
+	size, err := file.Size()
+	if err != nil {
+		return Record{}, false, err
+	}
+	created, err := file.CreatedTime()
+	if err != nil {
+		return Record{}, false, err
+	}
+	updated, err := file.ModTime()
+	if err != nil {
+		return Record{}, false, err
+	}
+	return Record{
+		Path:        file.Path(),
+		ContentType: file.ContentType(),
+		Size:        size,
+		Created:     created,
+		Updated:     updated,
+		Generation:  file.Generation(),
+		Metadata:    file.Metadata(),
+		PublicURL:   file.GetURL(),
+	}, true, nil
+}
+
+// File serves the record as a storage.File, its content is only reachable through the IFile.
+func (r Record) File() storage.File { return recordFile{r} }
+
+type recordFile struct {
+	r Record
+}
+
+func (f recordFile) FolderInfo() (string, string, bool) { return "", "", false }
+
+func (f recordFile) Path() string { return f.r.Path }
+
+func (f recordFile) Name() string { return path.Base(f.r.Path) }
+
+func (f recordFile) ContentType() string { return f.r.ContentType }
+
+func (f recordFile) Generation() int64 { return f.r.Generation }
+
+func (f recordFile) Metadata() map[string]string { return f.r.Metadata }
+
+func (f recordFile) Size() (int64, error) { return f.r.Size, nil }
+
+func (f recordFile) CreatedTime() (time.Time, error) { return f.r.Created, nil }
+
+func (f recordFile) ModTime() (time.Time, error) { return f.r.Updated, nil }
+
+func (f recordFile) NewWriter(ctx context.Context) (io.WriteCloser, func() error) {
+	return nil, func() error { return f.contentErr() }
+}
+
+func (f recordFile) NewReader(ctx context.Context) (io.ReadCloser, func() error, error) {
+	return nil, nil, f.contentErr()
+}
+
+func (f recordFile) Remove(ctx context.Context) error { return f.contentErr() }
+
+func (f recordFile) GetURL() string { return f.r.PublicURL }
+
+func (f recordFile) contentErr() error {
+	return fmt.Errorf("%w: indexed record %s has no content", errorhandler.ErrGetFile, f.r.Path)
+}
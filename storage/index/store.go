@@ -0,0 +1,217 @@
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	bolt "go.etcd.io/bbolt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type IIndexStore interface {
+	Put(ctx context.Context, records ...Record) error
+	Delete(ctx context.Context, paths ...string) error
+	DeletePrefix(ctx context.Context, prefix string) error
+	// Scan calls fn with the records under prefix whose path sorts after after, in path order.
+	Scan(ctx context.Context, prefix, after string, fn func(Record) error) error
+	// Reset removes every record.
+	Reset(ctx context.Context) error
+	Close() error
+}
+
+// NewMemoryStore method
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records: map[string]Record{},
+	}
+}
+
+type MemoryStore struct {
+	mu      sync.RWMutex
+	records map[string]Record
+}
+
+func (m *MemoryStore) Put(ctx context.Context, records ...Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, record := range records {
+		m.records[record.Path] = record
+	}
+	return nil
+}
+
+func (m *MemoryStore) Delete(ctx context.Context, paths ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, route := range paths {
+		delete(m.records, route)
+	}
+	return nil
+}
+
+func (m *MemoryStore) DeletePrefix(ctx context.Context, prefix string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for route := range m.records {
+		if strings.HasPrefix(route, prefix) {
+			delete(m.records, route)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) Scan(ctx context.Context, prefix, after string, fn func(Record) error) error {
+	m.mu.RLock()
+	var records []Record
+	for route, record := range m.records {
+		if strings.HasPrefix(route, prefix) && route > after {
+			records = append(records, record)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(records, func(i, j int) bool { return records[i].Path < records[j].Path })
+	for _, record := range records {
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) Reset(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = map[string]Record{}
+	return nil
+}
+
+func (m *MemoryStore) Close() error { return nil }
+
+var recordBucket = []byte("records")
+
+// NewBoltStore opens the BoltDB file at route, creating it if needed.
+func NewBoltStore(route string) (*BoltStore, error) {
+	db, err := bolt.Open(route, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrIndexStore, err.Error())
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(recordBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrIndexStore, err.Error())
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// BoltStore keeps the records in a BoltDB file keyed by path.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+func (b *BoltStore) Put(ctx context.Context, records ...Record) error {
+	return b.update(func(bucket *bolt.Bucket) error {
+		for _, record := range records {
+			value, err := json.Marshal(record)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(record.Path), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *BoltStore) Delete(ctx context.Context, paths ...string) error {
+	return b.update(func(bucket *bolt.Bucket) error {
+		for _, route := range paths {
+			if err := bucket.Delete([]byte(route)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *BoltStore) DeletePrefix(ctx context.Context, prefix string) error {
+	return b.update(func(bucket *bolt.Bucket) error {
+		c := bucket.Cursor()
+		for k, _ := c.Seek([]byte(prefix)); k != nil && strings.HasPrefix(string(k), prefix); k, _ = c.Seek([]byte(prefix)) {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Scan reads the records in a single read transaction, fn must not write to the store.
+func (b *BoltStore) Scan(ctx context.Context, prefix, after string, fn func(Record) error) error {
+	var errFn error
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(recordBucket).Cursor()
+		start := prefix
+		if after > start {
+			start = after
+		}
+		for k, v := c.Seek([]byte(start)); k != nil && strings.HasPrefix(string(k), prefix); k, v = c.Next() {
+			if string(k) == after {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var record Record
+			if err := json.Unmarshal(v, &record); err != nil {
+				return err
+			}
+			if errFn = fn(record); errFn != nil {
+				return errFn
+			}
+		}
+		return nil
+	})
+	if errFn != nil {
+		return errFn
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", errorhandler.ErrIndexStore, err.Error())
+	}
+	return nil
+}
+
+func (b *BoltStore) Reset(ctx context.Context) error {
+	if err := b.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(recordBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucket(recordBucket)
+		return err
+	}); err != nil {
+		return fmt.Errorf("%w: %s", errorhandler.ErrIndexStore, err.Error())
+	}
+	return nil
+}
+
+func (b *BoltStore) Close() error {
+	if err := b.db.Close(); err != nil {
+		return fmt.Errorf("%w: %s", errorhandler.ErrIndexStore, err.Error())
+	}
+	return nil
+}
+
+func (b *BoltStore) update(fn func(bucket *bolt.Bucket) error) error {
+	if err := b.db.Update(func(tx *bolt.Tx) error {
+		return fn(tx.Bucket(recordBucket))
+	}); err != nil {
+		return fmt.Errorf("%w: %s", errorhandler.ErrIndexStore, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package index
+
+import (
+	"context"
+	"github.com/stretchr/testify/suite"
+	"path/filepath"
+	"testing"
+)
+
+type StoreSuite struct {
+	suite.Suite
+	ctx context.Context
+}
+
+func (suite *StoreSuite) SetupTest() {
+	suite.ctx = context.Background()
+}
+
+func (suite *StoreSuite) scan(store IIndexStore, prefix, after string) []string {
+	var paths []string
+	suite.NoError(store.Scan(suite.ctx, prefix, after, func(record Record) error {
+		paths = append(paths, record.Path)
+		return nil
+	}))
+	return paths
+}
+
+func (suite *StoreSuite) testStore(store IIndexStore) {
+	suite.NoError(store.Put(suite.ctx,
+		Record{Path: "/media/b/2", Size: 2},
+		Record{Path: "/media/a/1", Size: 1, Metadata: map[string]string{"owner": "alice"}},
+		Record{Path: "/media/b/1", Size: 3},
+		Record{Path: "/other/1"},
+	))
+	suite.Equal([]string{"/media/a/1", "/media/b/1", "/media/b/2"}, suite.scan(store, "/media/", ""))
+	suite.Equal([]string{"/media/b/2"}, suite.scan(store, "/media/", "/media/b/1"))
+
+	var record Record
+	suite.NoError(store.Scan(suite.ctx, "/media/a/", "", func(r Record) error {
+		record = r
+		return nil
+	}))
+	suite.Equal(Record{Path: "/media/a/1", Size: 1, Metadata: map[string]string{"owner": "alice"}}, record)
+
+	suite.NoError(store.Delete(suite.ctx, "/media/a/1", "/media/none"))
+	suite.NoError(store.DeletePrefix(suite.ctx, "/media/b/"))
+	suite.Equal([]string{"/other/1"}, suite.scan(store, "", ""))
+
+	suite.NoError(store.Reset(suite.ctx))
+	suite.Empty(suite.scan(store, "", ""))
+	suite.NoError(store.Close())
+}
+
+func (suite *StoreSuite) TestMemoryStore() {
+	suite.testStore(NewMemoryStore())
+}
+
+func (suite *StoreSuite) TestBoltStore() {
+	store, err := NewBoltStore(filepath.Join(suite.T().TempDir(), "index.db"))
+	suite.NoError(err)
+	suite.testStore(store)
+}
+
+func (suite *StoreSuite) TestBoltStoreReopen() {
+	route := filepath.Join(suite.T().TempDir(), "index.db")
+	store, err := NewBoltStore(route)
+	suite.NoError(err)
+	suite.NoError(store.Put(suite.ctx, Record{Path: "/media/a/1", Size: 1}))
+	suite.NoError(store.Close())
+
+	reopened, err := NewBoltStore(route)
+	suite.NoError(err)
+	defer reopened.Close()
+	suite.Equal([]string{"/media/a/1"}, suite.scan(reopened, "", ""))
+}
+
+func TestStoreSuite(t *testing.T) {
+	suite.Run(t, new(StoreSuite))
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+)
+
+type LifecycleActionType string
+
+const (
+	LifecycleDelete          LifecycleActionType = "Delete"
+	LifecycleSetStorageClass LifecycleActionType = "SetStorageClass"
+)
+
+type LivenessType string
+
+const (
+	LivenessAny      LivenessType = ""
+	LivenessLive     LivenessType = "live"
+	LivenessArchived LivenessType = "archived"
+)
+
+// LifecycleRule applies its action to the objects matching every set condition.
+type LifecycleRule struct {
+	Action                LifecycleActionType `json:"action"`
+	StorageClass          string              `json:"storage_class,omitempty"`
+	AgeInDays             int64               `json:"age_in_days,omitempty"`
+	NumNewerVersions      int64               `json:"num_newer_versions,omitempty"`
+	Liveness              LivenessType        `json:"liveness,omitempty"`
+	MatchesStorageClasses []string            `json:"matches_storage_classes,omitempty"`
+}
+
+type Lifecycle struct {
+	Rules []LifecycleRule `json:"rules"`
+}
+
+// ILifecycle is implemented by the drivers and decorators managing the lifecycle rules of the bucket.
+type ILifecycle interface {
+	Lifecycle(ctx context.Context) (Lifecycle, error)
+	// SetLifecycle replaces every rule, an empty Lifecycle removes them.
+	SetLifecycle(ctx context.Context, lifecycle Lifecycle) error
+}
+
+// Validate checks every rule has a known action and at least one condition.
+func (l Lifecycle) Validate() error {
+	for i, rule := range l.Rules {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("%w: rule %d %s", errorhandler.ErrLifecycleRule, i, err.Error())
+		}
+	}
+	return nil
+}
+
+func (r LifecycleRule) validate() error {
+	switch r.Action {
+	case LifecycleDelete:
+		if r.StorageClass != "" {
+			return fmt.Errorf("storage class set on %s", r.Action)
+		}
+	case LifecycleSetStorageClass:
+		if r.StorageClass == "" {
+			return fmt.Errorf("storage class required on %s", r.Action)
+		}
+	default:
+		return fmt.Errorf("unknown action %q", r.Action)
+	}
+	switch r.Liveness {
+	case LivenessAny, LivenessLive, LivenessArchived:
+	default:
+		return fmt.Errorf("unknown liveness %q", r.Liveness)
+	}
+	if r.AgeInDays < 0 || r.NumNewerVersions < 0 {
+		return fmt.Errorf("negative condition")
+	}
+	if r.AgeInDays == 0 && r.NumNewerVersions == 0 && r.Liveness == LivenessAny && len(r.MatchesStorageClasses) == 0 {
+		return fmt.Errorf("no condition")
+	}
+	return nil
+}
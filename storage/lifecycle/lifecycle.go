@@ -0,0 +1,219 @@
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/cockroachdb/errors"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/kelseyhightower/envconfig"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FromEnv wraps file by the evaluator configured from environment, rules are kept
+// in a JSON file when LIFECYCLE_RULES_PATH is set and in memory otherwise.
+func FromEnv(file storage.IFile) (*Evaluator, error) {
+	media := config.Media{}
+	env := config.Lifecycle{}
+	for _, item := range []interface{}{&media, &env} {
+		if err := envconfig.Process("", item); err != nil {
+			return nil, fmt.Errorf("%w: %s", errorhandler.ErrInitialFileClient, err.Error())
+		}
+	}
+	return NewEvaluator(media, env, file)
+}
+
+// NewEvaluator loads the rules persisted at LifecycleRulesPath, starting without
+// rules if the file does not exist yet.
+func NewEvaluator(media config.Media, env config.Lifecycle, file storage.IFile) (*Evaluator, error) {
+	e := &Evaluator{
+		IFile: file,
+		media: media,
+		env:   env,
+		now:   time.Now,
+	}
+	if env.LifecycleRulesPath == "" {
+		return e, nil
+	}
+	b, err := os.ReadFile(env.LifecycleRulesPath)
+	if os.IsNotExist(err) {
+		return e, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrLifecycle, err.Error())
+	}
+	if err := json.Unmarshal(b, &e.lifecycle); err != nil {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrLifecycle, err.Error())
+	}
+	if err := validate(e.lifecycle); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Evaluator applies the delete rules of the bucket lifecycle to the wrapped IFile
+// for the drivers without lifecycle management. Only the objects with a live version
+// are evaluated, storage classes are not supported.
+type Evaluator struct {
+	storage.IFile
+	media     config.Media
+	env       config.Lifecycle
+	now       func() time.Time
+	mu        sync.RWMutex
+	lifecycle storage.Lifecycle
+}
+
+func (e *Evaluator) Unwrap() storage.IFile { return e.IFile }
+
+func (e *Evaluator) Lifecycle(ctx context.Context) (storage.Lifecycle, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	rules := make([]storage.LifecycleRule, len(e.lifecycle.Rules))
+	copy(rules, e.lifecycle.Rules)
+	return storage.Lifecycle{Rules: rules}, nil
+}
+
+func (e *Evaluator) SetLifecycle(ctx context.Context, lifecycle storage.Lifecycle) error {
+	if err := validate(lifecycle); err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.env.LifecycleRulesPath != "" {
+		if err := writeFile(e.env.LifecycleRulesPath, lifecycle); err != nil {
+			return err
+		}
+	}
+	e.lifecycle = lifecycle
+	return nil
+}
+
+// Run removes the objects and versions matched by a delete rule and returns their count.
+func (e *Evaluator) Run(ctx context.Context) (int, error) {
+	lifecycle, err := e.Lifecycle(ctx)
+	if err != nil || len(lifecycle.Rules) == 0 {
+		return 0, err
+	}
+	query := storage.Query{}
+	if e.media.PrefixPath != "" {
+		query = storage.WithFileCloudPrefix(query, e.media.PrefixPath)
+	}
+	var routes []string
+	if err := e.IFile.List(ctx, query, func(file storage.File) error {
+		if _, _, exist := file.FolderInfo(); !exist {
+			routes = append(routes, file.Path())
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	var (
+		mu    sync.Mutex
+		count int
+	)
+	err = storage.ForEach(ctx, storage.DefaultConcurrency, len(routes), func(ctx context.Context, i int) error {
+		n, err := e.apply(ctx, lifecycle, routes[i])
+		mu.Lock()
+		count += n
+		mu.Unlock()
+		return err
+	})
+	return count, err
+}
+
+// apply evaluates the rules on every version of the object at route, newest first.
+func (e *Evaluator) apply(ctx context.Context, lifecycle storage.Lifecycle, route string) (int, error) {
+	var versions []storage.File
+	if err := e.IFile.Versions(ctx, route, func(file storage.File) error {
+		versions = append(versions, file)
+		return nil
+	}); err != nil {
+		if errors.Is(err, errorhandler.ErrFileNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	sort.SliceStable(versions, func(i, j int) bool { return versions[i].Generation() > versions[j].Generation() })
+	now := e.now()
+	var count int
+	for i, version := range versions {
+		matched, err := matchAny(lifecycle, version, int64(i), now)
+		if err != nil {
+			return count, err
+		}
+		if !matched {
+			continue
+		}
+		if i == 0 {
+			err = e.IFile.Remove(ctx, route, storage.IfGenerationMatch(version.Generation()))
+		} else {
+			err = e.IFile.RemoveVersion(ctx, route, version.Generation())
+		}
+		if errors.Is(err, errorhandler.ErrFileNotExist) || errors.Is(err, errorhandler.ErrRemoveNotExist) || errors.Is(err, errorhandler.ErrPreconditionFailed) {
+			continue
+		}
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// matchAny reports whether a rule matches the version with newer versions newer than it.
+func matchAny(lifecycle storage.Lifecycle, version storage.File, newer int64, now time.Time) (bool, error) {
+	created, err := version.CreatedTime()
+	if err != nil {
+		return false, err
+	}
+	for _, rule := range lifecycle.Rules {
+		if rule.AgeInDays > 0 && now.Sub(created) < time.Duration(rule.AgeInDays)*24*time.Hour {
+			continue
+		}
+		if rule.NumNewerVersions > 0 && newer < rule.NumNewerVersions {
+			continue
+		}
+		if (rule.Liveness == storage.LivenessLive && newer > 0) || (rule.Liveness == storage.LivenessArchived && newer == 0) {
+			continue
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// Start runs Run every LifecycleInterval until ctx is done or stop is called.
+func (e *Evaluator) Start(ctx context.Context) (stop func()) {
+	return storage.Every(ctx, e.env.LifecycleInterval, "lifecycle evaluator", func(ctx context.Context) error {
+		_, err := e.Run(ctx)
+		return err
+	})
+}
+
+// validate rejects the rules the evaluator can't apply on top of Lifecycle.Validate.
+func validate(lifecycle storage.Lifecycle) error {
+	if err := lifecycle.Validate(); err != nil {
+		return err
+	}
+	for i, rule := range lifecycle.Rules {
+		if rule.Action != storage.LifecycleDelete || len(rule.MatchesStorageClasses) > 0 {
+			return fmt.Errorf("%w: rule %d storage class not supported", errorhandler.ErrLifecycleRule, i)
+		}
+	}
+	return nil
+}
+
+func writeFile(route string, lifecycle storage.Lifecycle) error {
+	b, err := json.Marshal(lifecycle)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errorhandler.ErrLifecycle, err.Error())
+	}
+	if err := storage.WriteFileAtomic(route, b); err != nil {
+		return fmt.Errorf("%w: %s", errorhandler.ErrLifecycle, err.Error())
+	}
+	return nil
+}
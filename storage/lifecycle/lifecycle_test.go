@@ -0,0 +1,175 @@
+package lifecycle
+
+import (
+	"context"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/justdomepaul/gin-storage/storage/storagetest"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type LifecycleSuite struct {
+	suite.Suite
+	ctx context.Context
+	now time.Time
+}
+
+func (suite *LifecycleSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.now = time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC)
+}
+
+func (suite *LifecycleSuite) newEvaluator(file storage.IFile, rules ...storage.LifecycleRule) *Evaluator {
+	e, err := NewEvaluator(config.Media{PrefixPath: "/media/"}, config.Lifecycle{LifecycleInterval: 10 * time.Millisecond}, file)
+	suite.NoError(err)
+	e.now = func() time.Time { return suite.now }
+	suite.NoError(e.SetLifecycle(suite.ctx, storage.Lifecycle{Rules: rules}))
+	return e
+}
+
+func (suite *LifecycleSuite) TestSetLifecycle() {
+	testCases := []struct {
+		Label string
+		Rule  storage.LifecycleRule
+		Error error
+	}{
+		{
+			Label: "Delete after days",
+			Rule:  storage.LifecycleRule{Action: storage.LifecycleDelete, AgeInDays: 30},
+		},
+		{
+			Label: "Transition not supported",
+			Rule:  storage.LifecycleRule{Action: storage.LifecycleSetStorageClass, StorageClass: "COLDLINE", AgeInDays: 30},
+			Error: errorhandler.ErrLifecycleRule,
+		},
+		{
+			Label: "Storage class condition not supported",
+			Rule:  storage.LifecycleRule{Action: storage.LifecycleDelete, MatchesStorageClasses: []string{"COLDLINE"}},
+			Error: errorhandler.ErrLifecycleRule,
+		},
+		{
+			Label: "Invalid rule",
+			Rule:  storage.LifecycleRule{Action: storage.LifecycleDelete},
+			Error: errorhandler.ErrLifecycleRule,
+		},
+	}
+	for _, tc := range testCases {
+		e := suite.newEvaluator(&storagetest.IFile{})
+		err := e.SetLifecycle(suite.ctx, storage.Lifecycle{Rules: []storage.LifecycleRule{tc.Rule}})
+		lifecycle, errGet := e.Lifecycle(suite.ctx)
+		suite.NoError(errGet)
+		if tc.Error != nil {
+			suite.ErrorIs(err, tc.Error, tc.Label)
+			suite.Empty(lifecycle.Rules, tc.Label)
+			continue
+		}
+		suite.NoError(err, tc.Label)
+		suite.Equal([]storage.LifecycleRule{tc.Rule}, lifecycle.Rules, tc.Label)
+	}
+}
+
+func (suite *LifecycleSuite) TestRulesFile() {
+	env := config.Lifecycle{LifecycleRulesPath: filepath.Join(suite.T().TempDir(), "lifecycle.json")}
+	e, err := NewEvaluator(config.Media{}, env, &storagetest.IFile{})
+	suite.NoError(err)
+	rules := []storage.LifecycleRule{{Action: storage.LifecycleDelete, AgeInDays: 30}}
+	suite.NoError(e.SetLifecycle(suite.ctx, storage.Lifecycle{Rules: rules}))
+
+	reloaded, err := NewEvaluator(config.Media{}, env, &storagetest.IFile{})
+	suite.NoError(err)
+	lifecycle, err := reloaded.Lifecycle(suite.ctx)
+	suite.NoError(err)
+	suite.Equal(rules, lifecycle.Rules)
+}
+
+func (suite *LifecycleSuite) TestRun() {
+	testCases := []struct {
+		Label   string
+		Rule    storage.LifecycleRule
+		Live    bool
+		Removed []int64
+	}{
+		{
+			Label: "Delete live after days",
+			Rule:  storage.LifecycleRule{Action: storage.LifecycleDelete, AgeInDays: 10, Liveness: storage.LivenessLive},
+			Live:  true,
+		},
+		{
+			Label:   "Keep two versions",
+			Rule:    storage.LifecycleRule{Action: storage.LifecycleDelete, NumNewerVersions: 2},
+			Removed: []int64{1},
+		},
+		{
+			Label:   "Delete archived after days",
+			Rule:    storage.LifecycleRule{Action: storage.LifecycleDelete, AgeInDays: 10, Liveness: storage.LivenessArchived},
+			Removed: []int64{1},
+		},
+		{
+			Label:   "Delete archived",
+			Rule:    storage.LifecycleRule{Action: storage.LifecycleDelete, Liveness: storage.LivenessArchived},
+			Removed: []int64{2, 1},
+		},
+	}
+	for _, tc := range testCases {
+		file := &storagetest.IFile{}
+		file.On("List", mock.Anything, storage.WithFileCloudPrefix(storage.Query{}, "/media/"), mock.Anything).
+			Return([]storage.File{storagetest.File{Route: "/media/dir/", Folder: true}, storagetest.File{Route: "/media/a"}}, nil)
+		file.On("Versions", mock.Anything, "/media/a", mock.Anything).Return([]storage.File{
+			storagetest.File{Route: "/media/a", Gen: 1, Created: suite.now.AddDate(0, 0, -30)},
+			storagetest.File{Route: "/media/a", Gen: 3, Created: suite.now.AddDate(0, 0, -20)},
+			storagetest.File{Route: "/media/a", Gen: 2, Created: suite.now.AddDate(0, 0, -5)},
+		}, nil)
+		file.On("Remove", mock.Anything, "/media/a", []storage.Precondition{storage.IfGenerationMatch(3)}).Return(nil)
+		file.On("RemoveVersion", mock.Anything, "/media/a", mock.Anything).Return(nil)
+
+		count, err := suite.newEvaluator(file, tc.Rule).Run(suite.ctx)
+		suite.NoError(err, tc.Label)
+		want := len(tc.Removed)
+		if tc.Live {
+			want++
+			file.AssertCalled(suite.T(), "Remove", mock.Anything, "/media/a", []storage.Precondition{storage.IfGenerationMatch(3)})
+		} else {
+			file.AssertNotCalled(suite.T(), "Remove", mock.Anything, mock.Anything, mock.Anything)
+		}
+		suite.Equal(want, count, tc.Label)
+		file.AssertNumberOfCalls(suite.T(), "RemoveVersion", len(tc.Removed))
+		for _, generation := range tc.Removed {
+			file.AssertCalled(suite.T(), "RemoveVersion", mock.Anything, "/media/a", generation)
+		}
+	}
+}
+
+func (suite *LifecycleSuite) TestRunWithoutRules() {
+	count, err := suite.newEvaluator(&storagetest.IFile{}).Run(suite.ctx)
+	suite.NoError(err)
+	suite.Equal(0, count)
+}
+
+func (suite *LifecycleSuite) TestStart() {
+	file := &storagetest.IFile{}
+	ran := make(chan struct{}, 1)
+	file.On("List", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			select {
+			case ran <- struct{}{}:
+			default:
+			}
+		}).Return([]storage.File{}, nil)
+
+	stop := suite.newEvaluator(file, storage.LifecycleRule{Action: storage.LifecycleDelete, AgeInDays: 1}).Start(suite.ctx)
+	defer stop()
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		suite.Fail("evaluator not run")
+	}
+}
+
+func TestLifecycleSuite(t *testing.T) {
+	suite.Run(t, new(LifecycleSuite))
+}
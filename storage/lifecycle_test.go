@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type LifecycleSuite struct {
+	suite.Suite
+}
+
+func (suite *LifecycleSuite) TestValidate() {
+	testCases := []struct {
+		Label string
+		Rule  LifecycleRule
+		Error error
+	}{
+		{
+			Label: "Delete after days",
+			Rule:  LifecycleRule{Action: LifecycleDelete, AgeInDays: 30},
+		},
+		{
+			Label: "Keep versions",
+			Rule:  LifecycleRule{Action: LifecycleDelete, NumNewerVersions: 3},
+		},
+		{
+			Label: "Transition storage class",
+			Rule:  LifecycleRule{Action: LifecycleSetStorageClass, StorageClass: "COLDLINE", AgeInDays: 90},
+		},
+		{
+			Label: "Transition without storage class",
+			Rule:  LifecycleRule{Action: LifecycleSetStorageClass, AgeInDays: 90},
+			Error: errorhandler.ErrLifecycleRule,
+		},
+		{
+			Label: "Delete with storage class",
+			Rule:  LifecycleRule{Action: LifecycleDelete, StorageClass: "COLDLINE", AgeInDays: 90},
+			Error: errorhandler.ErrLifecycleRule,
+		},
+		{
+			Label: "Unknown action",
+			Rule:  LifecycleRule{Action: "Archive", AgeInDays: 1},
+			Error: errorhandler.ErrLifecycleRule,
+		},
+		{
+			Label: "Unknown liveness",
+			Rule:  LifecycleRule{Action: LifecycleDelete, Liveness: "dead"},
+			Error: errorhandler.ErrLifecycleRule,
+		},
+		{
+			Label: "Negative age",
+			Rule:  LifecycleRule{Action: LifecycleDelete, AgeInDays: -1},
+			Error: errorhandler.ErrLifecycleRule,
+		},
+		{
+			Label: "No condition",
+			Rule:  LifecycleRule{Action: LifecycleDelete},
+			Error: errorhandler.ErrLifecycleRule,
+		},
+	}
+	for _, tc := range testCases {
+		err := Lifecycle{Rules: []LifecycleRule{tc.Rule}}.Validate()
+		if tc.Error != nil {
+			suite.ErrorIs(err, tc.Error, tc.Label)
+			continue
+		}
+		suite.NoError(err, tc.Label)
+	}
+}
+
+func TestLifecycleSuite(t *testing.T) {
+	suite.Run(t, new(LifecycleSuite))
+}
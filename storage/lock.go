@@ -0,0 +1,30 @@
+package storage
+
+import "time"
+
+// ObjectLock is the hold and retention state of an object, a locked object can't
+// be removed, moved or overwritten. Holds are set per object, RetainUntil is derived
+// from the Retention of the bucket.
+type ObjectLock struct {
+	EventBasedHold bool      `json:"event_based_hold"`
+	TemporaryHold  bool      `json:"temporary_hold"`
+	RetainUntil    time.Time `json:"retain_until,omitempty"`
+}
+
+// Locked reports whether a hold is set or the retention runs past now.
+func (l ObjectLock) Locked(now time.Time) bool {
+	return l.EventBasedHold || l.TemporaryHold || now.Before(l.RetainUntil)
+}
+
+// Retention is the retention policy of the bucket, every object is retained PeriodSeconds
+// after its creation. A locked policy can't be removed or shortened.
+type Retention struct {
+	PeriodSeconds int64 `json:"period_seconds"`
+	Locked        bool  `json:"locked"`
+}
+
+// Hold sets the holds of an object, a nil hold is left unchanged.
+type Hold struct {
+	EventBased *bool `json:"event_based,omitempty"`
+	Temporary  *bool `json:"temporary,omitempty"`
+}
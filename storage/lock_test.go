@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type LockSuite struct {
+	suite.Suite
+}
+
+func (suite *LockSuite) TestLocked() {
+	now := time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC)
+	testCases := []struct {
+		Label string
+		Lock  ObjectLock
+		Want  bool
+	}{
+		{
+			Label: "Unlocked",
+		},
+		{
+			Label: "Event based hold",
+			Lock:  ObjectLock{EventBasedHold: true},
+			Want:  true,
+		},
+		{
+			Label: "Temporary hold",
+			Lock:  ObjectLock{TemporaryHold: true},
+			Want:  true,
+		},
+		{
+			Label: "Retained",
+			Lock:  ObjectLock{RetainUntil: now.Add(time.Second)},
+			Want:  true,
+		},
+		{
+			Label: "Retention expired",
+			Lock:  ObjectLock{RetainUntil: now},
+		},
+	}
+	for _, tc := range testCases {
+		suite.Equal(tc.Want, tc.Lock.Locked(now), tc.Label)
+	}
+}
+
+func TestLockSuite(t *testing.T) {
+	suite.Run(t, new(LockSuite))
+}
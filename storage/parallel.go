@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultConcurrency bounds the goroutines ForEach runs when concurrency is not positive.
+const DefaultConcurrency = 8
+
+// ForEach calls fn for every index below n on at most concurrency goroutines.
+// The first error cancels the context given to the remaining calls and is returned.
+func ForEach(ctx context.Context, concurrency, n int, fn func(ctx context.Context, i int) error) error {
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	indexes := make(chan int)
+	for w := 0; w < concurrency && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := fn(ctx, i); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+feed:
+	for i := 0; i < n; i++ {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/suite"
+	"sync/atomic"
+	"testing"
+)
+
+type ParallelSuite struct {
+	suite.Suite
+}
+
+func (suite *ParallelSuite) TestForEach() {
+	var (
+		sum     int64
+		running int64
+		peak    int64
+	)
+	suite.NoError(ForEach(context.Background(), 3, 100, func(ctx context.Context, i int) error {
+		n := atomic.AddInt64(&running, 1)
+		for {
+			p := atomic.LoadInt64(&peak)
+			if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+				break
+			}
+		}
+		atomic.AddInt64(&sum, int64(i))
+		atomic.AddInt64(&running, -1)
+		return nil
+	}))
+	suite.Equal(int64(4950), sum)
+	suite.LessOrEqual(peak, int64(3))
+}
+
+func (suite *ParallelSuite) TestForEachError() {
+	errStop := errors.New("stop")
+	var calls int64
+	err := ForEach(context.Background(), 1, 100, func(ctx context.Context, i int) error {
+		atomic.AddInt64(&calls, 1)
+		if i == 3 {
+			return errStop
+		}
+		return nil
+	})
+	suite.ErrorIs(err, errStop)
+	suite.Less(calls, int64(100))
+}
+
+func (suite *ParallelSuite) TestForEachEmpty() {
+	suite.NoError(ForEach(context.Background(), 0, 0, func(ctx context.Context, i int) error {
+		suite.Fail("not expected")
+		return nil
+	}))
+}
+
+func TestParallelSuite(t *testing.T) {
+	suite.Run(t, new(ParallelSuite))
+}
@@ -0,0 +1,33 @@
+package storage
+
+// Precondition guards a write or a remove against concurrent changes of its target.
+type Precondition struct {
+	// GenerationMatch requires the target to be at this generation, zero for no requirement.
+	GenerationMatch int64
+	// DoesNotExist requires the target not to exist.
+	DoesNotExist bool
+}
+
+func IfGenerationMatch(generation int64) Precondition {
+	return Precondition{GenerationMatch: generation}
+}
+
+func IfDoesNotExist() Precondition {
+	return Precondition{DoesNotExist: true}
+}
+
+// MergePreconditions combines conds into a single Precondition, a later generation wins.
+func MergePreconditions(conds ...Precondition) Precondition {
+	var merged Precondition
+	for _, cond := range conds {
+		if cond.GenerationMatch != 0 {
+			merged.GenerationMatch = cond.GenerationMatch
+		}
+		merged.DoesNotExist = merged.DoesNotExist || cond.DoesNotExist
+	}
+	return merged
+}
+
+func (p Precondition) IsZero() bool {
+	return p.GenerationMatch == 0 && !p.DoesNotExist
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type PreconditionSuite struct {
+	suite.Suite
+}
+
+func (suite *PreconditionSuite) TestMergePreconditions() {
+	testCases := []struct {
+		Label string
+		Conds []Precondition
+		Want  Precondition
+	}{
+		{
+			Label: "No precondition",
+		},
+		{
+			Label: "Generation match",
+			Conds: []Precondition{IfGenerationMatch(3)},
+			Want:  Precondition{GenerationMatch: 3},
+		},
+		{
+			Label: "Later generation wins",
+			Conds: []Precondition{IfGenerationMatch(3), IfGenerationMatch(5)},
+			Want:  Precondition{GenerationMatch: 5},
+		},
+		{
+			Label: "Does not exist",
+			Conds: []Precondition{IfDoesNotExist(), {}},
+			Want:  Precondition{DoesNotExist: true},
+		},
+	}
+	for _, tc := range testCases {
+		merged := MergePreconditions(tc.Conds...)
+		suite.Equal(tc.Want, merged, tc.Label)
+		suite.Equal(len(tc.Conds) == 0, merged.IsZero(), tc.Label)
+	}
+}
+
+func TestPreconditionSuite(t *testing.T) {
+	suite.Run(t, new(PreconditionSuite))
+}
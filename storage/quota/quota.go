@@ -0,0 +1,424 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"github.com/cockroachdb/errors"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/kelseyhightower/envconfig"
+	"io"
+	"path"
+	"strings"
+)
+
+// FromEnv wraps file by the quota configured from environment, usages are kept
+// in a file when QUOTA_STORE_PATH is set and in memory otherwise.
+func FromEnv(file storage.IFile) (*Quota, error) {
+	media := config.Media{}
+	env := config.Quota{}
+	for _, item := range []interface{}{&media, &env} {
+		if err := envconfig.Process("", item); err != nil {
+			return nil, fmt.Errorf("%w: %s", errorhandler.ErrInitialFileClient, err.Error())
+		}
+	}
+	var store IUsageStore = NewMemoryStore()
+	if env.QuotaStorePath != "" {
+		fileStore, err := NewFileStore(env.QuotaStorePath)
+		if err != nil {
+			return nil, err
+		}
+		store = fileStore
+	}
+	return NewQuota(media, env, file, store), nil
+}
+
+// NewQuota method
+func NewQuota(media config.Media, env config.Quota, file storage.IFile, store IUsageStore) *Quota {
+	return &Quota{
+		IFile: file,
+		media: media,
+		env:   env,
+		store: store,
+	}
+}
+
+// Quota caps the bytes and objects stored under each top level prefix of the wrapped IFile.
+type Quota struct {
+	storage.IFile
+	media config.Media
+	env   config.Quota
+	store IUsageStore
+}
+
+type Report struct {
+	Prefix     string `json:"prefix"`
+	Bytes      int64  `json:"bytes"`
+	Objects    int64  `json:"objects"`
+	MaxBytes   int64  `json:"max_bytes,omitempty"`
+	MaxObjects int64  `json:"max_objects,omitempty"`
+}
+
+func (q *Quota) Unwrap() storage.IFile { return q.IFile }
+
+// Upload streams f within the remaining quota, an upload named over an existing object
+// replaces its usage. The usage is checked and added at once after the upload, a new
+// object going over the limit meanwhile is removed again. An overwrite can't be undone
+// and is recorded even over the limit.
+func (q *Quota) Upload(ctx context.Context, prefix string, f io.ReadCloser, attrs ...storage.UploadAttrs) (string, error) {
+	var replaced Usage
+	if name := storage.MergeUploadAttrs(attrs...).Name; name != "" {
+		size, exist, err := q.stat(ctx, path.Join(q.media.PrefixPath, prefix, name))
+		if err != nil {
+			return "", err
+		}
+		if exist {
+			replaced = Usage{Bytes: size, Objects: 1}
+		}
+	}
+	key := Key(prefix)
+	usage, err := q.store.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	usage = Usage{Bytes: usage.Bytes - replaced.Bytes, Objects: usage.Objects - replaced.Objects}
+	if (q.env.QuotaMaxObjects > 0 && usage.Objects >= q.env.QuotaMaxObjects) ||
+		(q.env.QuotaMaxBytes > 0 && usage.Bytes >= q.env.QuotaMaxBytes) {
+		return "", fmt.Errorf("%w: %s", errorhandler.ErrQuotaExceeded, key)
+	}
+	reader := &limitReader{ReadCloser: f}
+	if q.env.QuotaMaxBytes > 0 {
+		reader.limit = q.env.QuotaMaxBytes - usage.Bytes
+	}
+	pt, err := q.IFile.Upload(ctx, prefix, reader, attrs...)
+	if reader.exceeded {
+		return "", fmt.Errorf("%w: %s", errorhandler.ErrQuotaTooLarge, key)
+	}
+	if err != nil {
+		return "", err
+	}
+	// recorded where the object landed, as Remove and Reconcile account it
+	delta := Usage{Bytes: reader.n - replaced.Bytes, Objects: 1 - replaced.Objects}
+	_, err = q.store.AddWithin(ctx, q.keyOf(pt), delta, q.limit())
+	if errors.Is(err, errorhandler.ErrQuotaExceeded) && replaced.Objects == 0 {
+		if errRemove := q.IFile.Remove(ctx, pt); errRemove != nil {
+			return "", fmt.Errorf("%w: %s", err, errRemove.Error())
+		}
+		return "", err
+	}
+	if errors.Is(err, errorhandler.ErrQuotaExceeded) {
+		_, err = q.store.Add(ctx, q.keyOf(pt), delta)
+	}
+	if err != nil {
+		return pt, err
+	}
+	return pt, nil
+}
+
+func (q *Quota) Remove(ctx context.Context, route string, conds ...storage.Precondition) error {
+	size, exist, err := q.stat(ctx, route)
+	if err != nil {
+		return err
+	}
+	if err := q.IFile.Remove(ctx, route, conds...); err != nil {
+		return err
+	}
+	if !exist {
+		return nil
+	}
+	_, err = q.store.Add(ctx, q.keyOf(route), Usage{Bytes: -size, Objects: -1})
+	return err
+}
+
+func (q *Quota) Copy(ctx context.Context, src, dst string, overwrite bool, conds ...storage.Precondition) error {
+	_, delta, err := q.copyDelta(ctx, src, dst, overwrites(overwrite, conds))
+	if err != nil {
+		return err
+	}
+	dstKey := q.keyOf(dst)
+	if _, err := q.store.AddWithin(ctx, dstKey, delta, q.limit()); err != nil {
+		return err
+	}
+	if err := q.IFile.Copy(ctx, src, dst, overwrite, conds...); err != nil {
+		return q.release(ctx, dstKey, delta, err)
+	}
+	return nil
+}
+
+func (q *Quota) Move(ctx context.Context, src, dst string, overwrite bool, conds ...storage.Precondition) error {
+	srcSize, delta, err := q.copyDelta(ctx, src, dst, overwrites(overwrite, conds))
+	if err != nil {
+		return err
+	}
+	srcKey, dstKey := q.keyOf(src), q.keyOf(dst)
+	srcDelta := Usage{Bytes: -srcSize, Objects: -1}
+	if srcKey == dstKey {
+		delta, srcDelta = Usage{Bytes: delta.Bytes + srcDelta.Bytes, Objects: delta.Objects + srcDelta.Objects}, Usage{}
+	}
+	if _, err := q.store.AddWithin(ctx, dstKey, delta, q.limit()); err != nil {
+		return err
+	}
+	if err := q.IFile.Move(ctx, src, dst, overwrite, conds...); err != nil {
+		return q.release(ctx, dstKey, delta, err)
+	}
+	_, err = q.store.Add(ctx, srcKey, srcDelta)
+	return err
+}
+
+// RestoreVersion accounts the size difference between the restored generation and
+// the live object, noncurrent versions are not counted.
+func (q *Quota) RestoreVersion(ctx context.Context, route string, generation int64, conds ...storage.Precondition) error {
+	liveSize, exist, err := q.stat(ctx, route)
+	if err != nil {
+		return err
+	}
+	var (
+		size  int64
+		found bool
+	)
+	if err := q.IFile.Versions(ctx, route, func(file storage.File) error {
+		if file.Generation() != generation {
+			return nil
+		}
+		s, err := file.Size()
+		if err != nil {
+			return err
+		}
+		size, found = s, true
+		return nil
+	}); err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%w: %s#%d", errorhandler.ErrVersionNotExist, route, generation)
+	}
+	delta := Usage{Bytes: size - liveSize}
+	if !exist {
+		delta.Objects = 1
+	}
+	key := q.keyOf(route)
+	if _, err := q.store.AddWithin(ctx, key, delta, q.limit()); err != nil {
+		return err
+	}
+	if err := q.IFile.RestoreVersion(ctx, route, generation, conds...); err != nil {
+		return q.release(ctx, key, delta, err)
+	}
+	return nil
+}
+
+// RemoveFolder frees the usage of the objects under the folder, a partial failure
+// leaves the usage drifted until Reconcile.
+func (q *Quota) RemoveFolder(ctx context.Context, route string, dryRun bool) (int, error) {
+	if dryRun {
+		return q.IFile.RemoveFolder(ctx, route, dryRun)
+	}
+	usages, err := q.folderUsages(ctx, route)
+	if err != nil {
+		return 0, err
+	}
+	count, err := q.IFile.RemoveFolder(ctx, route, dryRun)
+	if err != nil {
+		return count, err
+	}
+	for key, usage := range usages {
+		if _, err := q.store.Add(ctx, key, Usage{Bytes: -usage.Bytes, Objects: -usage.Objects}); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// RenameFolder moves the usage of the objects under src to the prefix of dst, a
+// partial failure leaves the usage drifted until Reconcile.
+func (q *Quota) RenameFolder(ctx context.Context, src, dst string, progress storage.ProgressFn) (int, error) {
+	usages, err := q.folderUsages(ctx, src)
+	if err != nil {
+		return 0, err
+	}
+	dstKey := q.keyOf(path.Join(dst, "_"))
+	var delta Usage
+	for key, usage := range usages {
+		if key != dstKey {
+			delta.Bytes += usage.Bytes
+			delta.Objects += usage.Objects
+		}
+	}
+	if _, err := q.store.AddWithin(ctx, dstKey, delta, q.limit()); err != nil {
+		return 0, err
+	}
+	count, err := q.IFile.RenameFolder(ctx, src, dst, progress)
+	if err != nil {
+		return count, q.release(ctx, dstKey, delta, err)
+	}
+	for key, usage := range usages {
+		if key == dstKey {
+			continue
+		}
+		if _, err := q.store.Add(ctx, key, Usage{Bytes: -usage.Bytes, Objects: -usage.Objects}); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// Usage reports the current usage and limits of the top level prefix of prefix.
+func (q *Quota) Usage(ctx context.Context, prefix string) (Report, error) {
+	key := Key(prefix)
+	usage, err := q.store.Get(ctx, key)
+	if err != nil {
+		return Report{}, err
+	}
+	return Report{
+		Prefix:     key,
+		Bytes:      usage.Bytes,
+		Objects:    usage.Objects,
+		MaxBytes:   q.env.QuotaMaxBytes,
+		MaxObjects: q.env.QuotaMaxObjects,
+	}, nil
+}
+
+// Reconcile recomputes every usage from the objects listed by the wrapped IFile,
+// fixing the drift left by failed writes or objects changed outside the service.
+func (q *Quota) Reconcile(ctx context.Context) (map[string]Usage, error) {
+	query := storage.Query{}
+	if q.media.PrefixPath != "" {
+		query = storage.WithFileCloudPrefix(query, q.media.PrefixPath)
+	}
+	usages := map[string]Usage{}
+	if err := q.IFile.List(ctx, query, func(file storage.File) error {
+		if _, _, exist := file.FolderInfo(); exist {
+			return nil
+		}
+		size, err := file.Size()
+		if err != nil {
+			return err
+		}
+		key := q.keyOf(file.Path())
+		usages[key] = usages[key].Add(Usage{Bytes: size, Objects: 1})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	stored, err := q.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for key := range stored {
+		if _, ok := usages[key]; !ok {
+			usages[key] = Usage{}
+		}
+	}
+	for key, usage := range usages {
+		if err := q.store.Set(ctx, key, usage); err != nil {
+			return nil, err
+		}
+	}
+	return usages, nil
+}
+
+func (q *Quota) stat(ctx context.Context, route string) (int64, bool, error) {
+	var (
+		size  int64
+		exist bool
+	)
+	err := q.IFile.List(ctx, storage.WithFileCloudPrefix(storage.Query{}, route), func(file storage.File) error {
+		if file.Path() != route {
+			return nil
+		}
+		s, err := file.Size()
+		if err != nil {
+			return err
+		}
+		size, exist = s, true
+		return nil
+	})
+	return size, exist, err
+}
+
+// folderUsages sums the objects under the folder at route by quota key.
+func (q *Quota) folderUsages(ctx context.Context, route string) (map[string]Usage, error) {
+	usages := map[string]Usage{}
+	prefix := strings.TrimSuffix(route, "/") + "/"
+	err := q.IFile.List(ctx, storage.WithFileCloudPrefix(storage.Query{}, prefix), func(file storage.File) error {
+		if _, _, exist := file.FolderInfo(); exist {
+			return nil
+		}
+		size, err := file.Size()
+		if err != nil {
+			return err
+		}
+		key := q.keyOf(file.Path())
+		usages[key] = usages[key].Add(Usage{Bytes: size, Objects: 1})
+		return nil
+	})
+	return usages, err
+}
+
+// copyDelta returns the size of src and the usage dst gains once src is copied over it.
+func (q *Quota) copyDelta(ctx context.Context, src, dst string, overwrite bool) (int64, Usage, error) {
+	srcSize, _, err := q.stat(ctx, src)
+	if err != nil {
+		return 0, Usage{}, err
+	}
+	delta := Usage{Bytes: srcSize, Objects: 1}
+	if !overwrite {
+		return srcSize, delta, nil
+	}
+	dstSize, exist, err := q.stat(ctx, dst)
+	if err != nil {
+		return 0, Usage{}, err
+	}
+	if exist {
+		delta = Usage{Bytes: srcSize - dstSize}
+	}
+	return srcSize, delta, nil
+}
+
+// overwrites reports whether a copy may replace an existing dst, a generation
+// precondition requires dst to exist.
+func overwrites(overwrite bool, conds []storage.Precondition) bool {
+	return overwrite || storage.MergePreconditions(conds...).GenerationMatch != 0
+}
+
+func (q *Quota) limit() Usage {
+	return Usage{Bytes: q.env.QuotaMaxBytes, Objects: q.env.QuotaMaxObjects}
+}
+
+// release takes back the delta added to key ahead of a write failed with err.
+func (q *Quota) release(ctx context.Context, key string, delta Usage, err error) error {
+	if _, errRelease := q.store.Add(ctx, key, Usage{Bytes: -delta.Bytes, Objects: -delta.Objects}); errRelease != nil {
+		return fmt.Errorf("%w: %s", err, errRelease.Error())
+	}
+	return err
+}
+
+func (q *Quota) keyOf(route string) string {
+	return Key(path.Dir(strings.TrimPrefix(route, q.media.PrefixPath)))
+}
+
+// Key returns the top level segment of prefix, the unit quotas are accounted by.
+func Key(prefix string) string {
+	p := strings.Trim(path.Clean("/"+prefix), "/")
+	if i := strings.Index(p, "/"); i >= 0 {
+		return p[:i]
+	}
+	return p
+}
+
+type limitReader struct {
+	io.ReadCloser
+	n        int64
+	limit    int64
+	exceeded bool
+}
+
+func (r *limitReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	if r.limit > 0 && r.n > r.limit {
+		r.exceeded = true
+		return n, errorhandler.ErrQuotaTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,477 @@
+package quota
+
+import (
+	"context"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/justdomepaul/gin-storage/storage/storagetest"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"strings"
+	"testing"
+)
+
+type QuotaSuite struct {
+	suite.Suite
+	ctx   context.Context
+	media config.Media
+}
+
+func (suite *QuotaSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.media = config.Media{PrefixPath: "/media/"}
+}
+
+func (suite *QuotaSuite) TestKey() {
+	suite.Equal("tenant", Key("tenant"))
+	suite.Equal("tenant", Key("/tenant/sub/"))
+	suite.Equal("", Key(""))
+	suite.Equal("", Key("."))
+}
+
+func (suite *QuotaSuite) TestUpload() {
+	testCases := []struct {
+		Label   string
+		Env     config.Quota
+		Usage   Usage
+		Content string
+		Want    error
+	}{
+		{
+			Label:   "Upload within quota",
+			Env:     config.Quota{QuotaMaxBytes: 10, QuotaMaxObjects: 2},
+			Content: "12345",
+		},
+		{
+			Label:   "Upload without limit",
+			Content: "12345",
+		},
+		{
+			Label:   "Upload over object count",
+			Env:     config.Quota{QuotaMaxObjects: 1},
+			Usage:   Usage{Bytes: 1, Objects: 1},
+			Content: "12345",
+			Want:    errorhandler.ErrQuotaExceeded,
+		},
+		{
+			Label:   "Upload larger than remaining bytes",
+			Env:     config.Quota{QuotaMaxBytes: 8},
+			Usage:   Usage{Bytes: 5, Objects: 1},
+			Content: "12345",
+			Want:    errorhandler.ErrQuotaTooLarge,
+		},
+	}
+	for _, tc := range testCases {
+		store := NewMemoryStore()
+		suite.NoError(store.Set(suite.ctx, "tenant", tc.Usage))
+		file := &storagetest.IFile{}
+		file.On("Upload", mock.Anything, "tenant/sub", mock.Anything).Return("/media/tenant/sub/uuid", nil)
+
+		pt, err := NewQuota(suite.media, tc.Env, file, store).Upload(suite.ctx, "tenant/sub", io.NopCloser(strings.NewReader(tc.Content)))
+		usage, errGet := store.Get(suite.ctx, "tenant")
+		suite.NoError(errGet)
+		if tc.Want != nil {
+			suite.ErrorIs(err, tc.Want, tc.Label)
+			suite.Equal(tc.Usage, usage, tc.Label)
+			continue
+		}
+		suite.NoError(err, tc.Label)
+		suite.Equal("/media/tenant/sub/uuid", pt, tc.Label)
+		suite.Equal(Usage{Bytes: tc.Usage.Bytes + 5, Objects: tc.Usage.Objects + 1}, usage, tc.Label)
+	}
+}
+
+func (suite *QuotaSuite) TestUploadOverwrite() {
+	testCases := []struct {
+		Label   string
+		Env     config.Quota
+		Live    []storage.File
+		Content string
+		Want    Usage
+		Error   error
+	}{
+		{
+			Label:   "Overwrite replaces usage",
+			Env:     config.Quota{QuotaMaxBytes: 10, QuotaMaxObjects: 2},
+			Live:    []storage.File{storagetest.File{Route: "/media/tenant/name", Bytes: 4}},
+			Content: "123456",
+			Want:    Usage{Bytes: 10, Objects: 2},
+		},
+		{
+			Label:   "Overwrite at object count",
+			Env:     config.Quota{QuotaMaxObjects: 2},
+			Live:    []storage.File{storagetest.File{Route: "/media/tenant/name", Bytes: 4}},
+			Content: "12",
+			Want:    Usage{Bytes: 6, Objects: 2},
+		},
+		{
+			Label:   "Named upload at object count",
+			Env:     config.Quota{QuotaMaxObjects: 2},
+			Live:    []storage.File{},
+			Content: "12",
+			Want:    Usage{Bytes: 8, Objects: 2},
+			Error:   errorhandler.ErrQuotaExceeded,
+		},
+	}
+	for _, tc := range testCases {
+		store := NewMemoryStore()
+		suite.NoError(store.Set(suite.ctx, "tenant", Usage{Bytes: 8, Objects: 2}))
+		file := &storagetest.IFile{}
+		file.On("List", mock.Anything, storage.WithFileCloudPrefix(storage.Query{}, "/media/tenant/name"), mock.Anything).Return(tc.Live, nil)
+		file.On("Upload", mock.Anything, "tenant", mock.Anything).Return("/media/tenant/name", nil)
+
+		_, err := NewQuota(suite.media, tc.Env, file, store).Upload(suite.ctx, "tenant", io.NopCloser(strings.NewReader(tc.Content)), storage.UploadAttrs{Name: "name"})
+		if tc.Error != nil {
+			suite.ErrorIs(err, tc.Error, tc.Label)
+		} else {
+			suite.NoError(err, tc.Label)
+		}
+		usage, err := store.Get(suite.ctx, "tenant")
+		suite.NoError(err)
+		suite.Equal(tc.Want, usage, tc.Label)
+	}
+}
+
+// racingStore lets another upload take the quota between the check and the add of an upload.
+type racingStore struct {
+	*MemoryStore
+	race Usage
+}
+
+func (r *racingStore) AddWithin(ctx context.Context, key string, delta, limit Usage) (Usage, error) {
+	if _, err := r.MemoryStore.Add(ctx, key, r.race); err != nil {
+		return Usage{}, err
+	}
+	return r.MemoryStore.AddWithin(ctx, key, delta, limit)
+}
+
+func (suite *QuotaSuite) TestUploadRaced() {
+	store := &racingStore{MemoryStore: NewMemoryStore(), race: Usage{Bytes: 5, Objects: 1}}
+	file := &storagetest.IFile{}
+	file.On("Upload", mock.Anything, "tenant", mock.Anything).Return("/media/tenant/uuid", nil)
+	file.On("Remove", mock.Anything, "/media/tenant/uuid").Return(nil)
+
+	_, err := NewQuota(suite.media, config.Quota{QuotaMaxBytes: 8}, file, store).Upload(suite.ctx, "tenant", io.NopCloser(strings.NewReader("12345")))
+	suite.ErrorIs(err, errorhandler.ErrQuotaTooLarge)
+	file.AssertCalled(suite.T(), "Remove", mock.Anything, "/media/tenant/uuid")
+	usage, err := store.Get(suite.ctx, "tenant")
+	suite.NoError(err)
+	suite.Equal(Usage{Bytes: 5, Objects: 1}, usage)
+}
+
+func (suite *QuotaSuite) TestUploadKeyedByPath() {
+	store := NewMemoryStore()
+	file := &storagetest.IFile{}
+	file.On("Upload", mock.Anything, "tenant", mock.Anything).Return("/media/other/uuid", nil)
+
+	_, err := NewQuota(suite.media, config.Quota{}, file, store).Upload(suite.ctx, "tenant", io.NopCloser(strings.NewReader("12345")))
+	suite.NoError(err)
+	usage, err := store.Get(suite.ctx, "other")
+	suite.NoError(err)
+	suite.Equal(Usage{Bytes: 5, Objects: 1}, usage)
+	usage, err = store.Get(suite.ctx, "tenant")
+	suite.NoError(err)
+	suite.Equal(Usage{}, usage)
+}
+
+func (suite *QuotaSuite) TestRemove() {
+	store := NewMemoryStore()
+	suite.NoError(store.Set(suite.ctx, "tenant", Usage{Bytes: 12, Objects: 2}))
+	file := &storagetest.IFile{}
+	file.On("List", mock.Anything, storage.WithFileCloudPrefix(storage.Query{}, "/media/tenant/uuid"), mock.Anything).
+		Return([]storage.File{storagetest.File{Route: "/media/tenant/uuid", Bytes: 5}}, nil)
+	file.On("Remove", mock.Anything, "/media/tenant/uuid").Return(nil)
+
+	suite.NoError(NewQuota(suite.media, config.Quota{}, file, store).Remove(suite.ctx, "/media/tenant/uuid"))
+	usage, err := store.Get(suite.ctx, "tenant")
+	suite.NoError(err)
+	suite.Equal(Usage{Bytes: 7, Objects: 1}, usage)
+}
+
+func (suite *QuotaSuite) TestRemoveError() {
+	store := NewMemoryStore()
+	suite.NoError(store.Set(suite.ctx, "tenant", Usage{Bytes: 12, Objects: 2}))
+	file := &storagetest.IFile{}
+	file.On("List", mock.Anything, mock.Anything, mock.Anything).
+		Return([]storage.File{storagetest.File{Route: "/media/tenant/uuid", Bytes: 5}}, nil)
+	file.On("Remove", mock.Anything, "/media/tenant/uuid").Return(errorhandler.ErrFileRemove)
+
+	suite.ErrorIs(NewQuota(suite.media, config.Quota{}, file, store).Remove(suite.ctx, "/media/tenant/uuid"), errorhandler.ErrFileRemove)
+	usage, err := store.Get(suite.ctx, "tenant")
+	suite.NoError(err)
+	suite.Equal(Usage{Bytes: 12, Objects: 2}, usage)
+}
+
+func (suite *QuotaSuite) TestTransfer() {
+	testCases := []struct {
+		Label      string
+		Method     string
+		Dst        string
+		Overwrite  bool
+		Env        config.Quota
+		WriteError error
+		Want       map[string]Usage
+		Error      error
+	}{
+		{
+			Label:  "Copy into other prefix",
+			Method: "Copy",
+			Dst:    "/media/other/b",
+			Want:   map[string]Usage{"tenant": {Bytes: 12, Objects: 2}, "other": {Bytes: 9, Objects: 2}},
+		},
+		{
+			Label:     "Copy over existing",
+			Method:    "Copy",
+			Dst:       "/media/other/b",
+			Overwrite: true,
+			Want:      map[string]Usage{"tenant": {Bytes: 12, Objects: 2}, "other": {Bytes: 5, Objects: 1}},
+		},
+		{
+			Label:  "Move into other prefix",
+			Method: "Move",
+			Dst:    "/media/other/b",
+			Want:   map[string]Usage{"tenant": {Bytes: 7, Objects: 1}, "other": {Bytes: 9, Objects: 2}},
+		},
+		{
+			Label:  "Move within prefix",
+			Method: "Move",
+			Dst:    "/media/tenant/b",
+			Want:   map[string]Usage{"tenant": {Bytes: 12, Objects: 2}, "other": {Bytes: 4, Objects: 1}},
+		},
+		{
+			Label:  "Copy over quota",
+			Method: "Copy",
+			Dst:    "/media/other/b",
+			Env:    config.Quota{QuotaMaxBytes: 8},
+			Want:   map[string]Usage{"tenant": {Bytes: 12, Objects: 2}, "other": {Bytes: 4, Objects: 1}},
+			Error:  errorhandler.ErrQuotaTooLarge,
+		},
+		{
+			Label:      "Copy failed",
+			Method:     "Copy",
+			Dst:        "/media/other/b",
+			WriteError: errorhandler.ErrFileCopy,
+			Want:       map[string]Usage{"tenant": {Bytes: 12, Objects: 2}, "other": {Bytes: 4, Objects: 1}},
+			Error:      errorhandler.ErrFileCopy,
+		},
+		{
+			Label:  "Move over object count",
+			Method: "Move",
+			Dst:    "/media/other/b",
+			Env:    config.Quota{QuotaMaxObjects: 1},
+			Want:   map[string]Usage{"tenant": {Bytes: 12, Objects: 2}, "other": {Bytes: 4, Objects: 1}},
+			Error:  errorhandler.ErrQuotaExceeded,
+		},
+	}
+	for _, tc := range testCases {
+		store := NewMemoryStore()
+		suite.NoError(store.Set(suite.ctx, "tenant", Usage{Bytes: 12, Objects: 2}))
+		suite.NoError(store.Set(suite.ctx, "other", Usage{Bytes: 4, Objects: 1}))
+		file := &storagetest.IFile{}
+		file.On("List", mock.Anything, storage.WithFileCloudPrefix(storage.Query{}, "/media/tenant/a"), mock.Anything).
+			Return([]storage.File{storagetest.File{Route: "/media/tenant/a", Bytes: 5}}, nil)
+		file.On("List", mock.Anything, storage.WithFileCloudPrefix(storage.Query{}, tc.Dst), mock.Anything).
+			Return([]storage.File{storagetest.File{Route: "/media/other/b", Bytes: 4}}, nil)
+		file.On(tc.Method, mock.Anything, "/media/tenant/a", tc.Dst, tc.Overwrite).Return(tc.WriteError)
+
+		q := NewQuota(suite.media, tc.Env, file, store)
+		var err error
+		if tc.Method == "Copy" {
+			err = q.Copy(suite.ctx, "/media/tenant/a", tc.Dst, tc.Overwrite)
+		} else {
+			err = q.Move(suite.ctx, "/media/tenant/a", tc.Dst, tc.Overwrite)
+		}
+		if tc.Error != nil && tc.WriteError == nil {
+			suite.ErrorIs(err, tc.Error, tc.Label)
+			file.AssertNotCalled(suite.T(), tc.Method, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		} else if tc.Error != nil {
+			suite.ErrorIs(err, tc.Error, tc.Label)
+		} else {
+			suite.NoError(err, tc.Label)
+		}
+		usages, err := store.List(suite.ctx)
+		suite.NoError(err)
+		suite.Equal(tc.Want, usages, tc.Label)
+	}
+}
+
+func (suite *QuotaSuite) TestRestoreVersion() {
+	testCases := []struct {
+		Label      string
+		Live       []storage.File
+		Generation int64
+		Env        config.Quota
+		Want       Usage
+		Error      error
+	}{
+		{
+			Label:      "Restore over live",
+			Live:       []storage.File{storagetest.File{Route: "/media/tenant/a", Bytes: 5, Gen: 2}},
+			Generation: 1,
+			Want:       Usage{Bytes: 10, Objects: 2},
+		},
+		{
+			Label:      "Restore deleted object",
+			Live:       []storage.File{},
+			Generation: 1,
+			Want:       Usage{Bytes: 15, Objects: 3},
+		},
+		{
+			Label:      "Restore over quota",
+			Live:       []storage.File{},
+			Generation: 1,
+			Env:        config.Quota{QuotaMaxObjects: 2},
+			Want:       Usage{Bytes: 12, Objects: 2},
+			Error:      errorhandler.ErrQuotaExceeded,
+		},
+		{
+			Label:      "Restore unknown generation",
+			Live:       []storage.File{},
+			Generation: 9,
+			Want:       Usage{Bytes: 12, Objects: 2},
+			Error:      errorhandler.ErrVersionNotExist,
+		},
+	}
+	for _, tc := range testCases {
+		store := NewMemoryStore()
+		suite.NoError(store.Set(suite.ctx, "tenant", Usage{Bytes: 12, Objects: 2}))
+		file := &storagetest.IFile{}
+		file.On("List", mock.Anything, storage.WithFileCloudPrefix(storage.Query{}, "/media/tenant/a"), mock.Anything).Return(tc.Live, nil)
+		file.On("Versions", mock.Anything, "/media/tenant/a", mock.Anything).Return([]storage.File{
+			storagetest.File{Route: "/media/tenant/a", Bytes: 3, Gen: 1},
+			storagetest.File{Route: "/media/tenant/a", Bytes: 5, Gen: 2},
+		}, nil)
+		file.On("RestoreVersion", mock.Anything, "/media/tenant/a", tc.Generation).Return(nil)
+
+		err := NewQuota(suite.media, tc.Env, file, store).RestoreVersion(suite.ctx, "/media/tenant/a", tc.Generation)
+		usage, errGet := store.Get(suite.ctx, "tenant")
+		suite.NoError(errGet)
+		suite.Equal(tc.Want, usage, tc.Label)
+		if tc.Error != nil {
+			suite.ErrorIs(err, tc.Error, tc.Label)
+			file.AssertNotCalled(suite.T(), "RestoreVersion", mock.Anything, mock.Anything, mock.Anything)
+			continue
+		}
+		suite.NoError(err, tc.Label)
+	}
+}
+
+func (suite *QuotaSuite) TestRemoveFolder() {
+	for _, dryRun := range []bool{false, true} {
+		store := NewMemoryStore()
+		suite.NoError(store.Set(suite.ctx, "tenant", Usage{Bytes: 12, Objects: 3}))
+		file := &storagetest.IFile{}
+		file.On("List", mock.Anything, storage.WithFileCloudPrefix(storage.Query{}, "/media/tenant/sub/"), mock.Anything).
+			Return([]storage.File{storagetest.File{Route: "/media/tenant/sub/a", Bytes: 5}, storagetest.File{Route: "/media/tenant/sub/b", Bytes: 4}}, nil)
+		file.On("RemoveFolder", mock.Anything, "/media/tenant/sub", dryRun).Return(2, nil)
+
+		count, err := NewQuota(suite.media, config.Quota{}, file, store).RemoveFolder(suite.ctx, "/media/tenant/sub", dryRun)
+		suite.NoError(err)
+		suite.Equal(2, count)
+		usage, err := store.Get(suite.ctx, "tenant")
+		suite.NoError(err)
+		if dryRun {
+			suite.Equal(Usage{Bytes: 12, Objects: 3}, usage)
+		} else {
+			suite.Equal(Usage{Bytes: 3, Objects: 1}, usage)
+		}
+	}
+}
+
+func (suite *QuotaSuite) TestRenameFolder() {
+	testCases := []struct {
+		Label string
+		Dst   string
+		Env   config.Quota
+		Want  map[string]Usage
+		Error error
+	}{
+		{
+			Label: "Rename folder into other prefix",
+			Dst:   "/media/other/sub",
+			Want:  map[string]Usage{"tenant": {Bytes: 3, Objects: 1}, "other": {Bytes: 10, Objects: 3}},
+		},
+		{
+			Label: "Rename folder within prefix",
+			Dst:   "/media/tenant/moved",
+			Want:  map[string]Usage{"tenant": {Bytes: 12, Objects: 3}, "other": {Bytes: 1, Objects: 1}},
+		},
+		{
+			Label: "Rename folder over quota",
+			Dst:   "/media/other/sub",
+			Env:   config.Quota{QuotaMaxObjects: 2},
+			Want:  map[string]Usage{"tenant": {Bytes: 12, Objects: 3}, "other": {Bytes: 1, Objects: 1}},
+			Error: errorhandler.ErrQuotaExceeded,
+		},
+	}
+	for _, tc := range testCases {
+		store := NewMemoryStore()
+		suite.NoError(store.Set(suite.ctx, "tenant", Usage{Bytes: 12, Objects: 3}))
+		suite.NoError(store.Set(suite.ctx, "other", Usage{Bytes: 1, Objects: 1}))
+		file := &storagetest.IFile{}
+		file.On("List", mock.Anything, storage.WithFileCloudPrefix(storage.Query{}, "/media/tenant/sub/"), mock.Anything).
+			Return([]storage.File{storagetest.File{Route: "/media/tenant/sub/a", Bytes: 5}, storagetest.File{Route: "/media/tenant/sub/b", Bytes: 4}}, nil)
+		file.On("RenameFolder", mock.Anything, "/media/tenant/sub", tc.Dst, mock.Anything).Return(2, nil)
+
+		_, err := NewQuota(suite.media, tc.Env, file, store).RenameFolder(suite.ctx, "/media/tenant/sub", tc.Dst, nil)
+		if tc.Error != nil {
+			suite.ErrorIs(err, tc.Error, tc.Label)
+		} else {
+			suite.NoError(err, tc.Label)
+		}
+		usages, err := store.List(suite.ctx)
+		suite.NoError(err)
+		suite.Equal(tc.Want, usages, tc.Label)
+	}
+}
+
+func (suite *QuotaSuite) TestUsage() {
+	store := NewMemoryStore()
+	suite.NoError(store.Set(suite.ctx, "tenant", Usage{Bytes: 12, Objects: 2}))
+	report, err := NewQuota(suite.media, config.Quota{QuotaMaxBytes: 100}, &storagetest.IFile{}, store).Usage(suite.ctx, "tenant/sub")
+	suite.NoError(err)
+	suite.Equal(Report{Prefix: "tenant", Bytes: 12, Objects: 2, MaxBytes: 100}, report)
+}
+
+func (suite *QuotaSuite) TestReconcile() {
+	store := NewMemoryStore()
+	suite.NoError(store.Set(suite.ctx, "tenant", Usage{Bytes: 1, Objects: 1}))
+	suite.NoError(store.Set(suite.ctx, "gone", Usage{Bytes: 3, Objects: 3}))
+	file := &storagetest.IFile{}
+	file.On("List", mock.Anything, storage.WithFileCloudPrefix(storage.Query{}, "/media/"), mock.Anything).
+		Return([]storage.File{
+			storagetest.File{Route: "/media/tenant/a", Bytes: 5},
+			storagetest.File{Route: "/media/tenant/sub/b", Bytes: 6},
+			storagetest.File{Route: "/media/other/c", Bytes: 7},
+			storagetest.File{Route: "/media/d", Bytes: 8},
+		}, nil)
+
+	usages, err := NewQuota(suite.media, config.Quota{}, file, store).Reconcile(suite.ctx)
+	suite.NoError(err)
+	suite.Equal(map[string]Usage{
+		"tenant": {Bytes: 11, Objects: 2},
+		"other":  {Bytes: 7, Objects: 1},
+		"":       {Bytes: 8, Objects: 1},
+		"gone":   {},
+	}, usages)
+	stored, err := store.List(suite.ctx)
+	suite.NoError(err)
+	suite.Equal(usages, stored)
+}
+
+func (suite *QuotaSuite) TestUnwrap() {
+	file := &storagetest.IFile{}
+	q := NewQuota(suite.media, config.Quota{}, file, NewMemoryStore())
+	suite.Equal(file, q.Unwrap())
+	found, ok := storage.Unwrap[*Quota](q)
+	suite.True(ok)
+	suite.Equal(q, found)
+	_, ok = storage.Unwrap[*Quota](file)
+	suite.False(ok)
+}
+
+func TestQuotaSuite(t *testing.T) {
+	suite.Run(t, new(QuotaSuite))
+}
@@ -0,0 +1,182 @@
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"os"
+	"sync"
+)
+
+type Usage struct {
+	Bytes   int64 `json:"bytes"`
+	Objects int64 `json:"objects"`
+}
+
+func (u Usage) Add(delta Usage) Usage {
+	u.Bytes += delta.Bytes
+	u.Objects += delta.Objects
+	if u.Bytes < 0 {
+		u.Bytes = 0
+	}
+	if u.Objects < 0 {
+		u.Objects = 0
+	}
+	return u
+}
+
+// Within fails when adding delta goes over limit, a zero limit is unbounded and only
+// the growing parts of delta are checked.
+func (u Usage) Within(key string, delta, limit Usage) error {
+	if limit.Objects > 0 && delta.Objects > 0 && u.Objects+delta.Objects > limit.Objects {
+		return fmt.Errorf("%w: %s", errorhandler.ErrQuotaExceeded, key)
+	}
+	if limit.Bytes > 0 && delta.Bytes > 0 && u.Bytes+delta.Bytes > limit.Bytes {
+		return fmt.Errorf("%w: %s", errorhandler.ErrQuotaTooLarge, key)
+	}
+	return nil
+}
+
+type IUsageStore interface {
+	Get(ctx context.Context, key string) (Usage, error)
+	Add(ctx context.Context, key string, delta Usage) (Usage, error)
+	// AddWithin checks delta against limit as Usage.Within does and adds it in the same step.
+	AddWithin(ctx context.Context, key string, delta, limit Usage) (Usage, error)
+	Set(ctx context.Context, key string, usage Usage) error
+	List(ctx context.Context) (map[string]Usage, error)
+}
+
+// NewMemoryStore method
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		usages: map[string]Usage{},
+	}
+}
+
+type MemoryStore struct {
+	mu     sync.RWMutex
+	usages map[string]Usage
+}
+
+func (m *MemoryStore) Get(ctx context.Context, key string) (Usage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.usages[key], nil
+}
+
+func (m *MemoryStore) Add(ctx context.Context, key string, delta Usage) (Usage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usages[key] = m.usages[key].Add(delta)
+	return m.usages[key], nil
+}
+
+func (m *MemoryStore) AddWithin(ctx context.Context, key string, delta, limit Usage) (Usage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.usages[key].Within(key, delta, limit); err != nil {
+		return m.usages[key], err
+	}
+	m.usages[key] = m.usages[key].Add(delta)
+	return m.usages[key], nil
+}
+
+func (m *MemoryStore) Set(ctx context.Context, key string, usage Usage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usages[key] = usage
+	return nil
+}
+
+func (m *MemoryStore) List(ctx context.Context) (map[string]Usage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	usages := make(map[string]Usage, len(m.usages))
+	for key, usage := range m.usages {
+		usages[key] = usage
+	}
+	return usages, nil
+}
+
+// NewFileStore loads the usages persisted at route, starting empty if the file does not exist yet.
+func NewFileStore(route string) (*FileStore, error) {
+	fs := &FileStore{
+		route:  route,
+		memory: NewMemoryStore(),
+	}
+	b, err := os.ReadFile(route)
+	if os.IsNotExist(err) {
+		return fs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrUsageStore, err.Error())
+	}
+	if len(b) == 0 {
+		return fs, nil
+	}
+	if err := json.Unmarshal(b, &fs.memory.usages); err != nil {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrUsageStore, err.Error())
+	}
+	return fs, nil
+}
+
+// FileStore keeps usages in memory and writes them through to a JSON file on every change.
+type FileStore struct {
+	mu     sync.Mutex
+	route  string
+	memory *MemoryStore
+}
+
+func (f *FileStore) Get(ctx context.Context, key string) (Usage, error) {
+	return f.memory.Get(ctx, key)
+}
+
+func (f *FileStore) Add(ctx context.Context, key string, delta Usage) (Usage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	usage, err := f.memory.Add(ctx, key, delta)
+	if err != nil {
+		return usage, err
+	}
+	return usage, f.flush(ctx)
+}
+
+func (f *FileStore) AddWithin(ctx context.Context, key string, delta, limit Usage) (Usage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	usage, err := f.memory.AddWithin(ctx, key, delta, limit)
+	if err != nil {
+		return usage, err
+	}
+	return usage, f.flush(ctx)
+}
+
+func (f *FileStore) Set(ctx context.Context, key string, usage Usage) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.memory.Set(ctx, key, usage); err != nil {
+		return err
+	}
+	return f.flush(ctx)
+}
+
+func (f *FileStore) List(ctx context.Context) (map[string]Usage, error) {
+	return f.memory.List(ctx)
+}
+
+func (f *FileStore) flush(ctx context.Context) error {
+	usages, err := f.memory.List(ctx)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(usages)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errorhandler.ErrUsageStore, err.Error())
+	}
+	if err := storage.WriteFileAtomic(f.route, b); err != nil {
+		return fmt.Errorf("%w: %s", errorhandler.ErrUsageStore, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package quota
+
+import (
+	"context"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type StoreSuite struct {
+	suite.Suite
+	ctx context.Context
+}
+
+func (suite *StoreSuite) SetupTest() {
+	suite.ctx = context.Background()
+}
+
+func (suite *StoreSuite) TestMemoryStore() {
+	store := NewMemoryStore()
+	usage, err := store.Add(suite.ctx, "tenant", Usage{Bytes: 10, Objects: 1})
+	suite.NoError(err)
+	suite.Equal(Usage{Bytes: 10, Objects: 1}, usage)
+	usage, err = store.Add(suite.ctx, "tenant", Usage{Bytes: -20, Objects: -2})
+	suite.NoError(err)
+	suite.Equal(Usage{}, usage)
+	suite.NoError(store.Set(suite.ctx, "other", Usage{Bytes: 3, Objects: 1}))
+	usages, err := store.List(suite.ctx)
+	suite.NoError(err)
+	suite.Equal(map[string]Usage{"tenant": {}, "other": {Bytes: 3, Objects: 1}}, usages)
+}
+
+func (suite *StoreSuite) TestAddWithin() {
+	store := NewMemoryStore()
+	limit := Usage{Bytes: 10, Objects: 2}
+	usage, err := store.AddWithin(suite.ctx, "tenant", Usage{Bytes: 6, Objects: 1}, limit)
+	suite.NoError(err)
+	suite.Equal(Usage{Bytes: 6, Objects: 1}, usage)
+	_, err = store.AddWithin(suite.ctx, "tenant", Usage{Bytes: 5, Objects: 1}, limit)
+	suite.ErrorIs(err, errorhandler.ErrQuotaTooLarge)
+	_, err = store.AddWithin(suite.ctx, "tenant", Usage{Bytes: 1, Objects: 2}, limit)
+	suite.ErrorIs(err, errorhandler.ErrQuotaExceeded)
+	usage, err = store.AddWithin(suite.ctx, "tenant", Usage{Bytes: -2, Objects: 1}, Usage{Bytes: 1, Objects: 2})
+	suite.NoError(err)
+	suite.Equal(Usage{Bytes: 4, Objects: 2}, usage)
+}
+
+func (suite *StoreSuite) TestFileStore() {
+	route := filepath.Join(suite.T().TempDir(), "usage.json")
+	store, err := NewFileStore(route)
+	suite.NoError(err)
+	_, err = store.Add(suite.ctx, "tenant", Usage{Bytes: 10, Objects: 1})
+	suite.NoError(err)
+	suite.NoError(store.Set(suite.ctx, "other", Usage{Bytes: 3, Objects: 1}))
+	_, err = store.AddWithin(suite.ctx, "other", Usage{Bytes: 2, Objects: 1}, Usage{Objects: 2})
+	suite.NoError(err)
+
+	reloaded, err := NewFileStore(route)
+	suite.NoError(err)
+	usage, err := reloaded.Get(suite.ctx, "tenant")
+	suite.NoError(err)
+	suite.Equal(Usage{Bytes: 10, Objects: 1}, usage)
+	usages, err := reloaded.List(suite.ctx)
+	suite.NoError(err)
+	suite.Equal(map[string]Usage{"tenant": {Bytes: 10, Objects: 1}, "other": {Bytes: 5, Objects: 2}}, usages)
+}
+
+func (suite *StoreSuite) TestFileStoreCorrupted() {
+	route := filepath.Join(suite.T().TempDir(), "usage.json")
+	suite.NoError(os.WriteFile(route, []byte("{"), 0o600))
+	_, err := NewFileStore(route)
+	suite.Error(err)
+}
+
+func TestStoreSuite(t *testing.T) {
+	suite.Run(t, new(StoreSuite))
+}
@@ -0,0 +1,105 @@
+package scan
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// Result is the verdict of a scan, Signature names the match of an infected file.
+type Result struct {
+	Infected  bool
+	Signature string
+}
+
+// IScanner scans a stream of bytes.
+type IScanner interface {
+	Scan(ctx context.Context, r io.Reader) (Result, error)
+}
+
+// NewClamd method
+func NewClamd(network, address string, timeout time.Duration, chunkSize int) *Clamd {
+	return &Clamd{
+		network:   network,
+		address:   address,
+		timeout:   timeout,
+		chunkSize: chunkSize,
+	}
+}
+
+// Clamd scans through a clamd daemon over TCP or a Unix socket by the INSTREAM command.
+type Clamd struct {
+	network   string
+	address   string
+	timeout   time.Duration
+	chunkSize int
+}
+
+func (c *Clamd) Scan(ctx context.Context, r io.Reader) (Result, error) {
+	dialer := net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return Result{}, fmt.Errorf("%w: %s", errorhandler.ErrScan, err.Error())
+	}
+	defer conn.Close()
+	if c.timeout > 0 {
+		if err := conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+			return Result{}, fmt.Errorf("%w: %s", errorhandler.ErrScan, err.Error())
+		}
+	}
+	// clamd may close the stream early, e.g. over its StreamMaxLength, its reply tells why
+	streamErr := c.stream(conn, r)
+	line, err := bufio.NewReader(conn).ReadString(0)
+	if line == "" {
+		if streamErr != nil {
+			return Result{}, streamErr
+		}
+		return Result{}, fmt.Errorf("%w: %s", errorhandler.ErrScan, err.Error())
+	}
+	return parseReply(line)
+}
+
+// stream sends r as length prefixed chunks ended by an empty chunk.
+func (c *Clamd) stream(conn net.Conn, r io.Reader) error {
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return fmt.Errorf("%w: %s", errorhandler.ErrScan, err.Error())
+	}
+	chunk := make([]byte, 4+c.chunkSize)
+	for {
+		n, err := io.ReadFull(r, chunk[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(chunk[:4], uint32(n))
+			if _, err := conn.Write(chunk[:4+n]); err != nil {
+				return fmt.Errorf("%w: %s", errorhandler.ErrScan, err.Error())
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %s", errorhandler.ErrScan, err.Error())
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return fmt.Errorf("%w: %s", errorhandler.ErrScan, err.Error())
+	}
+	return nil
+}
+
+// parseReply parses "stream: OK", "stream: <signature> FOUND" or "<message> ERROR".
+func parseReply(line string) (Result, error) {
+	line = strings.TrimSpace(strings.TrimRight(line, "\x00"))
+	switch {
+	case line == "stream: OK":
+		return Result{}, nil
+	case strings.HasSuffix(line, " FOUND"):
+		return Result{Infected: true, Signature: strings.TrimSuffix(strings.TrimPrefix(line, "stream: "), " FOUND")}, nil
+	}
+	return Result{}, fmt.Errorf("%w: %s", errorhandler.ErrScan, line)
+}
@@ -0,0 +1,155 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClamd answers INSTREAM commands from the content it receives.
+type fakeClamd struct {
+	listener net.Listener
+	chunks   chan int
+}
+
+func newFakeClamd(network, address string) (*fakeClamd, error) {
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	clamd := &fakeClamd{listener: listener, chunks: make(chan int, 100)}
+	go clamd.serve()
+	return clamd, nil
+}
+
+func (f *fakeClamd) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeClamd) handle(conn net.Conn) {
+	defer conn.Close()
+	command := make([]byte, len("zINSTREAM\x00"))
+	if _, err := io.ReadFull(conn, command); err != nil || string(command) != "zINSTREAM\x00" {
+		conn.Write([]byte("UNKNOWN COMMAND\x00"))
+		return
+	}
+	var content bytes.Buffer
+	chunks := 0
+	for {
+		size := make([]byte, 4)
+		if _, err := io.ReadFull(conn, size); err != nil {
+			return
+		}
+		n := binary.BigEndian.Uint32(size)
+		if n == 0 {
+			break
+		}
+		chunks++
+		if _, err := io.CopyN(&content, conn, int64(n)); err != nil {
+			return
+		}
+	}
+	f.chunks <- chunks
+	switch {
+	case strings.Contains(content.String(), "EICAR"):
+		conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+	case strings.Contains(content.String(), "ENCRYPTED"):
+		conn.Write([]byte("stream: Heuristics.Encrypted.PDF FOUND\x00"))
+	case strings.Contains(content.String(), "TOO-BIG"):
+		conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+	default:
+		conn.Write([]byte("stream: OK\x00"))
+	}
+}
+
+func (f *fakeClamd) Close() error { return f.listener.Close() }
+
+type ClamdSuite struct {
+	suite.Suite
+	clamd *fakeClamd
+}
+
+func (suite *ClamdSuite) SetupTest() {
+	clamd, err := newFakeClamd("tcp", "127.0.0.1:0")
+	suite.NoError(err)
+	suite.clamd = clamd
+}
+
+func (suite *ClamdSuite) TearDownTest() {
+	suite.NoError(suite.clamd.Close())
+}
+
+func (suite *ClamdSuite) TestScan() {
+	testCases := []struct {
+		Label   string
+		Content string
+		Result  Result
+		Chunks  int
+		Error   error
+	}{
+		{
+			Label:   "Scan clean",
+			Content: "hello world",
+			Chunks:  3,
+		},
+		{
+			Label:   "Scan infected",
+			Content: "X5O!P%@AP EICAR",
+			Result:  Result{Infected: true, Signature: "Eicar-Test-Signature"},
+			Chunks:  4,
+		},
+		{
+			Label:   "Scan empty",
+			Content: "",
+		},
+		{
+			Label:   "Scan over clamd limit",
+			Content: "TOO-BIG",
+			Chunks:  2,
+			Error:   errorhandler.ErrScan,
+		},
+	}
+	clamd := NewClamd("tcp", suite.clamd.listener.Addr().String(), time.Second, 4)
+	for _, tc := range testCases {
+		result, err := clamd.Scan(context.Background(), strings.NewReader(tc.Content))
+		suite.ErrorIs(err, tc.Error, tc.Label)
+		suite.Equal(tc.Result, result, tc.Label)
+		suite.Equal(tc.Chunks, <-suite.clamd.chunks, tc.Label)
+	}
+}
+
+func (suite *ClamdSuite) TestScanUnix() {
+	clamd, err := newFakeClamd("unix", filepath.Join(suite.T().TempDir(), "clamd.sock"))
+	suite.NoError(err)
+	defer clamd.Close()
+
+	result, err := NewClamd("unix", clamd.listener.Addr().String(), time.Second, 1024).Scan(context.Background(), strings.NewReader("EICAR"))
+	suite.NoError(err)
+	suite.Equal(Result{Infected: true, Signature: "Eicar-Test-Signature"}, result)
+}
+
+func (suite *ClamdSuite) TestScanUnreachable() {
+	address := suite.clamd.listener.Addr().String()
+	suite.NoError(suite.clamd.Close())
+	_, err := NewClamd("tcp", address, time.Second, 1024).Scan(context.Background(), strings.NewReader("hello"))
+	suite.ErrorIs(err, errorhandler.ErrScan)
+	suite.clamd, _ = newFakeClamd("tcp", "127.0.0.1:0")
+}
+
+func TestClamdSuite(t *testing.T) {
+	suite.Run(t, new(ClamdSuite))
+}
@@ -0,0 +1,89 @@
+package scan
+
+import (
+	"context"
+	"fmt"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/kelseyhightower/envconfig"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// SignatureKey is the metadata of a quarantined object naming the signature it matched.
+const SignatureKey = "scan_signature"
+
+// FromEnv wraps file by the scanner of the clamd daemon configured from environment.
+func FromEnv(file storage.IFile) (*Scanner, error) {
+	env := config.Scan{}
+	if err := envconfig.Process("", &env); err != nil {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrInitialFileClient, err.Error())
+	}
+	return NewScanner(env, file, NewClamd(env.ScanNetwork, env.ScanAddress, env.ScanTimeout, env.ScanChunkSize)), nil
+}
+
+// NewScanner method
+func NewScanner(env config.Scan, file storage.IFile, scanner IScanner) *Scanner {
+	return &Scanner{
+		IFile:   file,
+		env:     env,
+		scanner: scanner,
+	}
+}
+
+// Scanner scans uploads before storing them. Infected uploads are rejected, suspicious
+// ones are stored under the quarantine prefix and rejected.
+type Scanner struct {
+	storage.IFile
+	env     config.Scan
+	scanner IScanner
+}
+
+func (s *Scanner) Unwrap() storage.IFile { return s.IFile }
+
+func (s *Scanner) Upload(ctx context.Context, prefix string, f io.ReadCloser, attrs ...storage.UploadAttrs) (string, error) {
+	spool, err := os.CreateTemp("", "scan-*")
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", errorhandler.ErrScan, err.Error())
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	result, err := s.scanner.Scan(ctx, io.TeeReader(f, spool))
+	if err != nil {
+		return "", err
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("%w: %s", errorhandler.ErrScan, err.Error())
+	}
+	reader := readCloser{Reader: spool, Closer: f}
+	if !result.Infected {
+		return s.IFile.Upload(ctx, prefix, reader, attrs...)
+	}
+	if !s.suspicious(result.Signature) {
+		return "", fmt.Errorf("%w: %s", errorhandler.ErrInfected, result.Signature)
+	}
+	attrs = append(attrs, storage.UploadAttrs{Metadata: map[string]string{SignatureKey: result.Signature}})
+	pt, err := s.IFile.Upload(ctx, path.Join(s.env.ScanQuarantinePrefix, prefix), reader, attrs...)
+	if err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%w: %s at %s", errorhandler.ErrQuarantined, result.Signature, pt)
+}
+
+func (s *Scanner) suspicious(signature string) bool {
+	for _, prefix := range s.env.ScanSuspicious {
+		if prefix != "" && strings.HasPrefix(signature, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,106 @@
+package scan
+
+import (
+	"context"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testIFile struct {
+	mock.Mock
+	storage.IFile
+}
+
+func (t *testIFile) Upload(ctx context.Context, prefix string, f io.ReadCloser, attrs ...storage.UploadAttrs) (string, error) {
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return "", err
+	}
+	if len(attrs) > 0 {
+		args := t.Called(ctx, prefix, string(b), attrs)
+		return args.Get(0).(string), args.Error(1)
+	}
+	args := t.Called(ctx, prefix, string(b))
+	return args.Get(0).(string), args.Error(1)
+}
+
+type ScannerSuite struct {
+	suite.Suite
+	clamd *fakeClamd
+	env   config.Scan
+}
+
+func (suite *ScannerSuite) SetupTest() {
+	clamd, err := newFakeClamd("tcp", "127.0.0.1:0")
+	suite.NoError(err)
+	suite.clamd = clamd
+	suite.env = config.Scan{ScanQuarantinePrefix: "quarantine", ScanSuspicious: []string{"Heuristics."}}
+}
+
+func (suite *ScannerSuite) TearDownTest() {
+	suite.NoError(suite.clamd.Close())
+}
+
+func (suite *ScannerSuite) TestUpload() {
+	testCases := []struct {
+		Label   string
+		Content string
+		Prefix  string
+		Attrs   []storage.UploadAttrs
+		Path    string
+		Error   error
+	}{
+		{
+			Label:   "Upload clean",
+			Content: "hello world",
+			Prefix:  "tenant",
+			Path:    "/media/tenant/a",
+		},
+		{
+			Label:   "Upload infected",
+			Content: "X5O!P%@AP EICAR",
+			Error:   errorhandler.ErrInfected,
+		},
+		{
+			Label:   "Upload suspicious",
+			Content: "ENCRYPTED",
+			Prefix:  "quarantine/tenant",
+			Attrs:   []storage.UploadAttrs{{Metadata: map[string]string{SignatureKey: "Heuristics.Encrypted.PDF"}}},
+			Error:   errorhandler.ErrQuarantined,
+		},
+		{
+			Label:   "Upload scan fail",
+			Content: "TOO-BIG",
+			Error:   errorhandler.ErrScan,
+		},
+	}
+	for _, tc := range testCases {
+		file := &testIFile{}
+		if len(tc.Attrs) > 0 {
+			file.On("Upload", mock.Anything, tc.Prefix, tc.Content, tc.Attrs).Return("/media/quarantine/tenant/a", nil)
+		} else {
+			file.On("Upload", mock.Anything, tc.Prefix, tc.Content).Return(tc.Path, nil)
+		}
+		scanner := NewScanner(suite.env, file, NewClamd("tcp", suite.clamd.listener.Addr().String(), time.Second, 4))
+
+		pt, err := scanner.Upload(context.Background(), "tenant", io.NopCloser(strings.NewReader(tc.Content)))
+		suite.ErrorIs(err, tc.Error, tc.Label)
+		suite.Equal(tc.Path, pt, tc.Label)
+		if tc.Prefix == "" {
+			file.AssertNotCalled(suite.T(), "Upload", mock.Anything, mock.Anything, mock.Anything)
+		} else {
+			file.AssertExpectations(suite.T())
+		}
+	}
+}
+
+func TestScannerSuite(t *testing.T) {
+	suite.Run(t, new(ScannerSuite))
+}
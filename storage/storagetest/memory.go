@@ -0,0 +1,236 @@
+// Package storagetest provides storage.IFile implementations for testing the
+// decorators wrapping it.
+package storagetest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemIFile keeps objects in memory. Every write bumps the generation, metadata
+// updates merge like Cloud Storage and keep it.
+type MemIFile struct {
+	storage.IFile
+	// Root is the PrefixPath uploads are stored under
+	Root string
+	// FailSuffix fails the uploads named with it
+	FailSuffix string
+	// FailMetadata fails every metadata update
+	FailMetadata bool
+	mu           sync.Mutex
+	objects      map[string]MemFile
+	generation   int64
+	unnamed      int
+}
+
+// NewMemIFile returns an empty MemIFile storing uploads under /media.
+func NewMemIFile() *MemIFile {
+	return &MemIFile{Root: "/media", objects: map[string]MemFile{}}
+}
+
+// Put stores content at route as a new generation.
+func (m *MemIFile) Put(route string, content []byte, metadata map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.put(route, content, "", metadata)
+}
+
+func (m *MemIFile) put(route string, content []byte, contentType string, metadata map[string]string) {
+	m.generation++
+	m.objects[route] = MemFile{path: route, content: content, contentType: contentType, metadata: metadata, generation: m.generation}
+}
+
+// Object returns the stored object at route, the zero MemFile when missing.
+func (m *MemIFile) Object(route string) MemFile {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.objects[route]
+}
+
+// Paths returns the sorted paths of the stored objects.
+func (m *MemIFile) Paths() []string {
+	var paths []string
+	_ = m.List(context.Background(), storage.Query{}, func(file storage.File) error {
+		paths = append(paths, file.Path())
+		return nil
+	})
+	return paths
+}
+
+func (m *MemIFile) Upload(ctx context.Context, prefix string, f io.ReadCloser, attrs ...storage.UploadAttrs) (string, error) {
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return "", err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	merged := storage.MergeUploadAttrs(attrs...)
+	name := merged.Name
+	if name == "" {
+		m.unnamed++
+		name = fmt.Sprintf("uuid%d", m.unnamed)
+	}
+	if m.FailSuffix != "" && strings.HasSuffix(name, m.FailSuffix) {
+		return "", fmt.Errorf("%w: %s", errorhandler.ErrFileUpload, name)
+	}
+	route := path.Join(m.Root, prefix, name)
+	m.put(route, b, merged.ContentType, merged.Metadata)
+	return route, nil
+}
+
+func (m *MemIFile) Stat(ctx context.Context, route string) (storage.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	file, ok := m.objects[route]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrFileNotExist, route)
+	}
+	return file, nil
+}
+
+// UpdateMetadata merges metadata into the object at path, an empty value
+// removes its key and an empty map clears it. The generation is kept.
+func (m *MemIFile) UpdateMetadata(ctx context.Context, route string, metadata map[string]string, conds ...storage.Precondition) (storage.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	file, ok := m.objects[route]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrFileNotExist, route)
+	}
+	if cond := storage.MergePreconditions(conds...); m.FailMetadata || (cond.GenerationMatch != 0 && cond.GenerationMatch != file.generation) {
+		return nil, fmt.Errorf("%w: %s", errorhandler.ErrPreconditionFailed, route)
+	}
+	merged := map[string]string{}
+	if len(metadata) > 0 {
+		for key, value := range file.metadata {
+			merged[key] = value
+		}
+	}
+	for key, value := range metadata {
+		if value == "" {
+			delete(merged, key)
+			continue
+		}
+		merged[key] = value
+	}
+	file.metadata = merged
+	m.objects[route] = file
+	return file, nil
+}
+
+func (m *MemIFile) Download(ctx context.Context, route string, generation int64) (storage.File, io.ReadCloser, error) {
+	file, err := m.Stat(ctx, route)
+	if err != nil {
+		return nil, nil, err
+	}
+	if generation != 0 && generation != file.Generation() {
+		return nil, nil, fmt.Errorf("%w: %s", errorhandler.ErrVersionNotExist, route)
+	}
+	return file, io.NopCloser(bytes.NewReader(file.(MemFile).content)), nil
+}
+
+func (m *MemIFile) Remove(ctx context.Context, route string, conds ...storage.Precondition) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	file, ok := m.objects[route]
+	if !ok {
+		return fmt.Errorf("%w: %s", errorhandler.ErrRemoveNotExist, route)
+	}
+	if cond := storage.MergePreconditions(conds...); cond.GenerationMatch != 0 && cond.GenerationMatch != file.generation {
+		return fmt.Errorf("%w: %s", errorhandler.ErrPreconditionFailed, route)
+	}
+	delete(m.objects, route)
+	return nil
+}
+
+func (m *MemIFile) Move(ctx context.Context, src, dst string, overwrite bool, conds ...storage.Precondition) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	file, ok := m.objects[src]
+	if !ok {
+		return fmt.Errorf("%w: %s", errorhandler.ErrFileNotExist, src)
+	}
+	if _, exist := m.objects[dst]; exist && !overwrite {
+		return fmt.Errorf("%w: %s", errorhandler.ErrFileExist, dst)
+	}
+	if cond := storage.MergePreconditions(conds...); cond.GenerationMatch != 0 && cond.GenerationMatch != file.generation {
+		return fmt.Errorf("%w: %s", errorhandler.ErrPreconditionFailed, src)
+	}
+	m.put(dst, file.content, file.contentType, file.metadata)
+	delete(m.objects, src)
+	return nil
+}
+
+// RenameFolder moves every object under src to dst as Move does.
+func (m *MemIFile) RenameFolder(ctx context.Context, src, dst string, progress storage.ProgressFn) (int, error) {
+	srcPrefix, dstPrefix := strings.TrimSuffix(src, "/")+"/", strings.TrimSuffix(dst, "/")+"/"
+	var routes []string
+	_ = m.List(ctx, storage.WithFileCloudPrefix(storage.Query{}, srcPrefix), func(file storage.File) error {
+		routes = append(routes, file.Path())
+		return nil
+	})
+	if len(routes) == 0 {
+		return 0, fmt.Errorf("%w: %s", errorhandler.ErrFolderNotExist, srcPrefix)
+	}
+	for n, route := range routes {
+		if err := m.Move(ctx, route, dstPrefix+strings.TrimPrefix(route, srcPrefix), false); err != nil {
+			return n, err
+		}
+	}
+	return len(routes), nil
+}
+
+func (m *MemIFile) List(ctx context.Context, q storage.Query, h storage.IterHandler) error {
+	m.mu.Lock()
+	var files []storage.File
+	for route, file := range m.objects {
+		if strings.HasPrefix(route, q.CloudPrefix) {
+			files = append(files, file)
+		}
+	}
+	m.mu.Unlock()
+	sort.Slice(files, func(i, j int) bool { return files[i].Path() < files[j].Path() })
+	for _, file := range files {
+		if err := h(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MemFile is an object stored by MemIFile.
+type MemFile struct {
+	storage.File
+	path        string
+	content     []byte
+	contentType string
+	metadata    map[string]string
+	generation  int64
+}
+
+// Content returns the stored bytes of the object.
+func (f MemFile) Content() []byte { return f.content }
+
+func (f MemFile) FolderInfo() (string, string, bool) { return "", "", false }
+
+func (f MemFile) Path() string { return f.path }
+
+func (f MemFile) ContentType() string { return f.contentType }
+
+func (f MemFile) Generation() int64 { return f.generation }
+
+func (f MemFile) Metadata() map[string]string { return f.metadata }
+
+func (f MemFile) Size() (int64, error) { return int64(len(f.content)), nil }
+
+func (f MemFile) NewReader(ctx context.Context) (io.ReadCloser, func() error, error) {
+	return io.NopCloser(bytes.NewReader(f.content)), func() error { return nil }, nil
+}
@@ -0,0 +1,74 @@
+package storagetest
+
+import (
+	"context"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"strings"
+	"testing"
+)
+
+type MemIFileSuite struct {
+	suite.Suite
+	ctx context.Context
+}
+
+func (suite *MemIFileSuite) SetupTest() {
+	suite.ctx = context.Background()
+}
+
+func (suite *MemIFileSuite) TestUpdateMetadata() {
+	file := NewMemIFile()
+	pt, err := file.Upload(suite.ctx, "tenant", io.NopCloser(strings.NewReader("content")), storage.UploadAttrs{Metadata: map[string]string{"owner": "a", "tag": "x"}})
+	suite.NoError(err)
+	suite.Equal("/media/tenant/uuid1", pt)
+	generation := file.Object(pt).Generation()
+
+	testCases := []struct {
+		Label    string
+		Metadata map[string]string
+		Conds    []storage.Precondition
+		Expected map[string]string
+		Error    error
+	}{
+		{
+			Label:    "Merge metadata",
+			Metadata: map[string]string{"owner": "b"},
+			Conds:    []storage.Precondition{storage.IfGenerationMatch(generation)},
+			Expected: map[string]string{"owner": "b", "tag": "x"},
+		},
+		{
+			Label:    "Remove key",
+			Metadata: map[string]string{"tag": ""},
+			Conds:    []storage.Precondition{storage.IfGenerationMatch(generation)},
+			Expected: map[string]string{"owner": "b"},
+		},
+		{
+			Label:    "Generation changed",
+			Metadata: map[string]string{"owner": "c"},
+			Conds:    []storage.Precondition{storage.IfGenerationMatch(generation + 1)},
+			Error:    errorhandler.ErrPreconditionFailed,
+		},
+		{
+			Label:    "Clear metadata",
+			Metadata: map[string]string{},
+			Expected: map[string]string{},
+		},
+	}
+	for _, tc := range testCases {
+		updated, err := file.UpdateMetadata(suite.ctx, pt, tc.Metadata, tc.Conds...)
+		if tc.Error != nil {
+			suite.ErrorIs(err, tc.Error, tc.Label)
+			continue
+		}
+		suite.NoError(err, tc.Label)
+		suite.Equal(tc.Expected, updated.Metadata(), tc.Label)
+		suite.Equal(generation, updated.Generation(), tc.Label)
+	}
+}
+
+func TestMemIFileSuite(t *testing.T) {
+	suite.Run(t, new(MemIFileSuite))
+}
@@ -0,0 +1,139 @@
+package storagetest
+
+import (
+	"context"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"time"
+)
+
+// IFile mocks storage.IFile. The preconditions are passed to Called only when
+// given, the upload attrs never are and the uploaded reader is drained.
+type IFile struct {
+	mock.Mock
+	storage.IFile
+}
+
+func (t *IFile) Upload(ctx context.Context, prefix string, f io.ReadCloser, attrs ...storage.UploadAttrs) (string, error) {
+	args := t.Called(ctx, prefix, f)
+	if _, err := io.Copy(io.Discard, f); err != nil {
+		return "", err
+	}
+	return args.Get(0).(string), args.Error(1)
+}
+
+func (t *IFile) Remove(ctx context.Context, path string, conds ...storage.Precondition) error {
+	if len(conds) > 0 {
+		return t.Called(ctx, path, conds).Error(0)
+	}
+	return t.Called(ctx, path).Error(0)
+}
+
+func (t *IFile) Copy(ctx context.Context, src, dst string, overwrite bool, conds ...storage.Precondition) error {
+	if len(conds) > 0 {
+		return t.Called(ctx, src, dst, overwrite, conds).Error(0)
+	}
+	return t.Called(ctx, src, dst, overwrite).Error(0)
+}
+
+func (t *IFile) Move(ctx context.Context, src, dst string, overwrite bool, conds ...storage.Precondition) error {
+	if len(conds) > 0 {
+		return t.Called(ctx, src, dst, overwrite, conds).Error(0)
+	}
+	return t.Called(ctx, src, dst, overwrite).Error(0)
+}
+
+func (t *IFile) RemoveFolder(ctx context.Context, path string, dryRun bool) (int, error) {
+	args := t.Called(ctx, path, dryRun)
+	return args.Int(0), args.Error(1)
+}
+
+func (t *IFile) RenameFolder(ctx context.Context, src, dst string, progress storage.ProgressFn) (int, error) {
+	args := t.Called(ctx, src, dst, progress)
+	return args.Int(0), args.Error(1)
+}
+
+func (t *IFile) Stat(ctx context.Context, path string) (storage.File, error) {
+	args := t.Called(ctx, path)
+	file, _ := args.Get(0).(storage.File)
+	return file, args.Error(1)
+}
+
+func (t *IFile) UpdateMetadata(ctx context.Context, path string, metadata map[string]string, conds ...storage.Precondition) (storage.File, error) {
+	var args mock.Arguments
+	if len(conds) > 0 {
+		args = t.Called(ctx, path, metadata, conds)
+	} else {
+		args = t.Called(ctx, path, metadata)
+	}
+	file, _ := args.Get(0).(storage.File)
+	return file, args.Error(1)
+}
+
+func (t *IFile) List(ctx context.Context, q storage.Query, h storage.IterHandler) error {
+	args := t.Called(ctx, q, h)
+	for _, file := range args.Get(0).([]storage.File) {
+		if err := h(file); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (t *IFile) Versions(ctx context.Context, path string, h storage.IterHandler) error {
+	args := t.Called(ctx, path, h)
+	for _, file := range args.Get(0).([]storage.File) {
+		if err := h(file); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (t *IFile) RemoveVersion(ctx context.Context, path string, generation int64) error {
+	return t.Called(ctx, path, generation).Error(0)
+}
+
+func (t *IFile) RestoreVersion(ctx context.Context, path string, generation int64, conds ...storage.Precondition) error {
+	if len(conds) > 0 {
+		return t.Called(ctx, path, generation, conds).Error(0)
+	}
+	return t.Called(ctx, path, generation).Error(0)
+}
+
+// File is a storage.File whose attributes are set by its fields.
+type File struct {
+	storage.File
+	Route    string
+	Folder   bool
+	Type     string
+	Bytes    int64
+	Gen      int64
+	Meta     map[string]string
+	Created  time.Time
+	Modified time.Time
+}
+
+func (t File) FolderInfo() (string, string, bool) {
+	if t.Folder {
+		return "", t.Route, true
+	}
+	return "", "", false
+}
+
+func (t File) Path() string { return t.Route }
+
+func (t File) ContentType() string { return t.Type }
+
+func (t File) Generation() int64 { return t.Gen }
+
+func (t File) Metadata() map[string]string { return t.Meta }
+
+func (t File) Size() (int64, error) { return t.Bytes, nil }
+
+func (t File) CreatedTime() (time.Time, error) { return t.Created, nil }
+
+func (t File) ModTime() (time.Time, error) { return t.Modified, nil }
+
+func (t File) GetURL() string { return "" }
@@ -0,0 +1,303 @@
+package trash
+
+import (
+	"context"
+	"fmt"
+	"github.com/cockroachdb/errors"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	zapTool "github.com/justdomepaul/toolbox/zap"
+	"github.com/kelseyhightower/envconfig"
+	"go.uber.org/zap"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FromEnv wraps file by the trash configured from environment.
+func FromEnv(file storage.IFile) (*Trash, error) {
+	media := config.Media{}
+	env := config.Trash{}
+	for _, item := range []interface{}{&media, &env} {
+		if err := envconfig.Process("", item); err != nil {
+			return nil, fmt.Errorf("%w: %s", errorhandler.ErrInitialFileClient, err.Error())
+		}
+	}
+	return NewTrash(media, env, file), nil
+}
+
+// NewTrash method
+func NewTrash(media config.Media, env config.Trash, file storage.IFile) *Trash {
+	return &Trash{
+		IFile: file,
+		media: media,
+		env:   env,
+		now:   time.Now,
+	}
+}
+
+// Metadata keys recording on a trashed object when and where it was removed.
+const (
+	DeletedAtKey = "trash_deleted_at"
+	OriginalKey  = "trash_original"
+)
+
+// Trash turns removes of the wrapped IFile into moves under a hidden trash prefix.
+// A trashed object is stored at {PrefixPath}/{TrashPrefix}/{deleted unix nano}/{original path
+// without PrefixPath} and records its deletion time and original path in its metadata.
+type Trash struct {
+	storage.IFile
+	media config.Media
+	env   config.Trash
+	now   func() time.Time
+}
+
+// Entry is an object in the trash.
+type Entry struct {
+	Path      string    `json:"path"`
+	Original  string    `json:"original"`
+	DeletedAt time.Time `json:"deleted_at"`
+	Size      int64     `json:"size"`
+}
+
+func (t *Trash) Unwrap() storage.IFile { return t.IFile }
+
+// Remove copies the object into the trash, records the deletion in the copy metadata then
+// removes the object at the generation read before copying, a failed step drops the copy.
+// A generation of conds other than the live one fails with ErrPreconditionFailed.
+func (t *Trash) Remove(ctx context.Context, route string, conds ...storage.Precondition) error {
+	if t.isTrashed(route) {
+		return t.IFile.Remove(ctx, route, conds...)
+	}
+	file, err := t.IFile.Stat(ctx, route)
+	if err == nil {
+		if cond := storage.MergePreconditions(conds...); cond.GenerationMatch != 0 && cond.GenerationMatch != file.Generation() {
+			return fmt.Errorf("%w: %s", errorhandler.ErrPreconditionFailed, route)
+		}
+		err = t.trash(ctx, route, file.Generation(), t.now())
+	}
+	if errors.Is(err, errorhandler.ErrFileNotExist) {
+		return fmt.Errorf("%w: %s", errorhandler.ErrRemoveNotExist, route)
+	}
+	return err
+}
+
+// RemoveFolder moves each object of the folder into the trash, each object is restored on its own.
+// Folder placeholders carry no content and are removed instead of trashed.
+func (t *Trash) RemoveFolder(ctx context.Context, route string, dryRun bool) (int, error) {
+	if dryRun || t.isTrashed(route) {
+		return t.IFile.RemoveFolder(ctx, route, dryRun)
+	}
+	var objects []storage.File
+	var placeholders []string
+	q := storage.WithFileCloudPrefix(storage.Query{}, strings.TrimSuffix(route, "/")+"/")
+	if err := t.IFile.List(ctx, q, func(file storage.File) error {
+		if _, _, exist := file.FolderInfo(); exist {
+			return nil
+		}
+		if strings.HasSuffix(file.Path(), "/") {
+			placeholders = append(placeholders, file.Path())
+		} else {
+			objects = append(objects, file)
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	deletedAt := t.now()
+	if err := storage.ForEach(ctx, storage.DefaultConcurrency, len(objects), func(ctx context.Context, i int) error {
+		if err := t.trash(ctx, objects[i].Path(), objects[i].Generation(), deletedAt); err != nil && !errors.Is(err, errorhandler.ErrFileNotExist) {
+			return err
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	if err := t.removeAll(ctx, placeholders); err != nil {
+		return 0, err
+	}
+	return len(objects) + len(placeholders), nil
+}
+
+func (t *Trash) List(ctx context.Context, q storage.Query, h storage.IterHandler) error {
+	return t.IFile.List(ctx, q, t.hide(q, h))
+}
+
+func (t *Trash) ListPage(ctx context.Context, q storage.Query, h storage.IterHandler) (string, error) {
+	return t.IFile.ListPage(ctx, q, t.hide(q, h))
+}
+
+// Entries lists the objects in the trash.
+func (t *Trash) Entries(ctx context.Context) ([]Entry, error) {
+	var entries []Entry
+	err := t.IFile.List(ctx, storage.WithFileCloudPrefix(storage.Query{}, t.root()+"/"), func(file storage.File) error {
+		if _, _, exist := file.FolderInfo(); exist {
+			return nil
+		}
+		entry, err := t.entry(file)
+		if err != nil {
+			return nil
+		}
+		if entry.Size, err = file.Size(); err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+// Restore moves the trashed object back to its original path and returns the path.
+// The trash metadata is then removed from the restored object, a failure to remove it
+// is only logged as the object is already restored.
+func (t *Trash) Restore(ctx context.Context, route string, overwrite bool) (string, error) {
+	entry, err := t.stat(ctx, route)
+	if err != nil {
+		return "", err
+	}
+	if err := t.IFile.Move(ctx, route, entry.Original, overwrite); err != nil {
+		return "", err
+	}
+	if _, err := t.IFile.UpdateMetadata(ctx, entry.Original, map[string]string{DeletedAtKey: "", OriginalKey: ""}); err != nil {
+		zapTool.Logger.Warn("trash restore", zap.String("path", entry.Original), zap.Error(err))
+	}
+	return entry.Original, nil
+}
+
+// Purge permanently removes the trashed object.
+func (t *Trash) Purge(ctx context.Context, route string) error {
+	if _, err := t.stat(ctx, route); err != nil {
+		return err
+	}
+	return t.IFile.Remove(ctx, route)
+}
+
+// PurgeExpired permanently removes the objects trashed longer than the retention, along with
+// folder placeholders left in the trash, and returns their count.
+func (t *Trash) PurgeExpired(ctx context.Context) (int, error) {
+	deadline := t.now().Add(-t.env.TrashRetention)
+	var expired []string
+	err := t.IFile.List(ctx, storage.WithFileCloudPrefix(storage.Query{}, t.root()+"/"), func(file storage.File) error {
+		if _, _, exist := file.FolderInfo(); exist {
+			return nil
+		}
+		if strings.HasSuffix(file.Path(), "/") {
+			expired = append(expired, file.Path())
+			return nil
+		}
+		if entry, err := t.entry(file); err == nil && entry.DeletedAt.Before(deadline) {
+			expired = append(expired, entry.Path)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := t.removeAll(ctx, expired); err != nil {
+		return 0, err
+	}
+	return len(expired), nil
+}
+
+// Start runs PurgeExpired every TrashPurgeInterval until ctx is done or stop is called.
+func (t *Trash) Start(ctx context.Context) (stop func()) {
+	return storage.Every(ctx, t.env.TrashPurgeInterval, "trash purger", func(ctx context.Context) error {
+		_, err := t.PurgeExpired(ctx)
+		return err
+	})
+}
+
+func (t *Trash) root() string {
+	return path.Join(t.media.PrefixPath, t.env.TrashPrefix)
+}
+
+func (t *Trash) isTrashed(route string) bool {
+	return strings.HasPrefix(route, t.root()+"/")
+}
+
+func (t *Trash) trashRoute(route string, deletedAt time.Time) string {
+	relative := strings.TrimPrefix(route, t.media.PrefixPath)
+	return path.Join(t.root(), strconv.FormatInt(deletedAt.UnixNano(), 10), relative)
+}
+
+// trash copies route into the trash, records deletedAt and route in the copy metadata
+// then removes route at generation, so that a route changed since it was read is kept.
+// The copy is removed when a later step fails.
+func (t *Trash) trash(ctx context.Context, route string, generation int64, deletedAt time.Time) error {
+	trashRoute := t.trashRoute(route, deletedAt)
+	if err := t.IFile.Copy(ctx, route, trashRoute, false); err != nil {
+		return err
+	}
+	_, err := t.IFile.UpdateMetadata(ctx, trashRoute, map[string]string{
+		DeletedAtKey: deletedAt.UTC().Format(time.RFC3339Nano),
+		OriginalKey:  route,
+	})
+	if err == nil {
+		err = t.IFile.Remove(ctx, route, storage.IfGenerationMatch(generation))
+	}
+	if err != nil {
+		if errRemove := t.IFile.Remove(ctx, trashRoute); errRemove != nil {
+			return fmt.Errorf("%w: %s", err, errRemove.Error())
+		}
+		return err
+	}
+	return nil
+}
+
+func (t *Trash) removeAll(ctx context.Context, routes []string) error {
+	return storage.ForEach(ctx, storage.DefaultConcurrency, len(routes), func(ctx context.Context, i int) error {
+		err := t.IFile.Remove(ctx, routes[i])
+		if err != nil && !errors.Is(err, errorhandler.ErrRemoveNotExist) && !errors.Is(err, errorhandler.ErrFileNotExist) {
+			return err
+		}
+		return nil
+	})
+}
+
+func (t *Trash) stat(ctx context.Context, route string) (Entry, error) {
+	if !t.isTrashed(route) {
+		return Entry{}, fmt.Errorf("%w: %s", errorhandler.ErrTrashEntry, route)
+	}
+	file, err := t.IFile.Stat(ctx, route)
+	if err != nil {
+		return Entry{}, err
+	}
+	return t.entry(file)
+}
+
+func (t *Trash) entry(file storage.File) (Entry, error) {
+	route := file.Path()
+	if !t.isTrashed(route) {
+		return Entry{}, fmt.Errorf("%w: %s", errorhandler.ErrTrashEntry, route)
+	}
+	metadata := file.Metadata()
+	original := metadata[OriginalKey]
+	deletedAt, err := time.Parse(time.RFC3339Nano, metadata[DeletedAtKey])
+	if original == "" || err != nil {
+		return Entry{}, fmt.Errorf("%w: %s", errorhandler.ErrTrashEntry, route)
+	}
+	return Entry{
+		Path:      route,
+		Original:  original,
+		DeletedAt: deletedAt.UTC(),
+	}, nil
+}
+
+// hide skips the trash from listings, unless the query targets the trash itself.
+func (t *Trash) hide(q storage.Query, h storage.IterHandler) storage.IterHandler {
+	if q.Has(storage.FileCloudPrefix) && strings.HasPrefix(q.CloudPrefix, t.root()+"/") {
+		return h
+	}
+	return func(file storage.File) error {
+		route := file.Path()
+		if _, folder, exist := file.FolderInfo(); exist {
+			route = folder
+		}
+		if route = strings.TrimSuffix(route, "/"); route == t.root() || t.isTrashed(route) {
+			return nil
+		}
+		return h(file)
+	}
+}
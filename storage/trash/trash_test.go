@@ -0,0 +1,333 @@
+package trash
+
+import (
+	"context"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	"github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/justdomepaul/gin-storage/storage/storagetest"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type TrashSuite struct {
+	suite.Suite
+	ctx   context.Context
+	media config.Media
+	env   config.Trash
+	now   time.Time
+}
+
+func (suite *TrashSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.media = config.Media{PrefixPath: "/media/"}
+	suite.env = config.Trash{TrashPrefix: ".trash", TrashRetention: time.Hour, TrashPurgeInterval: time.Millisecond}
+	suite.now = time.Unix(0, 1000)
+}
+
+func (suite *TrashSuite) newTrash(file storage.IFile) *Trash {
+	t := NewTrash(suite.media, suite.env, file)
+	t.now = func() time.Time { return suite.now }
+	return t
+}
+
+func trashed(route, original string, deletedAt int64) storagetest.File {
+	return storagetest.File{Route: route, Bytes: 5, Meta: map[string]string{
+		DeletedAtKey: time.Unix(0, deletedAt).UTC().Format(time.RFC3339Nano),
+		OriginalKey:  original,
+	}}
+}
+
+func trashMetadata(original string) map[string]string {
+	return trashed("", original, 1000).Meta
+}
+
+func (suite *TrashSuite) TestRemove() {
+	testCases := []struct {
+		Label         string
+		StatError     error
+		CopyError     error
+		MetadataError error
+		Want          error
+	}{
+		{
+			Label: "Remove into trash",
+		},
+		{
+			Label:     "Remove not exist",
+			StatError: errorhandler.ErrFileNotExist,
+			Want:      errorhandler.ErrRemoveNotExist,
+		},
+		{
+			Label:     "Remove removed meanwhile",
+			CopyError: errorhandler.ErrFileNotExist,
+			Want:      errorhandler.ErrRemoveNotExist,
+		},
+		{
+			Label:         "Remove without recorded deletion",
+			MetadataError: errorhandler.ErrFileUpdate,
+			Want:          errorhandler.ErrFileUpdate,
+		},
+	}
+	for _, tc := range testCases {
+		file := &storagetest.IFile{}
+		file.On("Stat", mock.Anything, "/media/tenant/uuid").Return(storagetest.File{Route: "/media/tenant/uuid", Gen: 3}, tc.StatError)
+		file.On("Copy", mock.Anything, "/media/tenant/uuid", "/media/.trash/1000/tenant/uuid", false).Return(tc.CopyError)
+		file.On("UpdateMetadata", mock.Anything, "/media/.trash/1000/tenant/uuid", trashMetadata("/media/tenant/uuid")).Return(nil, tc.MetadataError)
+		file.On("Remove", mock.Anything, "/media/tenant/uuid", []storage.Precondition{storage.IfGenerationMatch(3)}).Return(nil)
+		file.On("Remove", mock.Anything, "/media/.trash/1000/tenant/uuid").Return(nil)
+
+		err := suite.newTrash(file).Remove(suite.ctx, "/media/tenant/uuid")
+		if tc.Want != nil {
+			suite.ErrorIs(err, tc.Want, tc.Label)
+			file.AssertNotCalled(suite.T(), "Remove", mock.Anything, "/media/tenant/uuid", mock.Anything)
+			continue
+		}
+		suite.NoError(err, tc.Label)
+		file.AssertCalled(suite.T(), "Remove", mock.Anything, "/media/tenant/uuid", []storage.Precondition{storage.IfGenerationMatch(3)})
+		file.AssertNotCalled(suite.T(), "Remove", mock.Anything, "/media/.trash/1000/tenant/uuid")
+	}
+}
+
+func (suite *TrashSuite) TestRemovePrecondition() {
+	testCases := []struct {
+		Label       string
+		Generation  int64
+		RemoveError error
+		Error       error
+		Copied      bool
+	}{
+		{
+			Label:      "Remove at generation",
+			Generation: 3,
+			Copied:     true,
+		},
+		{
+			Label:      "Remove at stale generation",
+			Generation: 2,
+			Error:      errorhandler.ErrPreconditionFailed,
+		},
+		{
+			Label:       "Remove changed after copying",
+			Generation:  3,
+			RemoveError: errorhandler.ErrPreconditionFailed,
+			Error:       errorhandler.ErrPreconditionFailed,
+			Copied:      true,
+		},
+	}
+	conds := []storage.Precondition{storage.IfGenerationMatch(3)}
+	for _, tc := range testCases {
+		file := &storagetest.IFile{}
+		file.On("Stat", mock.Anything, "/media/tenant/uuid").Return(storagetest.File{Route: "/media/tenant/uuid", Gen: tc.Generation}, nil)
+		file.On("Copy", mock.Anything, "/media/tenant/uuid", "/media/.trash/1000/tenant/uuid", false).Return(nil)
+		file.On("UpdateMetadata", mock.Anything, "/media/.trash/1000/tenant/uuid", trashMetadata("/media/tenant/uuid")).Return(nil, nil)
+		file.On("Remove", mock.Anything, "/media/tenant/uuid", conds).Return(tc.RemoveError)
+		file.On("Remove", mock.Anything, "/media/.trash/1000/tenant/uuid").Return(nil)
+
+		err := suite.newTrash(file).Remove(suite.ctx, "/media/tenant/uuid", conds...)
+		if !tc.Copied {
+			file.AssertNotCalled(suite.T(), "Copy", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		}
+		if tc.Error != nil {
+			suite.ErrorIs(err, tc.Error, tc.Label)
+			if tc.Copied {
+				file.AssertCalled(suite.T(), "Remove", mock.Anything, "/media/.trash/1000/tenant/uuid")
+			}
+			continue
+		}
+		suite.NoError(err, tc.Label)
+		file.AssertNotCalled(suite.T(), "Remove", mock.Anything, "/media/.trash/1000/tenant/uuid")
+	}
+}
+
+func (suite *TrashSuite) TestRemoveTrashed() {
+	file := &storagetest.IFile{}
+	file.On("Remove", mock.Anything, "/media/.trash/1000/tenant/uuid").Return(nil)
+
+	suite.NoError(suite.newTrash(file).Remove(suite.ctx, "/media/.trash/1000/tenant/uuid"))
+	file.AssertNotCalled(suite.T(), "Move", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *TrashSuite) TestRemoveFolder() {
+	file := &storagetest.IFile{}
+	file.On("RemoveFolder", mock.Anything, "/media/tenant/folder", true).Return(3, nil)
+	file.On("List", mock.Anything, storage.WithFileCloudPrefix(storage.Query{}, "/media/tenant/folder/"), mock.Anything).
+		Return([]storage.File{
+			storagetest.File{Route: "/media/tenant/folder/"},
+			storagetest.File{Route: "/media/tenant/folder/a", Gen: 3},
+			storagetest.File{Route: "/media/tenant/folder/sub/b", Gen: 3},
+		}, nil)
+	for _, name := range []string{"a", "sub/b"} {
+		file.On("Copy", mock.Anything, "/media/tenant/folder/"+name, "/media/.trash/1000/tenant/folder/"+name, false).Return(nil)
+		file.On("UpdateMetadata", mock.Anything, "/media/.trash/1000/tenant/folder/"+name, trashMetadata("/media/tenant/folder/"+name)).Return(nil, nil)
+		file.On("Remove", mock.Anything, "/media/tenant/folder/"+name, []storage.Precondition{storage.IfGenerationMatch(3)}).Return(nil)
+	}
+	file.On("Remove", mock.Anything, "/media/tenant/folder/").Return(nil)
+	t := suite.newTrash(file)
+
+	count, err := t.RemoveFolder(suite.ctx, "/media/tenant/folder", true)
+	suite.NoError(err)
+	suite.Equal(3, count)
+	file.AssertNotCalled(suite.T(), "List", mock.Anything, mock.Anything, mock.Anything)
+
+	count, err = t.RemoveFolder(suite.ctx, "/media/tenant/folder", false)
+	suite.NoError(err)
+	suite.Equal(3, count)
+	file.AssertNotCalled(suite.T(), "RemoveFolder", mock.Anything, "/media/tenant/folder", false)
+	file.AssertNotCalled(suite.T(), "Copy", mock.Anything, "/media/tenant/folder/", mock.Anything, mock.Anything)
+	file.AssertCalled(suite.T(), "Remove", mock.Anything, "/media/tenant/folder/")
+}
+
+func (suite *TrashSuite) TestList() {
+	files := []storage.File{
+		storagetest.File{Route: "/media/tenant/uuid"},
+		storagetest.File{Route: "/media/.trash/", Folder: true},
+		storagetest.File{Route: "/media/.trash/1000/tenant/uuid"},
+	}
+	trashQuery := storage.WithFileCloudPrefix(storage.Query{}, "/media/.trash/")
+	file := &storagetest.IFile{}
+	file.On("List", mock.Anything, storage.Query{}, mock.Anything).Return(files, nil)
+	file.On("List", mock.Anything, trashQuery, mock.Anything).Return(files[2:], nil)
+	t := suite.newTrash(file)
+
+	var paths []string
+	suite.NoError(t.List(suite.ctx, storage.Query{}, func(file storage.File) error {
+		paths = append(paths, file.Path())
+		return nil
+	}))
+	suite.Equal([]string{"/media/tenant/uuid"}, paths)
+
+	paths = nil
+	suite.NoError(t.List(suite.ctx, trashQuery, func(file storage.File) error {
+		paths = append(paths, file.Path())
+		return nil
+	}))
+	suite.Equal([]string{"/media/.trash/1000/tenant/uuid"}, paths)
+}
+
+func (suite *TrashSuite) TestEntries() {
+	file := &storagetest.IFile{}
+	file.On("List", mock.Anything, storage.WithFileCloudPrefix(storage.Query{}, "/media/.trash/"), mock.Anything).
+		Return([]storage.File{
+			trashed("/media/.trash/1000/tenant/uuid", "/media/tenant/uuid", 1000),
+			storagetest.File{Route: "/media/.trash/1000/tenant/"},
+			storagetest.File{Route: "/media/.trash/broken"},
+		}, nil)
+
+	entries, err := suite.newTrash(file).Entries(suite.ctx)
+	suite.NoError(err)
+	suite.Equal([]Entry{{
+		Path:      "/media/.trash/1000/tenant/uuid",
+		Original:  "/media/tenant/uuid",
+		DeletedAt: time.Unix(0, 1000).UTC(),
+		Size:      5,
+	}}, entries)
+}
+
+func (suite *TrashSuite) TestRestore() {
+	testCases := []struct {
+		Label string
+		Path  string
+		File  storage.File
+		Error error
+		Want  error
+	}{
+		{
+			Label: "Restore entry",
+			Path:  "/media/.trash/1000/tenant/uuid",
+			File:  trashed("/media/.trash/1000/tenant/uuid", "/media/tenant/uuid", 1000),
+		},
+		{
+			Label: "Restore over existing",
+			Path:  "/media/.trash/1000/tenant/uuid",
+			File:  trashed("/media/.trash/1000/tenant/uuid", "/media/tenant/uuid", 1000),
+			Error: errorhandler.ErrFileExist,
+			Want:  errorhandler.ErrFileExist,
+		},
+		{
+			Label: "Restore not trashed",
+			Path:  "/media/tenant/uuid",
+			Want:  errorhandler.ErrTrashEntry,
+		},
+		{
+			Label: "Restore without recorded deletion",
+			Path:  "/media/.trash/1000/tenant/uuid",
+			File:  storagetest.File{Route: "/media/.trash/1000/tenant/uuid"},
+			Want:  errorhandler.ErrTrashEntry,
+		},
+	}
+	for _, tc := range testCases {
+		file := &storagetest.IFile{}
+		file.On("Stat", mock.Anything, tc.Path).Return(tc.File, nil)
+		file.On("Move", mock.Anything, tc.Path, "/media/tenant/uuid", false).Return(tc.Error)
+		file.On("UpdateMetadata", mock.Anything, "/media/tenant/uuid", map[string]string{DeletedAtKey: "", OriginalKey: ""}).Return(nil, nil)
+
+		pt, err := suite.newTrash(file).Restore(suite.ctx, tc.Path, false)
+		if tc.Want != nil {
+			suite.ErrorIs(err, tc.Want, tc.Label)
+			file.AssertNotCalled(suite.T(), "UpdateMetadata", mock.Anything, mock.Anything, mock.Anything)
+			continue
+		}
+		suite.NoError(err, tc.Label)
+		suite.Equal("/media/tenant/uuid", pt, tc.Label)
+		file.AssertCalled(suite.T(), "UpdateMetadata", mock.Anything, "/media/tenant/uuid", map[string]string{DeletedAtKey: "", OriginalKey: ""})
+	}
+}
+
+func (suite *TrashSuite) TestPurge() {
+	file := &storagetest.IFile{}
+	file.On("Stat", mock.Anything, "/media/.trash/1000/tenant/uuid").
+		Return(trashed("/media/.trash/1000/tenant/uuid", "/media/tenant/uuid", 1000), nil)
+	file.On("Remove", mock.Anything, "/media/.trash/1000/tenant/uuid").Return(nil)
+	t := suite.newTrash(file)
+
+	suite.NoError(t.Purge(suite.ctx, "/media/.trash/1000/tenant/uuid"))
+	suite.ErrorIs(t.Purge(suite.ctx, "/media/tenant/uuid"), errorhandler.ErrTrashEntry)
+	file.AssertNumberOfCalls(suite.T(), "Remove", 1)
+}
+
+func (suite *TrashSuite) TestPurgeExpired() {
+	expired := "/media/.trash/1000/tenant/a"
+	fresh := "/media/.trash/7200000001000/tenant/b"
+	placeholder := "/media/.trash/1000/tenant/"
+	file := &storagetest.IFile{}
+	file.On("List", mock.Anything, mock.Anything, mock.Anything).
+		Return([]storage.File{
+			trashed(expired, "/media/tenant/a", 1000),
+			trashed(fresh, "/media/tenant/b", 7200000001000),
+			storagetest.File{Route: placeholder},
+		}, nil)
+	file.On("Remove", mock.Anything, expired).Return(nil)
+	file.On("Remove", mock.Anything, placeholder).Return(errorhandler.ErrRemoveNotExist)
+	suite.now = time.Unix(0, 1000).Add(2 * time.Hour)
+
+	count, err := suite.newTrash(file).PurgeExpired(suite.ctx)
+	suite.NoError(err)
+	suite.Equal(2, count)
+	file.AssertNotCalled(suite.T(), "Remove", mock.Anything, fresh)
+}
+
+func (suite *TrashSuite) TestStart() {
+	file := &storagetest.IFile{}
+	called := make(chan struct{}, 1)
+	file.On("List", mock.Anything, mock.Anything, mock.Anything).Return([]storage.File{}, nil).Run(func(mock.Arguments) {
+		select {
+		case called <- struct{}{}:
+		default:
+		}
+	})
+
+	stop := suite.newTrash(file).Start(suite.ctx)
+	select {
+	case <-called:
+	case <-time.After(time.Second):
+		suite.Fail("purger not run")
+	}
+	stop()
+}
+
+func TestTrashSuite(t *testing.T) {
+	suite.Run(t, new(TrashSuite))
+}
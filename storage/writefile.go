@@ -0,0 +1,24 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic writes b aside then renames it over route, a crash never leaves a half
+// written file behind.
+func WriteFileAtomic(route string, b []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(route), filepath.Base(route)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), route)
+}
@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"github.com/stretchr/testify/suite"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type WriteFileSuite struct {
+	suite.Suite
+}
+
+func (suite *WriteFileSuite) TestWriteFileAtomic() {
+	dir := suite.T().TempDir()
+	route := filepath.Join(dir, "state.json")
+	suite.NoError(WriteFileAtomic(route, []byte("first")))
+	suite.NoError(WriteFileAtomic(route, []byte("second")))
+	b, err := os.ReadFile(route)
+	suite.NoError(err)
+	suite.Equal("second", string(b))
+	entries, err := os.ReadDir(dir)
+	suite.NoError(err)
+	suite.Len(entries, 1)
+
+	suite.Error(WriteFileAtomic(filepath.Join(dir, "missing", "state.json"), []byte("x")))
+}
+
+func TestWriteFileSuite(t *testing.T) {
+	suite.Run(t, new(WriteFileSuite))
+}
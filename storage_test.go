@@ -7,8 +7,10 @@ import (
 	"fmt"
 	"github.com/cockroachdb/errors"
 	"github.com/gin-gonic/gin"
+	"github.com/justdomepaul/gin-storage/pkg/config"
 	errorhandlerTool "github.com/justdomepaul/gin-storage/pkg/errorhandler"
 	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/justdomepaul/gin-storage/storage/quota"
 	"github.com/justdomepaul/toolbox/errorhandler"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
@@ -20,6 +22,8 @@ import (
 	"net/textproto"
 	"net/url"
 	"os"
+	"path"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -30,19 +34,95 @@ type testIFile struct {
 	storage.IFile
 }
 
-func (t *testIFile) Upload(ctx context.Context, prefix string, f io.ReadCloser) (string, error) {
+func (t *testIFile) Upload(ctx context.Context, prefix string, f io.ReadCloser, attrs ...storage.UploadAttrs) (string, error) {
+	if len(attrs) > 0 {
+		args := t.Called(ctx, prefix, f, attrs)
+		return args.Get(0).(string), args.Error(1)
+	}
 	args := t.Called(ctx, prefix, f)
 	return args.Get(0).(string), args.Error(1)
 }
 
+func (t *testIFile) Stat(ctx context.Context, path string) (storage.File, error) {
+	args := t.Called(ctx, path)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(storage.File), args.Error(1)
+}
+
+func (t *testIFile) ObjectLock(ctx context.Context, path string) (storage.ObjectLock, error) {
+	args := t.Called(ctx, path)
+	return args.Get(0).(storage.ObjectLock), args.Error(1)
+}
+
+func (t *testIFile) Retention(ctx context.Context) (storage.Retention, error) {
+	args := t.Called(ctx)
+	return args.Get(0).(storage.Retention), args.Error(1)
+}
+
+func (t *testIFile) SetRetention(ctx context.Context, periodSeconds int64) (storage.Retention, error) {
+	args := t.Called(ctx, periodSeconds)
+	return args.Get(0).(storage.Retention), args.Error(1)
+}
+
+func (t *testIFile) SetHold(ctx context.Context, path string, hold storage.Hold) (storage.ObjectLock, error) {
+	args := t.Called(ctx, path, hold)
+	return args.Get(0).(storage.ObjectLock), args.Error(1)
+}
+
+func (t *testIFile) UpdateMetadata(ctx context.Context, path string, metadata map[string]string, conds ...storage.Precondition) (storage.File, error) {
+	var args mock.Arguments
+	if len(conds) > 0 {
+		args = t.Called(ctx, path, metadata, conds)
+	} else {
+		args = t.Called(ctx, path, metadata)
+	}
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(storage.File), args.Error(1)
+}
+
 func (t *testIFile) GetURL(ctx context.Context, path string) (string, error) {
 	args := t.Called(ctx, path)
 	return args.Get(0).(string), args.Error(1)
 }
 
-func (t *testIFile) Remove(ctx context.Context, path string) error {
+func (t *testIFile) Remove(ctx context.Context, path string, conds ...storage.Precondition) error {
+	if len(conds) > 0 {
+		return t.Called(ctx, path, conds).Error(0)
+	}
+	return t.Called(ctx, path).Error(0)
+}
+
+func (t *testIFile) Copy(ctx context.Context, src, dst string, overwrite bool, conds ...storage.Precondition) error {
+	if len(conds) > 0 {
+		return t.Called(ctx, src, dst, overwrite, conds).Error(0)
+	}
+	return t.Called(ctx, src, dst, overwrite).Error(0)
+}
+
+func (t *testIFile) Move(ctx context.Context, src, dst string, overwrite bool, conds ...storage.Precondition) error {
+	if len(conds) > 0 {
+		return t.Called(ctx, src, dst, overwrite, conds).Error(0)
+	}
+	return t.Called(ctx, src, dst, overwrite).Error(0)
+}
+
+func (t *testIFile) CreateFolder(ctx context.Context, path string) (string, error) {
 	args := t.Called(ctx, path)
-	return args.Error(0)
+	return args.Get(0).(string), args.Error(1)
+}
+
+func (t *testIFile) RemoveFolder(ctx context.Context, path string, dryRun bool) (int, error) {
+	args := t.Called(ctx, path, dryRun)
+	return args.Int(0), args.Error(1)
+}
+
+func (t *testIFile) RenameFolder(ctx context.Context, src, dst string, progress storage.ProgressFn) (int, error) {
+	args := t.Called(ctx, src, dst, progress)
+	return args.Int(0), args.Error(1)
 }
 
 func (t *testIFile) List(ctx context.Context, q storage.Query, h storage.IterHandler) error {
@@ -50,6 +130,68 @@ func (t *testIFile) List(ctx context.Context, q storage.Query, h storage.IterHan
 	return args.Error(0)
 }
 
+func (t *testIFile) ListPage(ctx context.Context, q storage.Query, h storage.IterHandler) (string, error) {
+	args := t.Called(ctx, q, h)
+	return args.Get(0).(string), args.Error(1)
+}
+
+func (t *testIFile) Versions(ctx context.Context, path string, h storage.IterHandler) error {
+	args := t.Called(ctx, path, h)
+	return args.Error(0)
+}
+
+func (t *testIFile) Download(ctx context.Context, path string, generation int64) (storage.File, io.ReadCloser, error) {
+	args := t.Called(ctx, path, generation)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(storage.File), args.Get(1).(io.ReadCloser), args.Error(2)
+}
+
+func (t *testIFile) RestoreVersion(ctx context.Context, path string, generation int64, conds ...storage.Precondition) error {
+	if len(conds) > 0 {
+		return t.Called(ctx, path, generation, conds).Error(0)
+	}
+	return t.Called(ctx, path, generation).Error(0)
+}
+
+type testFile struct {
+	storage.File
+	path       string
+	folder     bool
+	generation int64
+	metadata   map[string]string
+}
+
+func (t testFile) FolderInfo() (string, string, bool) {
+	if t.folder {
+		return t.path, t.path, true
+	}
+	return "", "", false
+}
+
+func (t testFile) Path() string { return t.path }
+
+func (t testFile) Name() string { return path.Base(t.path) }
+
+func (t testFile) ContentType() string { return "image/png" }
+
+func (t testFile) Generation() int64 { return t.generation }
+
+func (t testFile) Metadata() map[string]string { return t.metadata }
+
+func (t testFile) Size() (int64, error) { return 5, nil }
+
+func (t testFile) CreatedTime() (time.Time, error) {
+	return time.Date(2022, 3, 10, 9, 14, 1, 0, time.UTC), nil
+}
+
+func (t testFile) ModTime() (time.Time, error) {
+	return time.Date(2022, 3, 11, 9, 14, 1, 0, time.UTC), nil
+}
+
+func (t testFile) GetURL() string { return "https://storage.googleapis.com/bucket" + t.path }
+
 func NewMockGinServer() *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
@@ -140,6 +282,13 @@ func PostFiles(uri string, forms map[string][]io.Reader, headers map[string]stri
 	return getBody(req, headers, router)
 }
 
+// PostJSON method
+func PostJSON(uri string, param map[string]interface{}, headers map[string]string, router *gin.Engine) ([]byte, error) {
+	jsonByte, _ := json.Marshal(param)
+	req := httptest.NewRequest(http.MethodPost, uri, bytes.NewReader(jsonByte))
+	return getBody(req, headers, router)
+}
+
 // PutJSON method
 func PutJSON(uri string, param map[string]interface{}, headers map[string]string, router *gin.Engine) ([]byte, error) {
 	jsonByte, _ := json.Marshal(param)
@@ -192,18 +341,46 @@ func (suite *StorageSuite) TestRegister() {
 	route := gin.New()
 	Register(route)
 	suite.T().Log(route)
-	suite.Equal(DefaultPrefix, route.Routes()[0].Path)
-	suite.Equal(http.MethodGet, route.Routes()[0].Method)
-	suite.Equal(DefaultPrefix, route.Routes()[1].Path)
-	suite.Equal(http.MethodPost, route.Routes()[1].Method)
-	suite.Equal(DefaultPrefix+"/multiple", route.Routes()[2].Path)
-	suite.Equal(http.MethodPost, route.Routes()[2].Method)
-	suite.Equal(DefaultPrefix, route.Routes()[3].Path)
-	suite.Equal(http.MethodPut, route.Routes()[3].Method)
-	suite.Equal(DefaultPrefix+"/multiple", route.Routes()[4].Path)
-	suite.Equal(http.MethodPut, route.Routes()[4].Method)
-	suite.Equal(DefaultPrefix, route.Routes()[5].Path)
-	suite.Equal(http.MethodDelete, route.Routes()[5].Method)
+	var routes []string
+	for _, info := range route.Routes() {
+		routes = append(routes, info.Method+" "+info.Path)
+	}
+	suite.ElementsMatch([]string{
+		http.MethodGet + " " + DefaultPrefix,
+		http.MethodPost + " " + DefaultPrefix,
+		http.MethodPost + " " + DefaultPrefix + "/multiple",
+		http.MethodPut + " " + DefaultPrefix,
+		http.MethodPut + " " + DefaultPrefix + "/multiple",
+		http.MethodDelete + " " + DefaultPrefix,
+		http.MethodDelete + " " + DefaultPrefix + "/multiple",
+		http.MethodGet + " " + DefaultPrefix + "/usage",
+		http.MethodPost + " " + DefaultPrefix + "/copy",
+		http.MethodPost + " " + DefaultPrefix + "/copy/multiple",
+		http.MethodPost + " " + DefaultPrefix + "/move",
+		http.MethodPost + " " + DefaultPrefix + "/move/multiple",
+		http.MethodPost + " " + DefaultPrefix + "/folder",
+		http.MethodDelete + " " + DefaultPrefix + "/folder",
+		http.MethodPost + " " + DefaultPrefix + "/folder/rename",
+		http.MethodGet + " " + DefaultPrefix + "/versions",
+		http.MethodPost + " " + DefaultPrefix + "/versions/restore",
+		http.MethodGet + " " + DefaultPrefix + "/download",
+		http.MethodGet + " " + DefaultPrefix + "/stat",
+		http.MethodPatch + " " + DefaultPrefix + "/meta",
+		http.MethodPost + " " + DefaultPrefix + "/commit",
+		http.MethodGet + " " + DefaultPrefix + "/search",
+		http.MethodGet + " " + DefaultPrefix + "/image",
+		http.MethodGet + " " + DefaultPrefix + "/hold",
+		http.MethodPut + " " + DefaultPrefix + "/hold",
+		http.MethodDelete + " " + DefaultPrefix + "/hold",
+		http.MethodGet + " " + DefaultPrefix + "/retention",
+		http.MethodPut + " " + DefaultPrefix + "/retention",
+		http.MethodGet + " " + DefaultPrefix + "/lifecycle",
+		http.MethodPut + " " + DefaultPrefix + "/lifecycle",
+		http.MethodGet + " " + DefaultPrefix + "/trash",
+		http.MethodPost + " " + DefaultPrefix + "/trash/restore",
+		http.MethodDelete + " " + DefaultPrefix + "/trash",
+		http.MethodPost + " " + DefaultPrefix + "/archive",
+	}, routes)
 	suite.T().Log(route.Routes()[0].Path)
 	suite.T().Log(storage.FILE)
 }
@@ -212,6 +389,7 @@ func (suite *StorageSuite) TestUpload() {
 	type want struct {
 		Path        string
 		UploadError error
+		Code        int
 	}
 
 	testCases := []struct {
@@ -232,6 +410,31 @@ func (suite *StorageSuite) TestUpload() {
 			Want: want{
 				Path:        "test/testPath",
 				UploadError: errorhandlerTool.ErrFileUpload,
+				Code:        http.StatusConflict,
+			},
+		},
+		{
+			Label:  "Upload media quota exceeded",
+			Prefix: "test",
+			Want: want{
+				UploadError: errorhandlerTool.ErrQuotaExceeded,
+				Code:        http.StatusInsufficientStorage,
+			},
+		},
+		{
+			Label:  "Upload media larger than quota",
+			Prefix: "test",
+			Want: want{
+				UploadError: errorhandlerTool.ErrQuotaTooLarge,
+				Code:        http.StatusRequestEntityTooLarge,
+			},
+		},
+		{
+			Label:  "Upload media infected",
+			Prefix: "test",
+			Want: want{
+				UploadError: errorhandlerTool.ErrInfected,
+				Code:        http.StatusUnprocessableEntity,
 			},
 		},
 	}
@@ -242,7 +445,7 @@ func (suite *StorageSuite) TestUpload() {
 			defer f.Close()
 
 			testIFile := &testIFile{}
-			testIFile.On("Upload", mock.Anything, tc.Prefix, mock.Anything).Return(tc.Want.Path, tc.Want.UploadError)
+			testIFile.On("Upload", mock.Anything, tc.Prefix, mock.Anything, mock.Anything).Return(tc.Want.Path, tc.Want.UploadError)
 			storage.Register(testIFile, func() {})
 			defer storage.Unload()
 			route := NewMockGinServer()
@@ -260,7 +463,7 @@ func (suite *StorageSuite) TestUpload() {
 				suite.NoError(json.Unmarshal(resp, &result))
 				suite.Equal(tc.Want.Path, result["path"])
 			} else {
-				suite.Error(err)
+				suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Want.Code))
 			}
 		}()
 	}
@@ -303,7 +506,7 @@ func (suite *StorageSuite) TestBatch() {
 			defer f2.Close()
 
 			testIFile := &testIFile{}
-			testIFile.On("Upload", mock.Anything, tc.Prefix, mock.Anything).Return(tc.Want.Path, tc.Want.UploadError)
+			testIFile.On("Upload", mock.Anything, tc.Prefix, mock.Anything, mock.Anything).Return(tc.Want.Path, tc.Want.UploadError)
 			storage.Register(testIFile, func() {})
 			defer storage.Unload()
 			route := NewMockGinServer()
@@ -451,6 +654,129 @@ func (suite *StorageSuite) TestMultiplePublicize() {
 	}
 }
 
+func (suite *StorageSuite) TestTransfer() {
+	type want struct {
+		Error error
+		Code  int
+	}
+
+	testCases := []struct {
+		Label     string
+		Method    string
+		URI       string
+		Src       string
+		Dst       string
+		Overwrite bool
+		Want      want
+	}{
+		{
+			Label:  "Copy media",
+			Method: "Copy",
+			URI:    "/storage/copy",
+			Src:    "test/a",
+			Dst:    "test/b",
+		},
+		{
+			Label:     "Move media",
+			Method:    "Move",
+			URI:       "/storage/move",
+			Src:       "test/a",
+			Dst:       "test/b",
+			Overwrite: true,
+		},
+		{
+			Label:  "Copy media not exist",
+			Method: "Copy",
+			URI:    "/storage/copy",
+			Src:    "test/a",
+			Dst:    "test/b",
+			Want: want{
+				Error: errorhandlerTool.ErrFileNotExist,
+				Code:  http.StatusNotFound,
+			},
+		},
+		{
+			Label:  "Move media onto existing",
+			Method: "Move",
+			URI:    "/storage/move",
+			Src:    "test/a",
+			Dst:    "test/b",
+			Want: want{
+				Error: errorhandlerTool.ErrFileExist,
+				Code:  http.StatusConflict,
+			},
+		},
+		{
+			Label:  "Copy media without destination",
+			Method: "Copy",
+			URI:    "/storage/copy",
+			Src:    "test/a",
+			Want: want{
+				Code: http.StatusBadRequest,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			testIFile := &testIFile{}
+			testIFile.On(tc.Method, mock.Anything, tc.Src, tc.Dst, tc.Overwrite).Return(tc.Want.Error)
+			storage.Register(testIFile, func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			resp, err := PostJSON(tc.URI, map[string]interface{}{
+				"src":       tc.Src,
+				"dst":       tc.Dst,
+				"overwrite": tc.Overwrite,
+			}, map[string]string{}, route)
+			if tc.Want.Code == 0 {
+				suite.NoError(err, tc.Label)
+				var result map[string]interface{}
+				suite.NoError(json.Unmarshal(resp, &result))
+				suite.Equal(tc.Dst, result["path"], tc.Label)
+			} else {
+				suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Want.Code), tc.Label)
+			}
+		}()
+	}
+}
+
+func (suite *StorageSuite) TestMultipleTransfer() {
+	paths := []TransferFile{
+		{Src: "test/a", Dst: "test/c"},
+		{Src: "test/b", Dst: "test/d", Overwrite: true},
+	}
+	for _, method := range []string{"Copy", "Move"} {
+		func() {
+			testIFile := &testIFile{}
+			testIFile.On(method, mock.Anything, "test/a", "test/c", false).Return(nil)
+			testIFile.On(method, mock.Anything, "test/b", "test/d", true).Return(nil)
+			storage.Register(testIFile, func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			resp, err := PostJSON("/storage/"+strings.ToLower(method)+"/multiple", map[string]interface{}{
+				"paths": paths,
+			}, map[string]string{}, route)
+			suite.NoError(err, method)
+			var result []TransferResult
+			suite.NoError(json.Unmarshal(resp, &result))
+			suite.Equal([]TransferResult{
+				{Src: "test/a", Dst: "test/c", Status: TransferStatusDone},
+				{Src: "test/b", Dst: "test/d", Status: TransferStatusDone},
+			}, result, method)
+			testIFile.AssertExpectations(suite.T())
+
+			_, err = PostJSON("/storage/"+strings.ToLower(method)+"/multiple", map[string]interface{}{
+				"paths": []TransferFile{},
+			}, map[string]string{}, route)
+			suite.Error(err, method)
+		}()
+	}
+}
+
 func (suite *StorageSuite) TestRemove() {
 	type want struct {
 		RemoveError error
@@ -496,7 +822,8 @@ func (suite *StorageSuite) TestRemove() {
 
 func (suite *StorageSuite) TestList() {
 	type want struct {
-		ListError error
+		NextPageToken string
+		ListError     error
 	}
 
 	testCases := []struct {
@@ -504,6 +831,8 @@ func (suite *StorageSuite) TestList() {
 		Path      string
 		Delimiter string
 		Prefix    string
+		PageSize  int
+		PageToken string
 		Want      want
 	}{
 		{
@@ -512,6 +841,15 @@ func (suite *StorageSuite) TestList() {
 			Prefix:    "/test",
 			Want:      want{},
 		},
+		{
+			Label:     "List media page",
+			Prefix:    "/test",
+			PageSize:  2,
+			PageToken: "current",
+			Want: want{
+				NextPageToken: "next",
+			},
+		},
 		{
 			Label: "List media error",
 			Want: want{
@@ -528,8 +866,14 @@ func (suite *StorageSuite) TestList() {
 			if tc.Prefix != "" {
 				q = storage.WithFileCloudPrefix(q, tc.Prefix)
 			}
+			if tc.PageSize != 0 {
+				q = storage.WithFilePageSize(q, tc.PageSize)
+			}
+			if tc.PageToken != "" {
+				q = storage.WithFilePageToken(q, tc.PageToken)
+			}
 			testIFile := &testIFile{}
-			testIFile.On("List", mock.Anything, q, mock.Anything).Return(tc.Want.ListError)
+			testIFile.On("ListPage", mock.Anything, q, mock.Anything).Return(tc.Want.NextPageToken, tc.Want.ListError)
 			storage.Register(testIFile, func() {})
 			defer storage.Unload()
 			route := NewMockGinServer()
@@ -542,10 +886,24 @@ func (suite *StorageSuite) TestList() {
 			if tc.Prefix != "" {
 				u.Set("prefix", tc.Prefix)
 			}
+			if tc.PageSize != 0 {
+				u.Set("page_size", strconv.Itoa(tc.PageSize))
+			}
+			if tc.PageToken != "" {
+				u.Set("page_token", tc.PageToken)
+			}
 			resp, err := Get("/storage?"+u.Encode(), map[string]string{}, route)
 			if tc.Want.ListError == nil {
 				suite.NoError(err)
 				suite.T().Log(string(resp))
+				var result map[string]interface{}
+				suite.NoError(json.Unmarshal(resp, &result))
+				suite.Equal([]interface{}{}, result["items"])
+				if tc.Want.NextPageToken != "" {
+					suite.Equal(tc.Want.NextPageToken, result["next_page_token"])
+				} else {
+					suite.NotContains(result, "next_page_token")
+				}
 			} else {
 				suite.Error(err)
 			}
@@ -553,6 +911,225 @@ func (suite *StorageSuite) TestList() {
 	}
 }
 
+func (suite *StorageSuite) TestListItems() {
+	testIFile := &testIFile{}
+	testIFile.On("ListPage", mock.Anything, storage.Query{}, mock.Anything).
+		Run(func(args mock.Arguments) {
+			suite.NoError(args.Get(2).(storage.IterHandler)(testFile{path: "/test/a"}))
+			suite.NoError(args.Get(2).(storage.IterHandler)(testFile{path: "/test/sub/", folder: true}))
+		}).
+		Return("", nil)
+	storage.Register(testIFile, func() {})
+	defer storage.Unload()
+	route := NewMockGinServer()
+	Register(route)
+
+	resp, err := Get("/storage", map[string]string{}, route)
+	suite.NoError(err)
+	var result ListResponse
+	suite.NoError(json.Unmarshal(resp, &result))
+	suite.Equal([]FileV1{
+		{
+			Name:        "a",
+			Path:        "/test/a",
+			Size:        5,
+			ContentType: "image/png",
+			PublicURL:   "https://storage.googleapis.com/bucket/test/a",
+			Created:     "2022-03-10T09:14:01Z",
+			Updated:     "2022-03-11T09:14:01Z",
+		},
+		{
+			Name:     "/test/sub/",
+			Path:     "/test/sub/",
+			IsFolder: true,
+		},
+	}, result.Items)
+	suite.Contains(string(resp), `"is_folder":false`)
+	suite.Contains(string(resp), `"size":0`)
+}
+
+func (suite *StorageSuite) TestListFilter() {
+	after := time.Date(2022, 3, 10, 0, 0, 0, 0, time.UTC)
+	q := storage.WithFileCloudPrefix(storage.Query{}, "/test")
+	q = storage.WithFileNameGlob(q, "/test/*.png")
+	q = storage.WithFileNameRegex(q, "^/test/")
+	q = storage.WithFileContentType(q, "image/*")
+	q = storage.WithFileSizeRange(q, 10, 0)
+	q = storage.WithFileCreatedRange(q, after, time.Time{})
+	q = storage.WithFileUpdatedRange(q, time.Time{}, after)
+	q = storage.WithFileSort(q, storage.SortBySize, true)
+	testIFile := &testIFile{}
+	testIFile.On("ListPage", mock.Anything, q, mock.Anything).Return("", nil)
+	storage.Register(testIFile, func() {})
+	defer storage.Unload()
+	route := NewMockGinServer()
+	Register(route)
+
+	u := &url.Values{}
+	u.Set("prefix", "/test")
+	u.Set("glob", "/test/*.png")
+	u.Set("regex", "^/test/")
+	u.Set("content_type", "image/*")
+	u.Set("min_size", "10")
+	u.Set("created_after", after.Format(time.RFC3339))
+	u.Set("updated_before", after.Format(time.RFC3339))
+	u.Set("sort", "size")
+	u.Set("order", "desc")
+	_, err := Get("/storage?"+u.Encode(), map[string]string{}, route)
+	suite.NoError(err)
+	testIFile.AssertExpectations(suite.T())
+
+	for _, invalid := range []string{"sort=owner", "min_size=big", "created_after=yesterday"} {
+		_, err := Get("/storage?"+invalid, map[string]string{}, route)
+		suite.EqualError(err, fmt.Sprintf("request error by code: %d", http.StatusBadRequest), invalid)
+	}
+}
+
+func (suite *StorageSuite) TestListPageSizeError() {
+	testIFile := &testIFile{}
+	storage.Register(testIFile, func() {})
+	defer storage.Unload()
+	route := NewMockGinServer()
+	Register(route)
+
+	_, err := Get("/storage?page_size=ten", map[string]string{}, route)
+	suite.Error(err)
+	testIFile.AssertNotCalled(suite.T(), "ListPage", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *StorageSuite) TestListStream() {
+	type want struct {
+		ContentType string
+		Body        string
+	}
+
+	testCases := []struct {
+		Label  string
+		Accept string
+		Files  []storage.File
+		Want   want
+	}{
+		{
+			Label:  "List media as NDJSON",
+			Accept: MIMENDJSON,
+			Files:  []storage.File{testFile{path: "/test/a"}, testFile{path: "/test/sub/", folder: true}},
+			Want: want{
+				ContentType: MIMENDJSON,
+				Body: `{"name":"a","path":"/test/a","is_folder":false,"size":5,"content_type":"image/png","public_url":"https://storage.googleapis.com/bucket/test/a","created":"2022-03-10T09:14:01Z","updated":"2022-03-11T09:14:01Z"}` + "\n" +
+					`{"name":"/test/sub/","path":"/test/sub/","is_folder":true,"size":0,"content_type":"","public_url":"","created":"","updated":""}` + "\n",
+			},
+		},
+		{
+			Label:  "List media as CSV",
+			Accept: MIMECSV,
+			Files:  []storage.File{testFile{path: "/test/a"}, testFile{path: "/test/sub/", folder: true}},
+			Want: want{
+				ContentType: MIMECSV,
+				Body: "name,path,is_folder,size,content_type,public_url,created,updated,generation,metadata\n" +
+					"a,/test/a,false,5,image/png,https://storage.googleapis.com/bucket/test/a,2022-03-10T09:14:01Z,2022-03-11T09:14:01Z,,\n" +
+					"/test/sub/,/test/sub/,true,0,,,,,,\n",
+			},
+		},
+		{
+			Label:  "List empty media as CSV",
+			Accept: MIMECSV,
+			Want: want{
+				ContentType: MIMECSV,
+				Body:        "name,path,is_folder,size,content_type,public_url,created,updated,generation,metadata\n",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			w := httptest.NewRecorder()
+			testIFile := &testIFile{}
+			testIFile.On("List", mock.Anything, storage.WithFileCloudPrefix(storage.Query{}, "/test"), mock.Anything).
+				Run(func(args mock.Arguments) {
+					for _, file := range tc.Files {
+						suite.NoError(args.Get(2).(storage.IterHandler)(file))
+						suite.True(w.Flushed, tc.Label)
+						w.Flushed = false
+					}
+				}).
+				Return(nil)
+			storage.Register(testIFile, func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			req := httptest.NewRequest(http.MethodGet, "/storage?prefix=/test", nil)
+			req.Header.Set("Accept", tc.Accept)
+			route.ServeHTTP(w, req)
+			suite.Equal(http.StatusOK, w.Code, tc.Label)
+			suite.Equal(tc.Want.ContentType, w.Header().Get("Content-Type"), tc.Label)
+			suite.Equal(tc.Want.Body, w.Body.String(), tc.Label)
+		}()
+	}
+}
+
+func (suite *StorageSuite) TestListStreamError() {
+	testIFile := &testIFile{}
+	testIFile.On("List", mock.Anything, mock.Anything, mock.Anything).Return(errorhandlerTool.ErrGetFile)
+	storage.Register(testIFile, func() {})
+	defer storage.Unload()
+	route := NewMockGinServer()
+	Register(route)
+
+	_, err := Get("/storage", map[string]string{"Accept": MIMENDJSON}, route)
+	suite.EqualError(err, fmt.Sprintf("request error by code: %d", http.StatusNotFound))
+}
+
+func (suite *StorageSuite) TestUsage() {
+	type want struct {
+		Report quota.Report
+		Error  bool
+	}
+
+	testCases := []struct {
+		Label string
+		Quota bool
+		Want  want
+	}{
+		{
+			Label: "Usage of prefix",
+			Quota: true,
+			Want: want{
+				Report: quota.Report{Prefix: "test", Bytes: 10, Objects: 1, MaxBytes: 100},
+			},
+		},
+		{
+			Label: "Usage without quota",
+			Want: want{
+				Error: true,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			var fileStorage storage.IFile = &testIFile{}
+			if tc.Quota {
+				store := quota.NewMemoryStore()
+				suite.NoError(store.Set(suite.ctx, "test", quota.Usage{Bytes: 10, Objects: 1}))
+				fileStorage = quota.NewQuota(config.Media{}, config.Quota{QuotaMaxBytes: 100}, fileStorage, store)
+			}
+			storage.Register(fileStorage, func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			resp, err := Get("/storage/usage?prefix=test/sub", map[string]string{}, route)
+			if tc.Want.Error {
+				suite.Error(err)
+				return
+			}
+			suite.NoError(err)
+			var result quota.Report
+			suite.NoError(json.Unmarshal(resp, &result))
+			suite.Equal(tc.Want.Report, result)
+		}()
+	}
+}
+
 func TestStorageSuite(t *testing.T) {
 	suite.Run(t, new(StorageSuite))
 }
@@ -0,0 +1,98 @@
+package gin_storage
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/justdomepaul/gin-storage/storage"
+	"io"
+	"net/http"
+)
+
+const (
+	MIMENDJSON = "application/x-ndjson"
+	MIMECSV    = "text/csv"
+)
+
+type listEncoder interface {
+	Header() error
+	Encode(row FileV1) error
+	Flush() error
+}
+
+type ndjsonEncoder struct {
+	encoder *json.Encoder
+}
+
+func (e ndjsonEncoder) Header() error { return nil }
+
+func (e ndjsonEncoder) Encode(row FileV1) error { return e.encoder.Encode(row) }
+
+func (e ndjsonEncoder) Flush() error { return nil }
+
+type csvEncoder struct {
+	writer *csv.Writer
+}
+
+func (e csvEncoder) Header() error { return e.writer.Write(fileV1Columns) }
+
+func (e csvEncoder) Encode(row FileV1) error { return e.writer.Write(row.record()) }
+
+func (e csvEncoder) Flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func newListEncoder(format string, w io.Writer) listEncoder {
+	if format == MIMECSV {
+		return csvEncoder{writer: csv.NewWriter(w)}
+	}
+	return ndjsonEncoder{encoder: json.NewEncoder(w)}
+}
+
+// streamList writes and flushes every listed file to the response as soon as the driver yields it.
+// The status is committed with the first row, an error after that truncates the stream.
+func (fh FileHandler) streamList(c *gin.Context, q storage.Query, format string) {
+	encoder := newListEncoder(format, c.Writer)
+	started := false
+	start := func() error {
+		started = true
+		c.Header("Content-Type", format)
+		c.Status(http.StatusOK)
+		return encoder.Header()
+	}
+	err := fh.storage.List(c, q, func(file storage.File) error {
+		row, err := NewFileV1(file)
+		if err != nil {
+			return err
+		}
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := encoder.Encode(row); err != nil {
+			return err
+		}
+		if err := encoder.Flush(); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if err != nil && !started {
+		panic(listError(err))
+	}
+	if err == nil && !started {
+		err = start()
+	}
+	if err == nil {
+		err = encoder.Flush()
+	}
+	if err == nil {
+		c.Writer.Flush()
+	}
+	if err != nil {
+		_ = c.Error(err)
+	}
+}
@@ -0,0 +1,73 @@
+package gin_storage
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	errorhandlerTool "github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/justdomepaul/gin-storage/storage/trash"
+	"github.com/justdomepaul/toolbox/errorhandler"
+	"net/http"
+)
+
+func (fh FileHandler) trash() *trash.Trash {
+	t, ok := storage.Unwrap[*trash.Trash](fh.storage)
+	if !ok {
+		panic(errorhandler.NewErrNotFound(errorhandlerTool.ErrTrashNotEnable))
+	}
+	return t
+}
+
+func (fh FileHandler) ListTrash(c *gin.Context) {
+	entries, err := fh.trash().Entries(c)
+	if err != nil {
+		panic(listError(err))
+	}
+	if entries == nil {
+		entries = []trash.Entry{}
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+func (fh FileHandler) RestoreTrash(c *gin.Context) {
+	t := fh.trash()
+	req := struct {
+		Path      string `json:"path,omitempty" validate:"required"`
+		Overwrite bool   `json:"overwrite,omitempty"`
+	}{}
+	defer c.Request.Body.Close()
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		panic(errorhandler.NewErrJSONUnmarshal(err))
+	}
+	if err := validator.New().Struct(&req); err != nil {
+		panic(errorhandler.NewErrVariable(err))
+	}
+	path, err := t.Restore(c, req.Path, req.Overwrite)
+	if err != nil {
+		panic(reportError(err))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"path": path,
+	})
+}
+
+func (fh FileHandler) PurgeTrash(c *gin.Context) {
+	t := fh.trash()
+	req := struct {
+		Path string `validate:"required"`
+	}{
+		Path: c.Query("path"),
+	}
+	if err := validator.New().Struct(&req); err != nil {
+		panic(errorhandler.NewErrVariable(err))
+	}
+	if err := fh.interceptors.beforeRemove(c, req.Path); err != nil {
+		panic(reportError(err))
+	}
+	if err := t.Purge(c, req.Path); err != nil {
+		panic(reportError(err))
+	}
+	fh.interceptors.afterRemove(c, req.Path)
+	c.String(http.StatusOK, "ok")
+}
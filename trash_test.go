@@ -0,0 +1,147 @@
+package gin_storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	errorhandlerTool "github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/justdomepaul/gin-storage/storage/trash"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"time"
+)
+
+func (suite *StorageSuite) newTrash(fileStorage storage.IFile) storage.IFile {
+	return trash.NewTrash(config.Media{}, config.Trash{TrashPrefix: ".trash", TrashRetention: time.Hour}, fileStorage)
+}
+
+func trashedFile(route, original string) testFile {
+	return testFile{path: route, metadata: map[string]string{
+		trash.DeletedAtKey: time.Unix(0, 1000).UTC().Format(time.RFC3339Nano),
+		trash.OriginalKey:  original,
+	}}
+}
+
+func (suite *StorageSuite) TestListTrash() {
+	testIFile := &testIFile{}
+	testIFile.On("List", mock.Anything, storage.WithFileCloudPrefix(storage.Query{}, ".trash/"), mock.Anything).
+		Run(func(args mock.Arguments) {
+			suite.NoError(args.Get(2).(storage.IterHandler)(trashedFile(".trash/1000/test/a", "test/a")))
+		}).Return(nil)
+	storage.Register(suite.newTrash(testIFile), func() {})
+	defer storage.Unload()
+	route := NewMockGinServer()
+	Register(route)
+
+	resp, err := Get("/storage/trash", map[string]string{}, route)
+	suite.NoError(err)
+	var entries []trash.Entry
+	suite.NoError(json.Unmarshal(resp, &entries))
+	suite.Equal([]trash.Entry{{
+		Path:      ".trash/1000/test/a",
+		Original:  "test/a",
+		DeletedAt: time.Unix(0, 1000).UTC(),
+		Size:      5,
+	}}, entries)
+}
+
+func (suite *StorageSuite) TestTrashNotEnable() {
+	storage.Register(&testIFile{}, func() {})
+	defer storage.Unload()
+	route := NewMockGinServer()
+	Register(route)
+
+	_, err := Get("/storage/trash", map[string]string{}, route)
+	suite.EqualError(err, fmt.Sprintf("request error by code: %d", http.StatusNotFound))
+}
+
+func (suite *StorageSuite) TestRestoreTrash() {
+	testCases := []struct {
+		Label string
+		Path  string
+		Error error
+		Code  int
+	}{
+		{
+			Label: "Restore entry",
+			Path:  ".trash/1000/test/a",
+		},
+		{
+			Label: "Restore over existing",
+			Path:  ".trash/1000/test/a",
+			Error: errorhandlerTool.ErrFileExist,
+			Code:  http.StatusConflict,
+		},
+		{
+			Label: "Restore not trashed",
+			Path:  "test/a",
+			Code:  http.StatusNotFound,
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			testIFile := &testIFile{}
+			testIFile.On("Stat", mock.Anything, tc.Path).Return(trashedFile(tc.Path, "test/a"), nil)
+			testIFile.On("Move", mock.Anything, tc.Path, "test/a", false).Return(tc.Error)
+			testIFile.On("UpdateMetadata", mock.Anything, "test/a", map[string]string{trash.DeletedAtKey: "", trash.OriginalKey: ""}).Return(nil, nil)
+			storage.Register(suite.newTrash(testIFile), func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			resp, err := PostJSON("/storage/trash/restore", map[string]interface{}{
+				"path": tc.Path,
+			}, map[string]string{}, route)
+			if tc.Code != 0 {
+				suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Code), tc.Label)
+				return
+			}
+			suite.NoError(err, tc.Label)
+			var result map[string]interface{}
+			suite.NoError(json.Unmarshal(resp, &result))
+			suite.Equal("test/a", result["path"], tc.Label)
+		}()
+	}
+}
+
+func (suite *StorageSuite) TestPurgeTrash() {
+	testCases := []struct {
+		Label string
+		Query string
+		Code  int
+	}{
+		{
+			Label: "Purge entry",
+			Query: "?path=.trash/1000/test/a",
+		},
+		{
+			Label: "Purge not trashed",
+			Query: "?path=test/a",
+			Code:  http.StatusNotFound,
+		},
+		{
+			Label: "Purge without path",
+			Code:  http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			testIFile := &testIFile{}
+			testIFile.On("Stat", mock.Anything, ".trash/1000/test/a").Return(trashedFile(".trash/1000/test/a", "test/a"), nil)
+			testIFile.On("Remove", mock.Anything, ".trash/1000/test/a").Return(nil)
+			storage.Register(suite.newTrash(testIFile), func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			resp, err := DeleteJSON("/storage/trash"+tc.Query, map[string]interface{}{}, map[string]string{}, route)
+			if tc.Code != 0 {
+				suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Code), tc.Label)
+				return
+			}
+			suite.NoError(err, tc.Label)
+			suite.Equal("ok", string(resp), tc.Label)
+		}()
+	}
+}
@@ -0,0 +1,131 @@
+package gin_storage
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/justdomepaul/gin-storage/storage/compression"
+	"github.com/justdomepaul/toolbox/errorhandler"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Versions responds every kept generation of the object at path.
+func (fh FileHandler) Versions(c *gin.Context) {
+	req := struct {
+		Path string `validate:"required"`
+	}{
+		Path: c.Query("path"),
+	}
+	if err := validator.New().Struct(&req); err != nil {
+		panic(errorhandler.NewErrVariable(err))
+	}
+	items := []FileV1{}
+	if err := fh.storage.Versions(c, req.Path, func(file storage.File) error {
+		item, err := NewFileV1(file)
+		if err != nil {
+			return err
+		}
+		items = append(items, item)
+		return nil
+	}); err != nil {
+		panic(reportError(err))
+	}
+	c.JSON(http.StatusOK, ListResponse{Items: items})
+}
+
+// Download responds the content of the object at path, of its generation when given.
+func (fh FileHandler) Download(c *gin.Context) {
+	req := struct {
+		Path       string `validate:"required"`
+		Generation int64  `validate:"min=0"`
+	}{
+		Path:       c.Query("path"),
+		Generation: queryInt64(c, "generation"),
+	}
+	if err := validator.New().Struct(&req); err != nil {
+		panic(errorhandler.NewErrVariable(err))
+	}
+	headers := map[string]string{}
+	file, reader, err := fh.download(c, req.Path, req.Generation, headers)
+	if err != nil {
+		panic(reportError(err))
+	}
+	defer reader.Close()
+	size, err := file.Size()
+	if err != nil {
+		panic(errorhandler.NewErrExecute(err))
+	}
+	contentType := file.ContentType()
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	headers["ETag"] = etag(file.Generation())
+	headers["X-Generation"] = strconv.FormatInt(file.Generation(), 10)
+	c.DataFromReader(http.StatusOK, size, contentType, reader, headers)
+}
+
+// download serves the stored bytes of a compressed object when the client accepts
+// its encoding, setting the response headers telling so.
+func (fh FileHandler) download(c *gin.Context, route string, generation int64, headers map[string]string) (storage.File, io.ReadCloser, error) {
+	cmp, ok := storage.Unwrap[*compression.Compression](fh.storage)
+	if !ok {
+		return fh.storage.Download(c, route, generation)
+	}
+	file, reader, encoding, err := cmp.DownloadEncoded(c, route, generation, acceptEncodings(c.GetHeader("Accept-Encoding")))
+	if err != nil {
+		return nil, nil, err
+	}
+	headers["Vary"] = "Accept-Encoding"
+	if encoding != "" {
+		headers["Content-Encoding"] = encoding
+	}
+	return file, reader, nil
+}
+
+// acceptEncodings lists the encodings of an Accept-Encoding header, but the refused ones.
+func acceptEncodings(header string) []string {
+	var encodings []string
+	for _, item := range strings.Split(header, ",") {
+		params := strings.Split(item, ";")
+		encoding := strings.ToLower(strings.TrimSpace(params[0]))
+		if encoding == "" {
+			continue
+		}
+		refused := false
+		for _, param := range params[1:] {
+			if q := strings.TrimSpace(param); strings.HasPrefix(q, "q=") {
+				weight, err := strconv.ParseFloat(strings.TrimPrefix(q, "q="), 64)
+				refused = err != nil || weight == 0
+			}
+		}
+		if !refused {
+			encodings = append(encodings, encoding)
+		}
+	}
+	return encodings
+}
+
+func (fh FileHandler) RestoreVersion(c *gin.Context) {
+	req := struct {
+		Path       string `json:"path,omitempty" validate:"required"`
+		Generation int64  `json:"generation,omitempty" validate:"required,min=1"`
+	}{}
+	defer c.Request.Body.Close()
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		panic(errorhandler.NewErrJSONUnmarshal(err))
+	}
+	if err := validator.New().Struct(&req); err != nil {
+		panic(errorhandler.NewErrVariable(err))
+	}
+	if err := fh.storage.RestoreVersion(c, req.Path, req.Generation, preconditions(c)...); err != nil {
+		panic(reportError(err))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"path":       req.Path,
+		"generation": req.Generation,
+	})
+}
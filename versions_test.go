@@ -0,0 +1,229 @@
+package gin_storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"github.com/justdomepaul/gin-storage/pkg/config"
+	errorhandlerTool "github.com/justdomepaul/gin-storage/pkg/errorhandler"
+	"github.com/justdomepaul/gin-storage/storage"
+	"github.com/justdomepaul/gin-storage/storage/compression"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+func (suite *StorageSuite) TestVersions() {
+	testCases := []struct {
+		Label string
+		Query string
+		Error error
+		Code  int
+	}{
+		{
+			Label: "List versions",
+			Query: "?path=test/a",
+		},
+		{
+			Label: "List versions not exist",
+			Query: "?path=test/a",
+			Error: errorhandlerTool.ErrFileNotExist,
+			Code:  http.StatusNotFound,
+		},
+		{
+			Label: "List versions without path",
+			Code:  http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			testIFile := &testIFile{}
+			testIFile.On("Versions", mock.Anything, "test/a", mock.Anything).Run(func(args mock.Arguments) {
+				if tc.Error == nil {
+					suite.NoError(args.Get(2).(storage.IterHandler)(testFile{path: "test/a", generation: 1}))
+					suite.NoError(args.Get(2).(storage.IterHandler)(testFile{path: "test/a", generation: 2}))
+				}
+			}).Return(tc.Error)
+			storage.Register(testIFile, func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			resp, err := Get("/storage/versions"+tc.Query, map[string]string{}, route)
+			if tc.Code != 0 {
+				suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Code), tc.Label)
+				return
+			}
+			suite.NoError(err, tc.Label)
+			var result ListResponse
+			suite.NoError(json.Unmarshal(resp, &result))
+			suite.Len(result.Items, 2, tc.Label)
+			suite.Equal(int64(1), result.Items[0].Generation, tc.Label)
+			suite.Equal(int64(2), result.Items[1].Generation, tc.Label)
+		}()
+	}
+}
+
+func (suite *StorageSuite) TestDownload() {
+	testCases := []struct {
+		Label      string
+		Query      string
+		Generation int64
+		Error      error
+		Code       int
+	}{
+		{
+			Label: "Download live",
+			Query: "?path=test/a",
+		},
+		{
+			Label:      "Download generation",
+			Query:      "?path=test/a&generation=1",
+			Generation: 1,
+		},
+		{
+			Label:      "Download unknown generation",
+			Query:      "?path=test/a&generation=9",
+			Generation: 9,
+			Error:      errorhandlerTool.ErrVersionNotExist,
+			Code:       http.StatusNotFound,
+		},
+		{
+			Label: "Download invalid generation",
+			Query: "?path=test/a&generation=first",
+			Code:  http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			testIFile := &testIFile{}
+			if tc.Error != nil {
+				testIFile.On("Download", mock.Anything, "test/a", tc.Generation).Return(nil, nil, tc.Error)
+			} else {
+				testIFile.On("Download", mock.Anything, "test/a", tc.Generation).
+					Return(testFile{path: "test/a", generation: 1}, io.NopCloser(strings.NewReader("12345")), nil)
+			}
+			storage.Register(testIFile, func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			resp, err := Get("/storage/download"+tc.Query, map[string]string{}, route)
+			if tc.Code != 0 {
+				suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Code), tc.Label)
+				return
+			}
+			suite.NoError(err, tc.Label)
+			suite.Equal("12345", string(resp), tc.Label)
+		}()
+	}
+}
+
+func (suite *StorageSuite) TestDownloadEncoded() {
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	_, err := w.Write([]byte("12345"))
+	suite.NoError(err)
+	suite.NoError(w.Close())
+
+	testCases := []struct {
+		Label          string
+		AcceptEncoding string
+		Encoding       string
+	}{
+		{
+			Label:          "Download accepting gzip",
+			AcceptEncoding: "br, gzip",
+			Encoding:       "gzip",
+		},
+		{
+			Label:          "Download refusing gzip",
+			AcceptEncoding: "gzip;q=0, br",
+		},
+		{
+			Label: "Download without accepted encoding",
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			testIFile := &testIFile{}
+			testIFile.On("Download", mock.Anything, "test/a", int64(0)).Return(
+				testFile{path: "test/a", generation: 1, metadata: map[string]string{compression.EncodingKey: "gzip", compression.SizeKey: "5"}},
+				io.NopCloser(bytes.NewReader(compressed.Bytes())), nil)
+			cmp, err := compression.NewCompression(config.Compression{CompressionEncoding: "gzip"}, testIFile)
+			suite.NoError(err, tc.Label)
+			storage.Register(cmp, func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			req := httptest.NewRequest(http.MethodGet, "/storage/download?path=test/a", nil)
+			if tc.AcceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tc.AcceptEncoding)
+			}
+			resp := httptest.NewRecorder()
+			route.ServeHTTP(resp, req)
+			suite.Equal(http.StatusOK, resp.Code, tc.Label)
+			suite.Equal("Accept-Encoding", resp.Header().Get("Vary"), tc.Label)
+			suite.Equal(tc.Encoding, resp.Header().Get("Content-Encoding"), tc.Label)
+			body := io.Reader(resp.Body)
+			if tc.Encoding != "" {
+				body, err = gzip.NewReader(body)
+				suite.NoError(err, tc.Label)
+			}
+			b, err := io.ReadAll(body)
+			suite.NoError(err, tc.Label)
+			suite.Equal("12345", string(b), tc.Label)
+		}()
+	}
+}
+
+func (suite *StorageSuite) TestRestoreVersion() {
+	testCases := []struct {
+		Label      string
+		Generation int64
+		Error      error
+		Code       int
+	}{
+		{
+			Label:      "Restore version",
+			Generation: 1,
+		},
+		{
+			Label:      "Restore unknown version",
+			Generation: 9,
+			Error:      errorhandlerTool.ErrVersionNotExist,
+			Code:       http.StatusNotFound,
+		},
+		{
+			Label: "Restore without generation",
+			Code:  http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		func() {
+			testIFile := &testIFile{}
+			testIFile.On("RestoreVersion", mock.Anything, "test/a", tc.Generation).Return(tc.Error)
+			storage.Register(testIFile, func() {})
+			defer storage.Unload()
+			route := NewMockGinServer()
+			Register(route)
+
+			resp, err := PostJSON("/storage/versions/restore", map[string]interface{}{
+				"path":       "test/a",
+				"generation": tc.Generation,
+			}, map[string]string{}, route)
+			if tc.Code != 0 {
+				suite.EqualError(err, fmt.Sprintf("request error by code: %d", tc.Code), tc.Label)
+				return
+			}
+			suite.NoError(err, tc.Label)
+			var result map[string]interface{}
+			suite.NoError(json.Unmarshal(resp, &result))
+			suite.Equal(float64(tc.Generation), result["generation"], tc.Label)
+		}()
+	}
+}